@@ -9,7 +9,16 @@ import (
 	"github.com/evcc-io/eebus/spine/model"
 )
 
-func HEMS(details communication.ManufacturerDetails) spine.Device {
+// HEMS creates the HEMS device. The use cases are registered by their implementations, see usecase/ev.
+func HEMS(details communication.ManufacturerDetails) (spine.Device, error) {
+	return HEMSWithStores(details, nil, nil)
+}
+
+// HEMSWithStores creates the HEMS device keeping its entity and feature addresses and its
+// message and heartbeat counters in the stores, either of which may be nil.
+// Failures to save while creating the device are returned, later failures are passed
+// to the handler set by SetStoreErrorHandler.
+func HEMSWithStores(details communication.ManufacturerDetails, addressStore spine.AddressStore, counterStore spine.CounterStore) (spine.Device, error) {
 	localDeviceName := model.DeviceClassificationStringType(details.DeviceName)
 	localDeviceCode := model.DeviceClassificationStringType(details.DeviceCode)
 	localBrandName := model.DeviceClassificationStringType(details.BrandName)
@@ -25,24 +34,43 @@ func HEMS(details communication.ManufacturerDetails) spine.Device {
 	operationState := model.DeviceDiagnosisOperatingStateType(model.DeviceDiagnosisOperatingStateEnumTypeNormalOperation)
 
 	dev := &spine.DeviceImpl{
-		Address: localDeviceAddress,
-		Type:    model.DeviceTypeType(model.DeviceTypeEnumTypeEnergyManagementSystem),
+		Address:    localDeviceAddress,
+		Type:       model.DeviceTypeType(model.DeviceTypeEnumTypeEnergyManagementSystem),
+		FeatureSet: model.NetworkManagementFeatureSetTypeSmart,
 	}
 
-	eid := entity.Numerator([]uint{0})
+	var storeErr error
+	dev.SetStoreErrorHandler(func(err error) {
+		if storeErr == nil {
+			storeErr = err
+		}
+	})
+
+	if addressStore != nil {
+		if err := dev.SetAddressStore(addressStore); err != nil {
+			return nil, err
+		}
+	}
 
-	{
-		e := entity.DeviceInformation()
-		e.SetAddress(eid())
-		dev.Add(e)
+	if counterStore != nil {
+		if err := dev.SetCounterStore(counterStore); err != nil {
+			return nil, err
+		}
 	}
-	{
-		e := entity.CEM()
-		e.SetAddress(eid())
-		e.SetManufacturerData(manufacturerData)
-		e.SetOperationState(operationState)
-		dev.Add(e)
+
+	// device information is added first to be allocated entity address 0
+	dev.Add(entity.DeviceInformation())
+
+	cem := entity.CEM()
+	cem.SetManufacturerData(manufacturerData)
+	cem.SetOperationState(operationState)
+	dev.Add(cem)
+
+	dev.SetStoreErrorHandler(nil)
+
+	if storeErr != nil {
+		return nil, storeErr
 	}
 
-	return dev
+	return dev, nil
 }
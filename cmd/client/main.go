@@ -18,6 +18,7 @@ import (
 	"github.com/evcc-io/eebus/server"
 	"github.com/evcc-io/eebus/ship"
 	"github.com/evcc-io/eebus/spine/model"
+	"github.com/evcc-io/eebus/usecase/ev"
 	"github.com/libp2p/zeroconf/v2"
 )
 
@@ -63,8 +64,17 @@ func connectService(entry *zeroconf.ServiceEntry, id string, details communicati
 		return
 	}
 
-	hems := app.HEMS(details)
+	hems, err := app.HEMS(details)
+	if err != nil {
+		log.Printf("%s: device setup failed: %v", entry.HostName, err)
+		return
+	}
+
 	ctrl := communication.NewConnectionController(log.Default(), conn, hems)
+	if _, err := ev.New(hems, ctrl); err != nil {
+		log.Printf("%s: use case setup failed: %v", entry.HostName, err)
+		return
+	}
 
 	err = ctrl.Boot()
 	if err != nil {
@@ -14,6 +14,7 @@ import (
 	"github.com/evcc-io/eebus/server"
 	"github.com/evcc-io/eebus/ship"
 	"github.com/evcc-io/eebus/spine/model"
+	"github.com/evcc-io/eebus/usecase/ev"
 	"github.com/evcc-io/eebus/util"
 )
 
@@ -80,13 +81,19 @@ func main() {
 	}
 	defer zc.Shutdown()
 
-	hems := app.HEMS(details)
+	hems, err := app.HEMS(details)
+	if err != nil {
+		panic(err)
+	}
 
 	ln := &server.Listener{
 		Log:          log,
 		AccessMethod: id,
 		Handler: func(ski string, conn ship.Conn) error {
 			ctrl := communication.NewConnectionController(log, conn, hems)
+			if _, err := ev.New(hems, ctrl); err != nil {
+				return err
+			}
 			return ctrl.Boot()
 		},
 	}
@@ -16,12 +16,11 @@ import (
 	"github.com/evcc-io/eebus/spine/model"
 	"github.com/evcc-io/eebus/util"
 	"github.com/rickb777/date/period"
+	"github.com/samber/lo"
 )
 
 type ConnectionController struct {
-	msgNum              uint64 // 64bit values need to be defined on top of the struct to make atomic commands work on 32bit systems
-	heartBeatNum        uint64 // see https://github.com/golang/go/issues/11891
-	subscriptionNum     uint64
+	subscriptionNum     uint64 // 64bit values need to be defined on top of the struct to make atomic commands work on 32bit systems, see https://github.com/golang/go/issues/11891
 	log                 util.Logger
 	conn                ship.Conn
 	localDevice         spine.Device
@@ -34,14 +33,43 @@ type ConnectionController struct {
 	spineMsgMux         sync.Mutex
 
 	subscriptionEntries  []model.SubscriptionManagementEntryDataType
+	subscriptionMux      sync.Mutex
+	partialReads         map[model.MsgCounterType]time.Time // send times of pending reads restricted by selectors or elements
+	partialReadsMux      sync.Mutex
+	pendingWrites        map[model.MsgCounterType]pendingWrite // writes waiting for their result
+	pendingWritesMux     sync.Mutex
+	receivedMsgCounters  msgCounterWindow // msgCounters received from the remote device
 	specificationVersion model.SpecificationVersionType
+	removeDeviceHandlers []func()
+	errorC               chan error
+	errorClosed          bool // errorC has been closed after Run returned
+	errorMux             sync.Mutex
 	// EV specific data
-	clientData *EVSEClientDataType
+	clientData        *EVSEClientDataType
+	clientDataMux     sync.RWMutex
+	clientDataUpdates []EVDataElementUpdateType // updates pending until the client data is unlocked
 	// EVCC specific
 	dataUpdateHandler func(EVDataElementUpdateType, *EVSEClientDataType)
+	// remote use case changes
+	useCaseEventHandler func(spine.UseCaseEvent)
+	// EV connection changes
+	evConnectionHandlers   []func(connected bool)
+	evConnectionHandlerMux sync.Mutex
+
+	// heartbeat supervision of the remote device
+	heartbeatMux         sync.Mutex
+	remoteHeartbeat      *RemoteHeartbeat
+	remoteHeartbeatLost  bool
+	remoteHeartbeatTimer *time.Timer
+	heartbeatStopped     bool // supervision is not restarted by heartbeats received after stopping
+	heartbeatLostHandler func(RemoteHeartbeat)
 
 	// defines the system voltage
 	Voltage float64
+	// interval for sending heartbeats to subscribers
+	HeartbeatInterval time.Duration
+	// timeout announced with the heartbeats
+	HeartbeatTimeout time.Duration
 }
 
 func NewConnectionController(log util.Logger, conn ship.Conn, local spine.Device) *ConnectionController {
@@ -58,16 +86,23 @@ func NewConnectionController(log util.Logger, conn ship.Conn, local spine.Device
 		specificationVersion: device.SpecificationVersion,
 		localDevice:          local,
 		clientData:           &clientData,
+		partialReads:         make(map[model.MsgCounterType]time.Time),
+		pendingWrites:        make(map[model.MsgCounterType]pendingWrite),
+		errorC:               make(chan error, errorBufferSize),
 		sequencesController:  NewSequencesController(log),
 		Voltage:              230.0,
+		HeartbeatInterval:    DefaultHeartbeatInterval,
+		HeartbeatTimeout:     DefaultHeartbeatTimeout,
 	}
 
 	return c
 }
 
 func (c *ConnectionController) Boot() error {
+	c.lockClientData()
 	c.clientData.EVData.CommunicationStandard = EVCommunicationStandardEnumTypeUnknown
 	c.clientData.EVData.ChargeState = EVChargeStateEnumTypeUnknown
+	c.unlockClientData()
 
 	m := c.localDevice.Entity([]model.AddressEntityType{0}).FeatureByProps(model.FeatureTypeEnumTypeNodeManagement, model.RoleTypeSpecial)
 	if f, ok := m.(*feature.NodeManagement); ok {
@@ -119,6 +154,11 @@ func (c *ConnectionController) Boot() error {
 	// 	f.Delegate = c
 	// }
 
+	c.removeDeviceHandlers = []func(){
+		c.localDevice.AddUseCaseChangeHandler(c.notifyNodeManagementUseCaseData),
+		c.localDevice.AddDiscoveryChangeHandler(c.notifyNodeManagementDetailedDiscoveryData),
+	}
+
 	c.sequencesController.Boot()
 
 	go c.Run()
@@ -133,14 +173,22 @@ func (c *ConnectionController) Boot() error {
 
 func (c *ConnectionController) CloseConnection(err error) {
 	c.stopHeartbeat()
+	c.stopDeviceNotifications()
 	_ = c.conn.Close()
 }
 
+// stopDeviceNotifications stops announcing local device changes to this connection
+func (c *ConnectionController) stopDeviceNotifications() {
+	for _, remove := range c.removeDeviceHandlers {
+		remove()
+	}
+}
+
 func (c *ConnectionController) Run() {
 	var err error
 	for err == nil {
 		if c.conn == nil || c.conn.IsConnectionClosed() {
-			err = errors.New("connection closed")
+			err = spine.ErrNotConnected
 			break
 		}
 
@@ -152,12 +200,14 @@ func (c *ConnectionController) Run() {
 
 			if err = json.Unmarshal(data, &datagram); err != nil {
 				c.log.Println("error unmarshaling datagram: ", err, string(data))
+				c.publishError(fmt.Errorf("unmarshal datagram: %w", err))
 				err = nil // don't break, otherwise charing will go to max limit
 				continue
 			}
 
 			if err = c.processDatagram(datagram.Datagram); err != nil {
 				c.log.Println("error processing datagram: ", err)
+				c.publishError(err)
 				err = nil // don't break, otherwise charing will go to max limit
 				continue
 			}
@@ -166,9 +216,19 @@ func (c *ConnectionController) Run() {
 
 	if err != nil {
 		c.log.Println("error processing incoming message: ", err)
+
+		if !errors.Is(err, spine.ErrNotConnected) {
+			err = fmt.Errorf("%w: %v", spine.ErrNotConnected, err)
+		}
+		c.publishError(err)
 	}
 
+	c.closeErrors()
+
 	c.stopHeartbeat()
+	c.stopDeviceNotifications()
+	c.clearPartialReads()
+	c.clearPendingWrites()
 	_ = c.conn.Close()
 }
 
@@ -193,6 +253,9 @@ func (c *ConnectionController) UpdateDeviceConfigurationData(f *feature.DeviceCo
 		}
 	}
 
+	c.lockClientData()
+	defer c.unlockClientData()
+
 	if c.clientData.EVData.AsymetricChargingSupported != asymtricSupport {
 		c.clientData.EVData.AsymetricChargingSupported = asymtricSupport
 		c.callDataUpdateHandler(EVDataElementUpdateAsymetricChargingType)
@@ -209,6 +272,9 @@ func (c *ConnectionController) UpdateDeviceDiagnosisData(f *feature.DeviceDiagno
 	re := c.remoteEntityForFeatureAddress(rf)
 	entityType := model.EntityTypeEnumType(re.GetType())
 
+	c.lockClientData()
+	defer c.unlockClientData()
+
 	prevEVSEOperationState := c.clientData.EVSEData.OperationState
 	prevEVChargeState := c.clientData.EVData.ChargeState
 
@@ -244,13 +310,43 @@ func (c *ConnectionController) UpdateDeviceDiagnosisData(f *feature.DeviceDiagno
 	}
 }
 
+func (c *ConnectionController) UpdateHeartbeatData(f *feature.DeviceDiagnosis, rf model.FeatureAddressType, data model.DeviceDiagnosisHeartbeatDataType) {
+	c.updateRemoteHeartbeat(data)
+}
+
+// updateLimits stores the calculated limits and notifies about changed current and power limits
+func (c *ConnectionController) updateLimits(limits feature.ElectricalLimits) {
+	ev := &c.clientData.EVData
+
+	amperageLimitsUpdated := false
+	for _, l := range limits.Phases {
+		limit := EVCurrentLimitType{Min: l.CurrentMin, Max: l.CurrentMax, Default: l.CurrentDefault}
+		if prev, ok := ev.Limits[l.Phase]; !ok || prev != limit {
+			ev.Limits[l.Phase] = limit
+			amperageLimitsUpdated = true
+		}
+	}
+
+	power := EVPowerLimitType{Min: limits.PowerMin, Max: limits.PowerMax, Source: limits.Source}
+	powerLimitsUpdated := ev.LimitsPower != power
+	ev.LimitsPower = power
+
+	ev.ElectricalLimits = limits
+
+	if amperageLimitsUpdated {
+		c.callDataUpdateHandler(EVDataElementUpdateAmperageLimits)
+	}
+	if powerLimitsUpdated {
+		c.callDataUpdateHandler(EVDataElementUpdatePowerLimits)
+	}
+}
+
 // TODO make this more generic, we assume that only one electric connection exists, that only single phases values are available and more
 func (c *ConnectionController) updateMeasurementData() {
 	var measurementDescription []feature.MeasurementDatasetDefinitionsType
 	var measurementData []feature.MeasurementDatasetDataType
 	var electricalParameterDescription []feature.ElectricalConnectionParameterDescriptionDataType
 	var electricalDescription []feature.ElectricalConnectionDatasetDataType
-	var electricalPermittedData []feature.ElectricalConnectionPermittedDataType
 
 	m := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM)).FeatureByProps(model.FeatureTypeEnumTypeMeasurement, model.RoleTypeClient)
 
@@ -264,7 +360,6 @@ func (c *ConnectionController) updateMeasurementData() {
 	if f, ok := e.(*feature.ElectricalConnection); ok {
 		electricalParameterDescription = f.GetElectricalConnectionDescription()
 		electricalDescription = f.GetElectricalConnectionData()
-		electricalPermittedData = f.GetElectricalConnectionPermittedData()
 	}
 
 	var measurementCurrentIds []uint
@@ -285,6 +380,9 @@ func (c *ConnectionController) updateMeasurementData() {
 		}
 	}
 
+	c.lockClientData()
+	defer c.unlockClientData()
+
 	for _, eItem := range electricalDescription {
 		if c.clientData.EVData.ConnectedPhases != eItem.ConnectedPhases {
 			c.clientData.EVData.ConnectedPhases = eItem.ConnectedPhases
@@ -304,114 +402,22 @@ func (c *ConnectionController) updateMeasurementData() {
 		}
 	}
 
-	// TODO this needs to be improved (a lot)
-	powerLimitsUpdated := false
-	amperageLimitsUpdated := false
-	for _, epdItem := range electricalPermittedData {
-		for _, epItem := range electricalParameterDescription {
-			if epItem.ParameterId == epdItem.ParameterId {
-				if epItem.ScopeType == model.ScopeTypeEnumTypeACPowerTotal {
-					if c.clientData.EVData.LimitsPower.Min != epdItem.MinValue {
-						c.clientData.EVData.LimitsPower.Min = epdItem.MinValue
-						powerLimitsUpdated = true
-					}
-					if c.clientData.EVData.LimitsPower.Max != epdItem.MaxValue {
-						c.clientData.EVData.LimitsPower.Max = epdItem.MaxValue
-						powerLimitsUpdated = true
-					}
-				} else if epItem.Phase > 0 {
-					phaseLimit, ok := c.clientData.EVData.Limits[epItem.Phase]
-					if !ok {
-						phaseLimit = EVCurrentLimitType{}
-						c.clientData.EVData.Limits[epItem.Phase] = phaseLimit
-					}
-
-					if phaseLimit.Min != epdItem.MinValue {
-						phaseLimit.Min = epdItem.MinValue
-						amperageLimitsUpdated = true
-					}
-					if phaseLimit.Max != epdItem.MaxValue {
-						phaseLimit.Max = epdItem.MaxValue
-						amperageLimitsUpdated = true
-					}
-					if phaseLimit.Default != epdItem.Value {
-						phaseLimit.Default = epdItem.Value
-						amperageLimitsUpdated = true
-					}
-					c.clientData.EVData.Limits[epItem.Phase] = phaseLimit
-				}
-			}
-		}
-	}
-
-	if amperageLimitsUpdated {
-		// Min current data should be derived from min power data
-		// but as this is only properly provided via VAS the currrent
-		// min values can not be trusted.
-		// Min current for 3-phase should be at least 2.2A (ISO)
-
-		minTotalPower := 0.0
-		var phase uint
-		for phase = 1; phase <= c.clientData.EVData.ConnectedPhases; phase++ {
-			minTotalPower += c.clientData.EVData.Limits[phase].Min * c.Voltage
-		}
-
-		minCurrent := 6.0
-		switch c.clientData.EVData.CommunicationStandard {
-		case EVCommunicationStandardEnumTypeISO151182ED1, EVCommunicationStandardEnumTypeISO151182ED2:
-			if c.clientData.EVData.ConnectedPhases == 3 {
-				minCurrent = 2.2
-			}
-		}
-
-		if minTotalPower < c.clientData.EVData.LimitsPower.Min {
-			// Adjust min current to match min power
-			minCurrent = c.clientData.EVData.LimitsPower.Min / c.Voltage / float64(c.clientData.EVData.ConnectedPhases)
-		}
-
-		var thePhase uint
-		for thePhase = 1; thePhase <= c.clientData.EVData.ConnectedPhases; thePhase++ {
-			if phaseLimit, ok := c.clientData.EVData.Limits[thePhase]; ok {
-				if phaseLimit.Min < minCurrent {
-					phaseLimit.Min = minCurrent
-					c.clientData.EVData.Limits[thePhase] = phaseLimit
-				}
-			}
-		}
-		c.callDataUpdateHandler(EVDataElementUpdateAmperageLimits)
-	}
-
-	if !powerLimitsUpdated || c.clientData.EVData.LimitsPower.Min <= 100.0 || c.clientData.EVData.LimitsPower.Max <= 100.0 {
-		// Min power data is only properly provided via VAS in ISO15118-2!
-		// So use the known min limits and calculate a more likely min power
-		var thePhase uint
-		var minPower, maxPower float64
-		for thePhase = 1; thePhase <= c.clientData.EVData.ConnectedPhases; thePhase++ {
-			if _, ok := c.clientData.EVData.Limits[thePhase]; !ok {
-				continue
-			}
-
-			minPower += c.clientData.EVData.Limits[thePhase].Min * c.Voltage
-			maxPower += c.clientData.EVData.Limits[thePhase].Max * c.Voltage
+	// limits are calculated from the permitted values of all parameters, power limits not reported
+	// by the EV are derived from the current limits using the measured voltage where available
+	if ec, ok := e.(*feature.ElectricalConnection); ok {
+		mf, _ := m.(*feature.Measurement)
+		if limits, ok := ec.ElectricalLimits(mf, c.Voltage); ok {
+			// the minimum currents reported by the EV are only reliable with ISO 15118-2 VAS
+			minCurrent := feature.MinimumCurrent(string(c.clientData.EVData.CommunicationStandard), limits.ConnectedPhases)
+			c.updateLimits(limits.ApplyMinimumCurrent(minCurrent, c.Voltage))
 		}
-		if c.clientData.EVData.LimitsPower.Min < minPower {
-			c.clientData.EVData.LimitsPower.Min = minPower
-		}
-		if c.clientData.EVData.LimitsPower.Max != maxPower {
-			c.clientData.EVData.LimitsPower.Max = maxPower
-		}
-
-		c.callDataUpdateHandler(EVDataElementUpdatePowerLimits)
 	}
 
 	c.log.Println("limits: ")
-	var thePhase uint
-	for thePhase = 1; thePhase <= c.clientData.EVData.ConnectedPhases; thePhase++ {
-		if phaseLimits, ok := c.clientData.EVData.Limits[thePhase]; ok {
-			c.log.Printf("  L%d current: min %.1fA, max %.1fA, pause %.1fA\n", thePhase, phaseLimits.Min, phaseLimits.Max, phaseLimits.Default)
-		}
+	for _, l := range c.clientData.EVData.ElectricalLimits.Phases {
+		c.log.Printf("  L%d current: min %.1fA, max %.1fA, pause %.1fA, power: min %.0fW, max %.0fW (%s)\n", l.Phase, l.CurrentMin, l.CurrentMax, l.CurrentDefault, l.PowerMin, l.PowerMax, l.Source)
 	}
-	c.log.Println("       Power: min ", c.clientData.EVData.LimitsPower.Min, "W, max ", c.clientData.EVData.LimitsPower.Max, "W")
+	c.log.Println("       Power: min ", c.clientData.EVData.LimitsPower.Min, "W, max ", c.clientData.EVData.LimitsPower.Max, "W (", c.clientData.EVData.LimitsPower.Source, ")")
 
 	for _, item := range measurementData {
 		if item.MeasurementId == measurementChargeID {
@@ -498,32 +504,69 @@ func (c *ConnectionController) UpdateMeasurementData(f *feature.Measurement) {
 }
 
 func (c *ConnectionController) UpdateIdentificationData(f *feature.Identification, data []feature.IdentificationDatasetDataType) {
+	c.lockClientData()
+	defer c.unlockClientData()
+
+	// in case of the EVSE-EV-communication dropping back from ISO to IEC, the values will be updated
+	// with empty strings, the previous identifications are kept in this case
+	var identifications []feature.IdentificationDatasetDataType
 	for _, item := range data {
-		// in case of the EVSE-EV-communication dropping back from ISO to IEC, this value will be updated
-		// with an empty string, so the previous identifier is lost
 		if len(item.IdentificationValue) > 0 {
-			c.clientData.EVData.Identification = item.IdentificationValue
-			c.log.Println("EV Identification: ", item.IdentificationValue)
+			identifications = append(identifications, item)
+			c.log.Printf("EV Identification: %s %s", item.IdentificationType, item.IdentificationValue)
 		}
 	}
+
+	if len(identifications) > 0 {
+		c.clientData.EVData.Identifications = identifications
+		c.clientData.EVData.Identification = identifications[len(identifications)-1].IdentificationValue
+	}
 }
 
-func (c *ConnectionController) UpdateUseCaseSupportData(f *feature.NodeManagement, useCasename model.UseCaseNameType, available bool) {
-	switch useCasename {
-	case model.UseCaseNameType(model.UseCaseNameEnumTypeEVStateOfCharge):
-		c.clientData.EVData.UCSoCAvailable = available
-		c.log.Println("SoC support: ", available)
+func (c *ConnectionController) UpdateUseCaseSupportData(f *feature.NodeManagement, event spine.UseCaseEvent) {
+	if event.Scenario == 0 && event.UseCase.Actor == model.UseCaseActorEnumTypeEV {
+		available := event.Supported
+
+		c.lockClientData()
+
+		switch event.UseCase.Name {
+		case model.UseCaseNameEnumTypeEVStateOfCharge:
+			c.clientData.EVData.UCSoCAvailable = available
+			c.log.Println("SoC support: ", available)
+			c.callDataUpdateHandler(EVDataElementUpdateUseCaseSoC)
+
+		case model.UseCaseNameEnumTypeOptimizationOfSelfConsumptionDuringEVCharging:
+			c.clientData.EVData.UCSelfConsumptionAvailable = available
+			c.log.Println("Self consumption support: ", available)
+			c.callDataUpdateHandler(EVDataElementUpdateUseCaseSelfConsumption)
+
+		case model.UseCaseNameEnumTypeCoordinatedEVCharging:
+			c.clientData.EVData.UCCoordinatedChargingAvailable = available
+			c.log.Println("Coordinated charging support: ", available)
+			c.callDataUpdateHandler(EVDataElementUpdateUseCaseCoordinatedCharging)
+		}
+
+		c.unlockClientData()
+	}
+
+	if event.Scenario != 0 && event.UseCase.Actor == model.UseCaseActorEnumTypeEV && event.UseCase.Name == model.UseCaseNameEnumTypeEVStateOfCharge {
+		c.lockClientData()
+
+		scenarios := lo.Reject(c.clientData.EVData.UCSoCScenarios, func(scenario model.UseCaseScenarioSupportType, _ int) bool {
+			return scenario == event.Scenario
+		})
+		if event.Supported {
+			scenarios = append(scenarios, event.Scenario)
+		}
+		c.clientData.EVData.UCSoCScenarios = scenarios
+		c.log.Println("SoC scenario", event.Scenario, "support: ", event.Supported)
 		c.callDataUpdateHandler(EVDataElementUpdateUseCaseSoC)
 
-	case model.UseCaseNameType(model.UseCaseNameEnumTypeOptimizationOfSelfConsumptionDuringEVCharging):
-		c.clientData.EVData.UCSelfConsumptionAvailable = available
-		c.log.Println("Self consumption support: ", available)
-		c.callDataUpdateHandler(EVDataElementUpdateUseCaseSelfConsumption)
+		c.unlockClientData()
+	}
 
-	case model.UseCaseNameType(model.UseCaseNameEnumTypeCoordinatedEVCharging):
-		c.clientData.EVData.UCCoordinatedChargingAvailable = available
-		c.log.Println("Coordinated charging support: ", available)
-		c.callDataUpdateHandler(EVDataElementUpdateUseCaseCoordinatedCharging)
+	if c.useCaseEventHandler != nil {
+		c.useCaseEventHandler(event)
 	}
 }
 
@@ -543,6 +586,9 @@ func (c *ConnectionController) UpdateDeviceClassificationData(f *feature.DeviceC
 	re := c.remoteEntityForFeatureAddress(rf)
 	entityType := model.EntityTypeEnumType(re.GetType())
 
+	c.lockClientData()
+	defer c.unlockClientData()
+
 	if entityType == model.EntityTypeEnumTypeEVSE {
 		if data.BrandName != nil {
 			c.clientData.EVSEData.Manufacturer.BrandName = string(*data.BrandName)
@@ -574,6 +620,9 @@ func (c *ConnectionController) UpdateLoadControlLimitData(f *feature.LoadControl
 		return
 	}
 
+	c.lockClientData()
+	defer c.unlockClientData()
+
 	for _, item := range limitDescriptionData {
 		for _, dataItem := range limitData {
 			if dataItem.LimitId == item.LimitId {
@@ -598,7 +647,9 @@ func (c *ConnectionController) UpdateTimeSeriesDescriptionData(f *feature.TimeSe
 		case model.TimeSeriesTypeEnumTypeConstraints:
 			if item.UpdateRequired {
 				// we need to send a response with a plan (within 20s or something like that)
+				c.lockClientData()
 				c.callDataUpdateHandler(EVDataElementUpdateChargingPlanRequired)
+				c.unlockClientData()
 			}
 			return
 		case model.TimeSeriesTypeEnumTypePlan:
@@ -612,6 +663,9 @@ func (c *ConnectionController) UpdateTimeSeriesDescriptionData(f *feature.TimeSe
 func (c *ConnectionController) UpdateTimeSeriesData(f *feature.TimeSeries, timeSeriesData feature.TimeSeriesDatasetType) {
 	timeSeriesDescriptionData := f.GetTimeSeriesDescriptionData()
 
+	c.lockClientData()
+	defer c.unlockClientData()
+
 	c.clientData.EVData.ChargingStrategy = EVChargingStrategyEnumTypeUnknown
 
 	if timeSeriesDescriptionData == nil {
@@ -685,13 +739,13 @@ func (c *ConnectionController) UpdateIncentiveConstraintsData(f *feature.Incenti
 	// now we need to reply with the incentiveTableDescription
 
 	if c.remoteDevice == nil {
-		// errors.New("charger is not connected")
+		// spine.ErrNotConnected
 		return
 	}
 
 	evEntity := c.remoteDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeEV))
 	if evEntity == nil {
-		// errors.New("no ev connected")
+		// spine.ErrNoEV
 		return
 	}
 
@@ -705,14 +759,17 @@ func (c *ConnectionController) UpdateIncentiveConstraintsData(f *feature.Incenti
 	}
 }
 
+// WriteChargingPlan sends the power limits and incentives of the charging plan to the EV.
+//
+// Deprecated: use CEVC of the usecase/ev package.
 func (c *ConnectionController) WriteChargingPlan(chargingPlan EVChargingPlan) error {
 	if c.remoteDevice == nil {
-		return errors.New("charger is not connected")
+		return spine.ErrNotConnected
 	}
 
 	evEntity := c.remoteDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeEV))
 	if evEntity == nil {
-		return errors.New("no ev connected")
+		return spine.ErrNoEV
 	}
 
 	ctx := c.context(nil)
@@ -722,10 +779,13 @@ func (c *ConnectionController) WriteChargingPlan(chargingPlan EVChargingPlan) er
 	l, ok := lf.(*feature.TimeSeries)
 
 	if !ok {
-		return errors.New("timeseries feature is not available on local device")
+		return fmt.Errorf("%w: timeseries on local device", spine.ErrFeatureNotSupported)
 	}
 
 	rf := evEntity.FeatureByProps(model.FeatureTypeEnumTypeTimeSeries, model.RoleTypeServer)
+	if rf == nil {
+		return fmt.Errorf("%w: timeseries on remote device", spine.ErrFeatureNotSupported)
+	}
 
 	timeSeriesSlots := []feature.TimeSeriesChargingSlot{}
 	for _, slot := range chargingPlan.Slots {
@@ -762,10 +822,13 @@ func (c *ConnectionController) WriteChargingPlan(chargingPlan EVChargingPlan) er
 	l2, ok := lf2.(*feature.IncentiveTable)
 
 	if !ok {
-		return errors.New("incentivetable feature is not available on local device")
+		return fmt.Errorf("%w: incentivetable on local device", spine.ErrFeatureNotSupported)
 	}
 
 	rf2 := evEntity.FeatureByProps(model.FeatureTypeEnumTypeIncentiveTable, model.RoleTypeServer)
+	if rf2 == nil {
+		return fmt.Errorf("%w: incentivetable on remote device", spine.ErrFeatureNotSupported)
+	}
 
 	incentiveSlots := []feature.IncentiveChargingSlot{}
 	for _, slot := range chargingPlan.Slots {
@@ -801,6 +864,8 @@ func (c *ConnectionController) WriteChargingPlan(chargingPlan EVChargingPlan) er
 }
 
 // TODO error handling and returning
+//
+// Deprecated: use OPEV and OSCEV of the usecase/ev package.
 func (c *ConnectionController) WriteCurrentLimitData(overloadProtectionCurrentsPerPhase []float64, selfConsumptionCurrentsPerPhase []float64, evData *EVDataType) error {
 	var electricalParameterDescription []feature.ElectricalConnectionParameterDescriptionDataType
 	var measurementDescription []feature.MeasurementDatasetDefinitionsType
@@ -822,19 +887,22 @@ func (c *ConnectionController) WriteCurrentLimitData(overloadProtectionCurrentsP
 	l, ok := lf.(*feature.LoadControl)
 
 	if !ok {
-		return errors.New("loadcontrol feature is not available on local device")
+		return fmt.Errorf("%w: loadcontrol on local device", spine.ErrFeatureNotSupported)
 	}
 
 	if c.remoteDevice == nil {
-		return errors.New("charger is not connected")
+		return spine.ErrNotConnected
 	}
 
 	evEntity := c.remoteDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeEV))
 	if evEntity == nil {
-		return errors.New("no ev connected")
+		return spine.ErrNoEV
 	}
 
 	rf := evEntity.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeServer)
+	if rf == nil {
+		return fmt.Errorf("%w: loadcontrol on remote device", spine.ErrFeatureNotSupported)
+	}
 
 	limitDescription = l.GetLoadControlLimitDescriptionData()
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/evcc-io/eebus/device/feature"
 	"github.com/evcc-io/eebus/spine"
 	"github.com/evcc-io/eebus/spine/model"
 )
@@ -43,10 +44,12 @@ func (c *ConnectionController) UpdateDevice(stateChange model.NetworkManagementS
 		c.log.Println("detected ev connection")
 
 		// a new EV is connected, so reset all data
+		c.lockClientData()
 		c.clientData.EVData = EVDataType{
 			ChargeState: EVChargeStateEnumTypeActive,
 			Limits:      make(map[uint]EVCurrentLimitType),
 		}
+		c.unlockClientData()
 
 		err := c.requestNodeManagementUseCaseData()
 		if err != nil {
@@ -72,11 +75,22 @@ func (c *ConnectionController) UpdateDevice(stateChange model.NetworkManagementS
 		if err != nil {
 			c.log.Println(msgCounter, err)
 		}
+
+		c.lockClientData()
 		c.callDataUpdateHandler(EVDataElementUpdateEVConnectionState)
+		c.unlockClientData()
+
+		c.callEVConnectionHandlers(true)
 	} else if !isEVConnected && stateChange == model.NetworkManagementStateChangeTypeRemoved {
 		c.log.Println("detected ev disconnection")
+		c.lockClientData()
 		c.clientData.EVData.ChargeState = EVChargeStateEnumTypeUnplugged
-		c.remoteDevice.ResetUseCaseActors()
+		c.unlockClientData()
+
+		// the EVSE's use cases remain valid
+		for _, event := range c.remoteDevice.RemoveRemoteUseCases(model.UseCaseActorEnumTypeEV) {
+			c.UpdateUseCaseSupportData(nil, event)
+		}
 
 		// reset all the EV relevant features data
 		for _, entity := range c.localDevice.GetEntities() {
@@ -85,7 +99,11 @@ func (c *ConnectionController) UpdateDevice(stateChange model.NetworkManagementS
 			}
 		}
 
+		c.lockClientData()
 		c.callDataUpdateHandler(EVDataElementUpdateEVConnectionState)
+		c.unlockClientData()
+
+		c.callEVConnectionHandlers(false)
 	}
 }
 
@@ -134,6 +152,90 @@ func (c *ConnectionController) requestNodeManagementUseCaseData() error {
 	return c.sendSpineMessage(datagram)
 }
 
+// notifyNodeManagementUseCaseData announces changed local use cases to a subscribed remote device
+func (c *ConnectionController) notifyNodeManagementUseCaseData() {
+	nodeMgmtF, featureDestination := c.remoteNodeManagementFeature()
+	nodeMgmt, ok := nodeMgmtF.(*feature.NodeManagement)
+	if !ok || !c.isNodeManagementSubscribed(nodeMgmtF) {
+		return
+	}
+
+	cmd := []model.CmdType{{
+		NodeManagementUseCaseData: nodeMgmt.UseCaseData(),
+	}}
+
+	if err := c.context(nil).Notify(spine.FeatureAddressType(nodeMgmtF), &featureDestination, cmd); err != nil {
+		c.log.Println("Sending UseCaseData notify failed!", err)
+	}
+}
+
+// notifyNodeManagementDetailedDiscoveryData announces added or removed local entities and features to a subscribed remote device
+func (c *ConnectionController) notifyNodeManagementDetailedDiscoveryData(change spine.DiscoveryChange) {
+	if change.Change == model.NetworkManagementStateChangeTypeRemoved {
+		c.removeSubscriptionsForDiscoveryChange(change)
+	}
+
+	nodeMgmtF, featureDestination := c.remoteNodeManagementFeature()
+	if !c.isNodeManagementSubscribed(nodeMgmtF) {
+		return
+	}
+
+	deviceAddress := c.localDevice.GetAddress()
+	stateChange := change.Change
+
+	data := model.NodeManagementDetailedDiscoveryDataType{
+		DeviceInformation: &model.NodeManagementDetailedDiscoveryDeviceInformationType{
+			Description: &model.NetworkManagementDeviceDescriptionDataType{
+				DeviceAddress: &model.DeviceAddressType{Device: &deviceAddress},
+			},
+		},
+	}
+
+	if change.Feature == nil {
+		ei := change.Entity.Information()
+		ei.Description.LastStateChange = &stateChange
+		data.EntityInformation = []model.NodeManagementDetailedDiscoveryEntityInformationType{*ei}
+
+		if stateChange == model.NetworkManagementStateChangeTypeAdded {
+			for _, f := range change.Entity.GetFeatures() {
+				data.FeatureInformation = append(data.FeatureInformation, *f.Information())
+			}
+		}
+	} else {
+		fi := change.Feature.Information()
+		fi.Description.LastStateChange = &stateChange
+		data.FeatureInformation = []model.NodeManagementDetailedDiscoveryFeatureInformationType{*fi}
+	}
+
+	function := model.FunctionType(model.FunctionEnumTypeNodeManagementDetailedDiscoveryData)
+	cmd := []model.CmdType{{
+		Function:                            &function,
+		Filter:                              []model.FilterType{*model.NewFilterTypePartial()},
+		NodeManagementDetailedDiscoveryData: &data,
+	}}
+
+	if err := c.context(nil).Notify(spine.FeatureAddressType(nodeMgmtF), &featureDestination, cmd); err != nil {
+		c.log.Println("Sending DetailedDiscoveryData notify failed!", err)
+	}
+}
+
+// isNodeManagementSubscribed checks if the connected remote device is subscribed to the local node management
+func (c *ConnectionController) isNodeManagementSubscribed(nodeMgmtF spine.Feature) bool {
+	if c.remoteDevice == nil || nodeMgmtF == nil || c.conn.IsConnectionClosed() {
+		return false
+	}
+
+	address := nodeMgmtF.GetAddress()
+	for _, item := range c.subscriptions() {
+		if item.ServerAddress != nil && reflect.DeepEqual(item.ServerAddress.Entity, address.Entity) &&
+			reflect.DeepEqual(item.ServerAddress.Feature, address.Feature) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *ConnectionController) remoteNodeManagementFeature() (spine.Feature, model.FeatureAddressType) {
 	deviceInfoE := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeDeviceInformation))
 	nodeMgmtF := deviceInfoE.FeatureByProps(model.FeatureTypeEnumTypeNodeManagement, model.RoleTypeSpecial)
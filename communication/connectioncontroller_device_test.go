@@ -0,0 +1,103 @@
+package communication
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+	"github.com/evcc-io/eebus/util"
+)
+
+func TestEVDisconnectClearsFeatureData(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	c := NewConnectionController(&util.NopLogger{}, nil, local)
+	c.SetDevice(&spine.DeviceImpl{Address: "d:_i:EVSE"})
+
+	functions := map[model.FeatureTypeEnumType]model.FunctionEnumType{
+		model.FeatureTypeEnumTypeDeviceConfiguration:  model.FunctionEnumTypeDeviceConfigurationKeyValueListData,
+		model.FeatureTypeEnumTypeElectricalConnection: model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData,
+		model.FeatureTypeEnumTypeIdentification:       model.FunctionEnumTypeIdentificationListData,
+		model.FeatureTypeEnumTypeIncentiveTable:       model.FunctionEnumTypeIncentiveTableData,
+		model.FeatureTypeEnumTypeLoadControl:          model.FunctionEnumTypeLoadControlLimitListData,
+		model.FeatureTypeEnumTypeMeasurement:          model.FunctionEnumTypeMeasurementListData,
+		model.FeatureTypeEnumTypeTimeSeries:           model.FunctionEnumTypeTimeSeriesListData,
+	}
+
+	for featureType, function := range functions {
+		cem.FeatureByProps(featureType, model.RoleTypeClient).SetData(function, struct{}{})
+	}
+
+	// all handlers are called
+	var disconnected int
+	for i := 0; i < 2; i++ {
+		c.AddEVConnectionHandler(func(connected bool) {
+			if !connected {
+				disconnected++
+			}
+		})
+	}
+
+	c.UpdateDevice(model.NetworkManagementStateChangeTypeRemoved)
+
+	if disconnected != 2 {
+		t.Errorf("expected ev disconnection for 2 handlers, got %d", disconnected)
+	}
+
+	for featureType, function := range functions {
+		if data := cem.FeatureByProps(featureType, model.RoleTypeClient).Data(function); data != nil {
+			t.Errorf("%s: expected data cleared, got %v", featureType, data)
+		}
+	}
+}
+
+func TestEVDisconnectRemovesEVUseCases(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	local.Add(entity.CEM())
+
+	remote := &spine.DeviceImpl{Address: "d:_i:EVSE"}
+	c := NewConnectionController(&util.NopLogger{}, nil, local)
+	c.SetDevice(remote)
+
+	var data model.NodeManagementUseCaseDataType
+	if err := json.Unmarshal([]byte(`[{"useCaseInformation":[[{"address":[{"device":"d:_i:EVSE"},{"entity":[1]}]},{"actor":"EVSE"},{"useCaseSupport":[[{"useCaseName":"evseCommissioningAndConfiguration"},{"useCaseAvailable":true},{"scenarioSupport":[1,2]}]]}],[{"address":[{"device":"d:_i:EVSE"},{"entity":[1,1]}]},{"actor":"EV"},{"useCaseSupport":[[{"useCaseName":"evStateOfCharge"},{"useCaseAvailable":true},{"scenarioSupport":[1,2]}]]}]]}]`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range remote.UpdateRemoteUseCases(data.UseCaseInformation, false) {
+		c.UpdateUseCaseSupportData(nil, event)
+	}
+
+	if ev := &c.clientData.EVData; !ev.UCSoCAvailable || len(ev.UCSoCScenarios) != 2 {
+		t.Fatalf("expected soc use case, got %v %v", ev.UCSoCAvailable, ev.UCSoCScenarios)
+	}
+
+	var events []spine.UseCaseEvent
+	c.SetUseCaseEventHandler(func(event spine.UseCaseEvent) {
+		events = append(events, event)
+	})
+
+	c.UpdateDevice(model.NetworkManagementStateChangeTypeRemoved)
+
+	// availability and both scenarios
+	if len(events) != 3 {
+		t.Errorf("expected 3 events, got %v", events)
+	}
+	for _, event := range events {
+		if event.UseCase.Actor != model.UseCaseActorEnumTypeEV || event.Supported {
+			t.Errorf("unexpected event: %v", event)
+		}
+	}
+
+	if ev := &c.clientData.EVData; ev.UCSoCAvailable || len(ev.UCSoCScenarios) != 0 {
+		t.Errorf("expected soc use case removed, got %v %v", ev.UCSoCAvailable, ev.UCSoCScenarios)
+	}
+
+	if !remote.SupportsUseCase(nil, model.UseCaseNameEnumTypeEVSECommissioningAndConfiguration, 2, "") {
+		t.Error("expected evse use case to remain")
+	}
+}
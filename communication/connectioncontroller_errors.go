@@ -0,0 +1,123 @@
+package communication
+
+import (
+	"strings"
+	"time"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// errorBufferSize is the number of errors buffered for the application
+const errorBufferSize = 16
+
+// Errors returns the channel errors are published to, e.g. processing errors or
+// writes rejected by the remote device. Errors are dropped if the channel is full.
+// The channel is closed when Run returns.
+func (c *ConnectionController) Errors() <-chan error {
+	return c.errorC
+}
+
+// publishError sends the error to the error channel without blocking
+func (c *ConnectionController) publishError(err error) {
+	c.errorMux.Lock()
+	defer c.errorMux.Unlock()
+
+	if c.errorClosed {
+		c.log.Println("error channel closed, dropping: ", err)
+		return
+	}
+
+	select {
+	case c.errorC <- err:
+	default:
+		c.log.Println("error channel full, dropping: ", err)
+	}
+}
+
+// closeErrors closes the error channel, later errors are dropped
+func (c *ConnectionController) closeErrors() {
+	c.errorMux.Lock()
+	defer c.errorMux.Unlock()
+
+	if !c.errorClosed {
+		c.errorClosed = true
+		close(c.errorC)
+	}
+}
+
+// pendingWriteTimeout is the time after which a write without result is forgotten
+const pendingWriteTimeout = time.Minute
+
+// pendingWrite is a write waiting for its result
+type pendingWrite struct {
+	function string // data names of the cmds
+	sent     time.Time
+}
+
+// addPendingWrite remembers a write until its result has been received and forgets expired ones
+func (c *ConnectionController) addPendingWrite(msgCounter model.MsgCounterType, cmd []model.CmdType) {
+	names := make([]string, 0, len(cmd))
+	for _, item := range cmd {
+		names = append(names, item.DataName())
+	}
+
+	c.pendingWritesMux.Lock()
+	defer c.pendingWritesMux.Unlock()
+
+	now := time.Now()
+	for counter, write := range c.pendingWrites {
+		if now.Sub(write.sent) > pendingWriteTimeout {
+			delete(c.pendingWrites, counter)
+		}
+	}
+
+	c.pendingWrites[msgCounter] = pendingWrite{function: strings.Join(names, ","), sent: now}
+}
+
+// removePendingWrite forgets a write, e.g. if it could not be sent
+func (c *ConnectionController) removePendingWrite(msgCounter model.MsgCounterType) {
+	c.pendingWritesMux.Lock()
+	defer c.pendingWritesMux.Unlock()
+
+	delete(c.pendingWrites, msgCounter)
+}
+
+// clearPendingWrites forgets all pending writes, their results can't arrive after the connection is closed
+func (c *ConnectionController) clearPendingWrites() {
+	c.pendingWritesMux.Lock()
+	defer c.pendingWritesMux.Unlock()
+
+	c.pendingWrites = make(map[model.MsgCounterType]pendingWrite)
+}
+
+// processWriteResult publishes a WriteRejectedError if the result refers to a pending write and contains an error
+func (c *ConnectionController) processWriteResult(datagram model.DatagramType) {
+	reference := datagram.Header.MsgCounterReference
+	if reference == nil || datagram.Header.CmdClassifier == nil || *datagram.Header.CmdClassifier != model.CmdClassifierTypeResult {
+		return
+	}
+
+	c.pendingWritesMux.Lock()
+	write, ok := c.pendingWrites[*reference]
+	delete(c.pendingWrites, *reference)
+	c.pendingWritesMux.Unlock()
+
+	if !ok {
+		return
+	}
+
+	for _, cmd := range datagram.Payload.Cmd {
+		if errType := model.NewErrorTypeFromResult(cmd.ResultData); errType != nil {
+			err := &spine.WriteRejectedError{
+				MsgCounter:  *reference,
+				Function:    write.function,
+				ErrorNumber: errType.ErrorNumber,
+				Description: errType.Description,
+			}
+
+			c.log.Println(err)
+			c.publishError(err)
+		}
+	}
+}
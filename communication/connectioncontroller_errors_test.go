@@ -0,0 +1,139 @@
+package communication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestProcessWriteResult(t *testing.T) {
+	c, conn := testController(t)
+
+	cem := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
+	sender := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient).GetAddress()
+	loadControl := c.remoteDevice.Entity([]model.AddressEntityType{1, 1}).Feature(1).GetAddress()
+
+	write := func() model.MsgCounterType {
+		if err := c.context(nil).Write(sender, loadControl, []model.CmdType{{LoadControlLimitListData: &model.LoadControlLimitListDataType{}}}); err != nil {
+			t.Fatal(err)
+		}
+		res := conn.written()
+		return *res[len(res)-1].Header.MsgCounter
+	}
+
+	result := func(msgCounter, reference model.MsgCounterType, errorNumber model.ErrorNumberType) {
+		header := testHeader(c, model.CmdClassifierTypeResult, msgCounter, model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+		header.MsgCounterReference = &reference
+
+		description := model.DescriptionType("limit out of range")
+		_ = c.processDatagram(model.DatagramType{
+			Header: header,
+			Payload: model.PayloadType{Cmd: []model.CmdType{
+				{ResultData: &model.ResultDataType{ErrorNumber: &errorNumber, Description: &description}},
+			}},
+		})
+	}
+
+	// successful writes are not published
+	result(1, write(), model.ErrorNumberTypeNoError)
+	select {
+	case err := <-c.Errors():
+		t.Errorf("unexpected error: %v", err)
+	default:
+	}
+
+	msgCounter := write()
+	result(2, msgCounter, model.ErrorNumberTypeCommandRejected)
+
+	select {
+	case err := <-c.Errors():
+		var rejected *spine.WriteRejectedError
+		if !errors.As(err, &rejected) || rejected.MsgCounter != msgCounter || rejected.Function != "loadControlLimitListData" || rejected.ErrorNumber != model.ErrorNumberTypeCommandRejected {
+			t.Errorf("unexpected error: %v", err)
+		}
+	default:
+		t.Fatal("expected rejected write")
+	}
+
+	// results are matched once
+	result(3, msgCounter, model.ErrorNumberTypeCommandRejected)
+	select {
+	case err := <-c.Errors():
+		if errors.Is(err, spine.ErrWriteRejected) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	default:
+	}
+}
+
+func TestPublishErrorNonBlocking(t *testing.T) {
+	c, _ := testController(t)
+
+	for i := 0; i < errorBufferSize+1; i++ {
+		c.publishError(errors.New("error"))
+	}
+
+	if len(c.Errors()) != errorBufferSize {
+		t.Errorf("expected %d buffered errors, got %d", errorBufferSize, len(c.Errors()))
+	}
+}
+
+func TestErrorsClosedAfterRun(t *testing.T) {
+	c, conn := testController(t)
+	_ = conn.Close()
+
+	c.Run()
+
+	if err := <-c.Errors(); !errors.Is(err, spine.ErrNotConnected) {
+		t.Errorf("expected not connected, got %v", err)
+	}
+
+	if _, ok := <-c.Errors(); ok {
+		t.Error("expected closed channel")
+	}
+
+	// errors published after closing are dropped
+	c.publishError(errors.New("late"))
+}
+
+func TestPendingWrites(t *testing.T) {
+	c, conn := testController(t)
+
+	limits := []model.CmdType{{LoadControlLimitListData: &model.LoadControlLimitListDataType{}}}
+
+	c.addPendingWrite(1, limits)
+	c.pendingWrites[1] = pendingWrite{sent: time.Now().Add(-2 * pendingWriteTimeout)}
+	c.addPendingWrite(2, limits)
+
+	if _, ok := c.pendingWrites[1]; ok {
+		t.Error("expected expired pending write removed")
+	}
+
+	// writes that could not be sent are not pending
+	cem := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
+	sender := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient).GetAddress()
+	loadControl := c.remoteDevice.Entity([]model.AddressEntityType{1, 1}).Feature(1).GetAddress()
+
+	conn.mux.Lock()
+	conn.fail = 1
+	conn.mux.Unlock()
+
+	ctx := c.context(nil)
+	if err := ctx.Write(sender, loadControl, limits); !errors.Is(err, errTestWrite) {
+		t.Errorf("expected write error, got %v", err)
+	}
+	if len(c.pendingWrites) != 1 {
+		t.Errorf("expected failed write not pending, got %v", c.pendingWrites)
+	}
+
+	// pending writes are cleared when the connection is closed
+	_ = conn.Close()
+	c.Run()
+
+	if len(c.pendingWrites) != 0 {
+		t.Errorf("expected pending writes cleared, got %v", c.pendingWrites)
+	}
+}
@@ -1,9 +1,12 @@
 package communication
 
 import (
-	"errors"
 	"sync"
 	"time"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
 )
 
 type ManufacturerDetails struct {
@@ -64,6 +67,7 @@ type EVCurrentLimitType struct {
 
 type EVPowerLimitType struct {
 	Min, Max float64
+	Source   feature.LimitSource
 }
 
 type EVChargingStrategyEnumType string
@@ -79,6 +83,7 @@ type EVDataType struct {
 	UCSelfConsumptionAvailable     bool
 	UCCoordinatedChargingAvailable bool
 	UCSoCAvailable                 bool
+	UCSoCScenarios                 []model.UseCaseScenarioSupportType // scenarios of the EV State of Charge use case supported by the EV
 	AsymetricChargingSupported     bool
 	CommunicationStandard          EVCommunicationStandardEnumType
 	OverloadProtectionActive       bool
@@ -89,10 +94,12 @@ type EVDataType struct {
 	ChargingDemand                 float64
 	ChargingTargetDuration         time.Duration
 	Manufacturer                   ManufacturerDetails
-	Identification                 string
+	Identification                 string // last non-empty identification value
+	Identifications                []feature.IdentificationDatasetDataType
 	ChargeState                    EVChargeStateEnumType
 	Limits                         map[uint]EVCurrentLimitType
 	LimitsPower                    EVPowerLimitType
+	ElectricalLimits               feature.ElectricalLimits // per phase and total limits with their provenance
 	Measurements                   EVMeasurementsType
 }
 
@@ -125,20 +132,162 @@ type EVChargingPlan struct {
 	Slots    []EVChargingSlot
 }
 
+// GetData returns a snapshot of the EVSE and EV data
+//
+// Deprecated: use the use cases of the usecase/ev package.
 func (c *ConnectionController) GetData() (*EVSEClientDataType, error) {
 	if c == nil {
-		return nil, errors.New("offline")
+		return nil, spine.ErrNotConnected
 	}
 
-	return c.clientData, nil
+	c.clientDataMux.RLock()
+	defer c.clientDataMux.RUnlock()
+
+	return c.clientData.snapshot(), nil
+}
+
+// snapshot returns a copy of the data which is not modified by later updates
+func (d *EVSEClientDataType) snapshot() *EVSEClientDataType {
+	ev := &d.EVData
+
+	res := &EVSEClientDataType{
+		EVSEData: d.EVSEData,
+		EVData: EVDataType{
+			UCSelfConsumptionAvailable:     ev.UCSelfConsumptionAvailable,
+			UCCoordinatedChargingAvailable: ev.UCCoordinatedChargingAvailable,
+			UCSoCAvailable:                 ev.UCSoCAvailable,
+			UCSoCScenarios:                 append([]model.UseCaseScenarioSupportType(nil), ev.UCSoCScenarios...),
+			AsymetricChargingSupported:     ev.AsymetricChargingSupported,
+			CommunicationStandard:          ev.CommunicationStandard,
+			OverloadProtectionActive:       ev.OverloadProtectionActive,
+			SelfConsumptionActive:          ev.SelfConsumptionActive,
+			SoCDataAvailable:               ev.SoCDataAvailable,
+			ConnectedPhases:                ev.ConnectedPhases,
+			ChargingStrategy:               ev.ChargingStrategy,
+			ChargingDemand:                 ev.ChargingDemand,
+			ChargingTargetDuration:         ev.ChargingTargetDuration,
+			Manufacturer:                   ev.Manufacturer,
+			Identification:                 ev.Identification,
+			Identifications:                append([]feature.IdentificationDatasetDataType(nil), ev.Identifications...),
+			ChargeState:                    ev.ChargeState,
+			Limits:                         make(map[uint]EVCurrentLimitType, len(ev.Limits)),
+			LimitsPower:                    ev.LimitsPower,
+			ElectricalLimits:               ev.ElectricalLimits,
+		},
+	}
+
+	for phase, limit := range ev.Limits {
+		res.EVData.Limits[phase] = limit
+	}
+	res.EVData.ElectricalLimits.Phases = append([]feature.PhaseLimits(nil), ev.ElectricalLimits.Phases...)
+
+	m := &res.EVData.Measurements
+	m.Timestamp = ev.Measurements.Timestamp
+	m.ChargedEnergy = ev.Measurements.ChargedEnergy
+	m.SoC = ev.Measurements.SoC
+
+	ev.Measurements.Current.Range(func(key, value any) bool {
+		m.Current.Store(key, value)
+		return true
+	})
+	ev.Measurements.Power.Range(func(key, value any) bool {
+		m.Power.Store(key, value)
+		return true
+	})
+
+	return res
 }
 
+// SetDataUpdateHandler sets the handler called for changes of the EVSE and EV data.
+//
+// Deprecated: use the use cases of the usecase/ev package.
 func (c *ConnectionController) SetDataUpdateHandler(dataUpdateHandler func(EVDataElementUpdateType, *EVSEClientDataType)) {
 	c.dataUpdateHandler = dataUpdateHandler
 }
 
+// AddEVConnectionHandler registers a handler called when an EV has been connected to or disconnected from the EVSE
+func (c *ConnectionController) AddEVConnectionHandler(evConnectionHandler func(connected bool)) {
+	c.evConnectionHandlerMux.Lock()
+	defer c.evConnectionHandlerMux.Unlock()
+
+	c.evConnectionHandlers = append(c.evConnectionHandlers, evConnectionHandler)
+}
+
+// callEVConnectionHandlers calls the EV connection handlers in the order they have been registered
+func (c *ConnectionController) callEVConnectionHandlers(connected bool) {
+	c.evConnectionHandlerMux.Lock()
+	handlers := append([]func(bool){}, c.evConnectionHandlers...)
+	c.evConnectionHandlerMux.Unlock()
+
+	for _, handler := range handlers {
+		handler(connected)
+	}
+}
+
+// SetUseCaseEventHandler sets the handler called for changes of the remote use cases and their scenarios
+func (c *ConnectionController) SetUseCaseEventHandler(useCaseEventHandler func(spine.UseCaseEvent)) {
+	c.useCaseEventHandler = useCaseEventHandler
+}
+
+// lockClientData locks the client data for modification
+func (c *ConnectionController) lockClientData() {
+	c.clientDataMux.Lock()
+}
+
+// unlockClientData unlocks the client data and calls the data update handler
+// for the updates queued in the meantime with a snapshot of the data
+func (c *ConnectionController) unlockClientData() {
+	updates := c.clientDataUpdates
+	c.clientDataUpdates = nil
+
+	var data *EVSEClientDataType
+	if len(updates) > 0 && c.dataUpdateHandler != nil {
+		data = c.clientData.snapshot()
+	}
+
+	c.clientDataMux.Unlock()
+
+	if data == nil {
+		return
+	}
+
+	for _, updateType := range updates {
+		c.dataUpdateHandler(updateType, data)
+	}
+}
+
+// SetHeartbeatLostHandler sets the handler called when no heartbeat has been received
+// from the remote device within the announced timeout
+func (c *ConnectionController) SetHeartbeatLostHandler(heartbeatLostHandler func(RemoteHeartbeat)) {
+	c.heartbeatMux.Lock()
+	defer c.heartbeatMux.Unlock()
+
+	c.heartbeatLostHandler = heartbeatLostHandler
+}
+
+// RemoteHeartbeat returns the last heartbeat received from the remote device and if it is still alive
+func (c *ConnectionController) RemoteHeartbeat() (RemoteHeartbeat, bool) {
+	c.heartbeatMux.Lock()
+	defer c.heartbeatMux.Unlock()
+
+	if c.remoteHeartbeat == nil {
+		return RemoteHeartbeat{}, false
+	}
+
+	return *c.remoteHeartbeat, !c.remoteHeartbeatLost
+}
+
+// callDataUpdateHandler queues the update until the client data is unlocked.
+// It must only be called while holding the client data lock.
 func (c *ConnectionController) callDataUpdateHandler(updateType EVDataElementUpdateType) {
-	if c.dataUpdateHandler != nil {
-		c.dataUpdateHandler(updateType, c.clientData)
+	c.clientDataUpdates = append(c.clientDataUpdates, updateType)
+}
+
+// RemoteCapabilities returns the functions and operations supported by the features of the remote device
+func (c *ConnectionController) RemoteCapabilities() ([]spine.FeatureCapability, error) {
+	if c == nil || c.remoteDevice == nil {
+		return nil, spine.ErrNotConnected
 	}
+
+	return spine.Capabilities(c.remoteDevice), nil
 }
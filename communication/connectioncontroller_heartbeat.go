@@ -1,65 +1,94 @@
 package communication
 
 import (
-	"os"
-	"os/signal"
-	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/evcc-io/eebus/spine/model"
 )
 
-// TODO heartBeatCounter should be global on CEM level, not on connection level
+const (
+	// DefaultHeartbeatInterval is the default interval for sending heartbeats to subscribers
+	DefaultHeartbeatInterval = 800 * time.Millisecond
+	// DefaultHeartbeatTimeout is the default timeout announced with the heartbeats
+	// and used for supervising remote heartbeats not announcing a timeout
+	DefaultHeartbeatTimeout = 4 * time.Second
+)
+
+// RemoteHeartbeat contains the last heartbeat received from the remote device
+type RemoteHeartbeat struct {
+	Counter   uint64
+	Timestamp time.Time     // local time the heartbeat has been received
+	Timeout   time.Duration // announced by the remote device or DefaultHeartbeatTimeout
+}
+
 func (c *ConnectionController) heartBeatCounter() *uint64 {
-	i := atomic.AddUint64(&c.heartBeatNum, 1)
+	i := c.localDevice.NextHeartbeatCounter()
 	return &i
 }
 
-func (c *ConnectionController) sendHearbeat(stopC chan struct{}, d time.Duration) {
-	ticker := time.NewTicker(d)
+// heartbeatData returns the local heartbeat data with the current counter
+func (c *ConnectionController) heartbeatData(counter *uint64) model.DeviceDiagnosisHeartbeatDataType {
+	if counter == nil {
+		i := c.localDevice.HeartbeatCounter()
+		counter = &i
+	}
+
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.9Z")
+
+	return model.DeviceDiagnosisHeartbeatDataType{
+		Timestamp:        &timestamp,
+		HeartbeatCounter: counter,
+		HeartbeatTimeout: model.NewISO8601Duration(c.HeartbeatTimeout),
+	}
+}
+
+// sendHeartbeat notifies all subscribers of the local DeviceDiagnosis server
+func (c *ConnectionController) sendHeartbeat() error {
+	ctx := c.context(nil)
+	localEntity := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
+
+	var data *model.DeviceDiagnosisHeartbeatDataType
+
+	// we could have multiple subscriptions, e.g. if they are coming in for local client and server roles (which is wrong, but anyway)
+	for _, item := range c.subscriptions() {
+		// check if this is a subscription to a local devicediagnosis feature
+		lfType, err := c.featureTypeForAddress(localEntity, item.ServerAddress)
+		if err != nil || lfType != model.FeatureTypeEnumTypeDeviceDiagnosis {
+			continue
+		}
+
+		// all subscribers receive the same heartbeat counter
+		if data == nil {
+			hb := c.heartbeatData(c.heartBeatCounter())
+			data = &hb
+		}
+
+		res := []model.CmdType{{
+			DeviceDiagnosisHeartbeatData: data,
+		}}
+
+		if err := ctx.Notify(item.ServerAddress, item.ClientAddress, res); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runHeartbeat sends heartbeats until the heartbeat is stopped
+func (c *ConnectionController) runHeartbeat(stopC chan struct{}) {
+	ticker := time.NewTicker(c.HeartbeatInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
-			var heartBeatTimeout string = "PT4S"
-
-			ctx := c.context(nil)
-
-			var senderAddr, destinationAddr *model.FeatureAddressType
-			localEntity := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
-
-			// we could have multiple subscriptions, e.g. if they are coming in for local client and server roles (which is wrong, but anyway)
-			for _, item := range c.subscriptionEntries {
-				// check if this is a subscription to a local devicediagnosis feature
-				lfType, err := c.featureTypeForAddress(localEntity, item.ServerAddress)
-				if err != nil {
-					continue
-				}
-
-				if lfType != model.FeatureTypeEnumTypeDeviceDiagnosis {
-					continue
-				}
-
-				senderAddr = item.ServerAddress
-				destinationAddr = item.ClientAddress
-				timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.9Z")
-
-				res := []model.CmdType{{
-					DeviceDiagnosisHeartbeatData: &model.DeviceDiagnosisHeartbeatDataType{
-						Timestamp:        &timestamp,
-						HeartbeatCounter: c.heartBeatCounter(),
-						HeartbeatTimeout: &heartBeatTimeout,
-					},
-				}}
-
-				// err := ctx.Notify(lf.GetAddress(), rf.GetAddress(), res)
-				err = ctx.Notify(senderAddr, destinationAddr, res)
-				if err != nil {
-					c.log.Println("ERROR sending heartbeat: ", err)
-					// TODO: when a connection is closed, we shouldn't get here
-					return
-				}
+			if err := c.sendHeartbeat(); err != nil {
+				c.log.Println("ERROR sending heartbeat: ", err)
+				// TODO: when a connection is closed, we shouldn't get here
+				return
 			}
+
 		case <-stopC:
 			return
 		}
@@ -76,6 +105,24 @@ func (c *ConnectionController) IsHeartbeatClosed() bool {
 	return false
 }
 
+// startHeartbeat starts sending heartbeats to subscribers of the local DeviceDiagnosis server
+func (c *ConnectionController) startHeartbeat() {
+	c.stopMux.Lock()
+	defer c.stopMux.Unlock()
+
+	if c.stopHeartbeatC != nil && !c.IsHeartbeatClosed() {
+		return
+	}
+
+	c.heartbeatMux.Lock()
+	c.heartbeatStopped = false
+	c.heartbeatMux.Unlock()
+
+	c.stopHeartbeatC = make(chan struct{})
+	go c.runHeartbeat(c.stopHeartbeatC)
+}
+
+// stopHeartbeat stops sending heartbeats and supervising the remote heartbeat
 func (c *ConnectionController) stopHeartbeat() {
 	c.stopMux.Lock()
 	defer c.stopMux.Unlock()
@@ -83,21 +130,77 @@ func (c *ConnectionController) stopHeartbeat() {
 	if c.stopHeartbeatC != nil && !c.IsHeartbeatClosed() {
 		close(c.stopHeartbeatC)
 	}
+
+	c.heartbeatMux.Lock()
+	defer c.heartbeatMux.Unlock()
+
+	c.heartbeatStopped = true
+
+	if c.remoteHeartbeatTimer != nil {
+		c.remoteHeartbeatTimer.Stop()
+	}
 }
 
-func (c *ConnectionController) startHeartBeatSend() {
-	c.stopHeartbeatC = make(chan struct{})
+// updateRemoteHeartbeat supervises the heartbeat received from the remote device.
+// A heartbeat with unchanged counter or received after stopping does not restart the supervision.
+func (c *ConnectionController) updateRemoteHeartbeat(data model.DeviceDiagnosisHeartbeatDataType) {
+	if data.HeartbeatCounter == nil {
+		return
+	}
 
-	go func() {
-		c.sendHearbeat(c.stopHeartbeatC, 800*time.Millisecond)
-	}()
+	timeout := DefaultHeartbeatTimeout
+	if data.HeartbeatTimeout != nil {
+		if d, err := model.GetISO8601Duration(*data.HeartbeatTimeout); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+
+	c.heartbeatMux.Lock()
+	defer c.heartbeatMux.Unlock()
 
-	// catch signals
-	go func() {
-		signalC := make(chan os.Signal, 1)
-		signal.Notify(signalC, os.Interrupt, syscall.SIGTERM)
+	if c.heartbeatStopped {
+		return
+	}
 
-		<-signalC // wait for signal
-		c.stopHeartbeat()
-	}()
+	if c.remoteHeartbeat != nil && c.remoteHeartbeat.Counter == *data.HeartbeatCounter {
+		return
+	}
+
+	if c.remoteHeartbeatLost {
+		c.log.Println("remote heartbeat resumed")
+	}
+
+	c.remoteHeartbeat = &RemoteHeartbeat{
+		Counter:   *data.HeartbeatCounter,
+		Timestamp: time.Now(),
+		Timeout:   timeout,
+	}
+	c.remoteHeartbeatLost = false
+
+	if c.remoteHeartbeatTimer != nil {
+		c.remoteHeartbeatTimer.Stop()
+	}
+
+	heartbeat := *c.remoteHeartbeat
+	c.remoteHeartbeatTimer = time.AfterFunc(timeout, func() {
+		c.remoteHeartbeatTimeout(heartbeat)
+	})
+}
+
+// remoteHeartbeatTimeout reports the heartbeat as lost unless a new heartbeat has been received
+func (c *ConnectionController) remoteHeartbeatTimeout(heartbeat RemoteHeartbeat) {
+	c.heartbeatMux.Lock()
+	if c.heartbeatStopped || c.remoteHeartbeat == nil || c.remoteHeartbeat.Counter != heartbeat.Counter || c.remoteHeartbeatLost {
+		c.heartbeatMux.Unlock()
+		return
+	}
+	c.remoteHeartbeatLost = true
+	handler := c.heartbeatLostHandler
+	c.heartbeatMux.Unlock()
+
+	c.log.Printf("remote heartbeat lost, last counter %d received at %s", heartbeat.Counter, heartbeat.Timestamp.Format(time.RFC3339))
+
+	if handler != nil {
+		handler(heartbeat)
+	}
 }
@@ -0,0 +1,65 @@
+package communication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestRemoteHeartbeatSupervision(t *testing.T) {
+	c, _ := testController(t)
+
+	lost := make(chan RemoteHeartbeat, 1)
+	c.SetHeartbeatLostHandler(func(heartbeat RemoteHeartbeat) {
+		lost <- heartbeat
+	})
+
+	heartbeat := func(counter uint64) {
+		c.updateRemoteHeartbeat(model.DeviceDiagnosisHeartbeatDataType{
+			HeartbeatCounter: &counter,
+			HeartbeatTimeout: model.NewISO8601Duration(100 * time.Millisecond),
+		})
+	}
+
+	heartbeat(1)
+	if hb, ok := c.RemoteHeartbeat(); !ok || hb.Counter != 1 || hb.Timeout != 100*time.Millisecond {
+		t.Errorf("unexpected heartbeat: %+v", hb)
+	}
+
+	// an unchanged counter does not restart the supervision
+	time.Sleep(60 * time.Millisecond)
+	heartbeat(1)
+
+	select {
+	case hb := <-lost:
+		if hb.Counter != 1 {
+			t.Errorf("unexpected lost heartbeat: %+v", hb)
+		}
+	case <-time.After(80 * time.Millisecond):
+		t.Fatal("expected heartbeat lost")
+	}
+
+	// a new counter resumes the supervision
+	heartbeat(2)
+	c.stopHeartbeat()
+
+	select {
+	case hb := <-lost:
+		t.Errorf("unexpected lost heartbeat after stop: %+v", hb)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// heartbeats received after stopping do not restart the supervision
+	heartbeat(3)
+
+	select {
+	case hb := <-lost:
+		t.Errorf("unexpected lost heartbeat after stop: %+v", hb)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	if hb, _ := c.RemoteHeartbeat(); hb.Counter != 2 {
+		t.Errorf("unexpected heartbeat after stop: %+v", hb)
+	}
+}
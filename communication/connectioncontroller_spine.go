@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"sync/atomic"
+	"strings"
 	"time"
 
 	"github.com/evcc-io/eebus/spine"
@@ -21,12 +21,79 @@ var cmdClassifierRequiresTransmissionAck = map[model.CmdClassifierType]bool{
 	model.CmdClassifierTypeResult: false,
 }
 
-// TODO check if msgCounter value should also be global on CEM level and not on connection level
+// msgCounter returns the next message counter of the local device, shared by all connections
 func (c *ConnectionController) msgCounter() *model.MsgCounterType {
-	i := model.MsgCounterType(atomic.AddUint64(&c.msgNum, 1))
+	i := c.localDevice.NextMsgCounter()
 	return &i
 }
 
+// partialReadTimeout is the time after which a partial read without reply is forgotten
+const partialReadTimeout = time.Minute
+
+// addPartialRead remembers a read request restricted by selectors or elements and forgets expired ones
+func (c *ConnectionController) addPartialRead(msgCounter model.MsgCounterType) {
+	c.partialReadsMux.Lock()
+	defer c.partialReadsMux.Unlock()
+
+	now := time.Now()
+	for counter, sent := range c.partialReads {
+		if now.Sub(sent) > partialReadTimeout {
+			delete(c.partialReads, counter)
+		}
+	}
+
+	c.partialReads[msgCounter] = now
+}
+
+// isPartialRead checks and clears if the reference belongs to a partial read request
+func (c *ConnectionController) isPartialRead(msgCounterReference *model.MsgCounterType) bool {
+	if msgCounterReference == nil {
+		return false
+	}
+
+	c.partialReadsMux.Lock()
+	defer c.partialReadsMux.Unlock()
+
+	_, ok := c.partialReads[*msgCounterReference]
+	delete(c.partialReads, *msgCounterReference)
+
+	return ok
+}
+
+// clearPartialReads forgets all pending partial reads, their replies can't arrive after the connection is closed
+func (c *ConnectionController) clearPartialReads() {
+	c.partialReadsMux.Lock()
+	defer c.partialReadsMux.Unlock()
+
+	c.partialReads = make(map[model.MsgCounterType]time.Time)
+}
+
+// checkRemoteOperation fails if the remote feature does not support reading or writing the cmds.
+// Requests are not checked before the remote device has been discovered.
+func (c *ConnectionController) checkRemoteOperation(op model.CmdClassifierType, destinationAddress *model.FeatureAddressType, cmd []model.CmdType) error {
+	if c.remoteDevice == nil || destinationAddress == nil || destinationAddress.Feature == nil {
+		return nil
+	}
+
+	remoteEntity := c.remoteDevice.Entity(destinationAddress.Entity)
+	if remoteEntity == nil {
+		return nil
+	}
+
+	remoteFeature := remoteEntity.Feature(uint(*destinationAddress.Feature))
+	if remoteFeature == nil {
+		return fmt.Errorf("%w: no feature %d on entity %v", spine.ErrFeatureNotSupported, *destinationAddress.Feature, destinationAddress.Entity)
+	}
+
+	for _, item := range cmd {
+		if err := spine.CheckFunctionOperation(remoteFeature, model.FunctionEnumType(item.DataName()), op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *ConnectionController) sendSpineMessage(datagram model.DatagramType) error {
 	data := &model.CmiDatagramType{
 		Datagram: datagram,
@@ -38,19 +105,33 @@ func (c *ConnectionController) sendSpineMessage(datagram model.DatagramType) err
 	}
 
 	cmdClassifier := datagram.Header.CmdClassifier
-	cmd := datagram.Payload.Cmd[0]
 
 	destinationAddress := datagram.Header.AddressDestination
 	if c.remoteDevice != nil {
+		if destinationAddress == nil {
+			return fmt.Errorf("sendSpineMessage: %w: missing remote address", spine.ErrNotConnected)
+		}
+
 		remoteEntity := c.remoteDevice.Entity(destinationAddress.Entity)
 		if remoteEntity == nil {
-			return errors.New("sendSpineMessage: invalid remote entity address")
+			return fmt.Errorf("sendSpineMessage: %w: invalid remote entity address %v", spine.ErrNotConnected, destinationAddress.Entity)
 		}
-		remoteFeature := remoteEntity.Feature(uint(*destinationAddress.Feature))
-		c.log.Printf("send: %s %s:%s %s", *cmdClassifier, remoteEntity.GetType(), remoteFeature.GetType(), c.cmdDetails(cmd))
-	} else {
-		c.log.Printf("send: %s %s", *cmdClassifier, c.cmdDetails(cmd))
 
+		// the feature may not have been discovered yet
+		var featureType model.FeatureTypeEnumType
+		if destinationAddress.Feature != nil {
+			if remoteFeature := remoteEntity.Feature(uint(*destinationAddress.Feature)); remoteFeature != nil {
+				featureType = remoteFeature.GetType()
+			}
+		}
+
+		for _, cmd := range datagram.Payload.Cmd {
+			c.log.Printf("send: %s %s:%s %s", *cmdClassifier, remoteEntity.GetType(), featureType, c.cmdDetails(cmd))
+		}
+	} else {
+		for _, cmd := range datagram.Payload.Cmd {
+			c.log.Printf("send: %s %s", *cmdClassifier, c.cmdDetails(cmd))
+		}
 	}
 
 	err = c.conn.Write(json.RawMessage(payload))
@@ -68,13 +149,20 @@ func (c *ConnectionController) sendAcknowledgementMessage(err error, featureSour
 	var resultData model.ResultDataType
 
 	if err == nil {
-		resultSuccess = 0
+		resultSuccess = model.ErrorNumberTypeNoError
 		resultData = model.ResultDataType{
 			ErrorNumber: &resultSuccess,
 		}
 	} else {
-		resultSuccess = 1
+		resultSuccess = model.ErrorNumberTypeGeneralError
 		resultDescription = model.DescriptionType(err.Error())
+
+		var errType *model.ErrorType
+		if errors.As(err, &errType) {
+			resultSuccess = errType.ErrorNumber
+			resultDescription = errType.Description
+		}
+
 		resultData = model.ResultDataType{
 			ErrorNumber: &resultSuccess,
 			Description: &resultDescription,
@@ -101,46 +189,44 @@ func (c *ConnectionController) sendAcknowledgementMessage(err error, featureSour
 }
 
 func (c *ConnectionController) processDatagram(datagram model.DatagramType) error {
-	// TODO validate datagram.Header.AddressSource
-
-	destAddr := datagram.Header.AddressDestination
-	if destAddr == nil {
-		return errors.New("processDatagram: invalid datagram.Header.AddressDestination")
+	// re-delivered datagrams are not processed twice
+	if msgCounter := datagram.Header.MsgCounter; msgCounter != nil && !c.receivedMsgCounters.add(*msgCounter) {
+		return fmt.Errorf("processDatagram: duplicate or outdated msgCounter %d", *msgCounter)
 	}
 
-	// if destDevice is empty assume it's us
-	destDevice := destAddr.Device
-	if destDevice != nil {
-		if c.localDevice.GetAddress() != *destDevice {
-			return nil // not us
+	// rejected writes are published to the application
+	c.processWriteResult(datagram)
+
+	entity, feature, err := c.validateDatagram(datagram)
+	if err != nil {
+		// results can only be sent to a complete source address
+		if src := datagram.Header.AddressSource; expectsErrorResult(datagram.Header) && src != nil && src.Entity != nil && src.Feature != nil {
+			featureSource := datagram.Header.AddressDestination
+			featureDestination := datagram.Header.AddressSource
+			if ackErr := c.sendAcknowledgementMessage(err, featureSource, featureDestination, datagram.Header.MsgCounter); ackErr != nil {
+				return ackErr
+			}
 		}
-	}
 
-	if destAddr.Entity == nil {
-		return errors.New("processDatagram: invalid datagram.Header.AddressDestination.Entity")
+		return fmt.Errorf("processDatagram: %w", err)
 	}
 
-	entity := c.localDevice.Entity(destAddr.Entity)
-	if entity == nil {
-		return errors.New("processDatagram: invalid entity address")
-	}
+	// replies to partial reads do not necessarily repeat the filter
+	partialRead := *datagram.Header.CmdClassifier == model.CmdClassifierTypeReply && c.isPartialRead(datagram.Header.MsgCounterReference)
 
-	featureID := destAddr.Feature
-	if featureID == nil {
-		return errors.New("processDatagram: invalid datagram.Header.AddressDestination.Feature")
-	}
-
-	feature := entity.Feature(uint(*featureID))
-	if feature == nil {
-		return errors.New("processDatagram: invalid feature address")
+	// process all cmds in order and acknowledge them with a single result
+	var errs []error
+	for _, cmd := range datagram.Payload.Cmd {
+		if err := c.processCmd(datagram, entity, feature, cmd, partialRead); err != nil {
+			errs = append(errs, err)
+		}
 	}
-
-	err := c.processCmd(datagram, entity, feature)
+	err = aggregateErrors(errs)
 
 	// handle processing success acknowledgement message. Protocol 5.2.4 & 5.2.5
 	cmdClassifier := datagram.Header.CmdClassifier
 	ackRequest := datagram.Header.AckRequest != nil && *datagram.Header.AckRequest
-	if !cmdClassifierRequiresTransmissionAck[*cmdClassifier] && ackRequest {
+	if !cmdClassifierRequiresTransmissionAck[*cmdClassifier] && ackRequest || err != nil && expectsErrorResult(datagram.Header) {
 		featureSource := datagram.Header.AddressDestination
 		featureDestination := datagram.Header.AddressSource
 		msgCounter := datagram.Header.MsgCounter
@@ -190,7 +276,7 @@ func (c *ConnectionController) sendRequestToEVForFeatureAndFunction(featureType
 
 	rf := re.FeatureByProps(featureType, model.RoleTypeServer)
 	if rf == nil {
-		return nil, fmt.Errorf("remote entity server feature not found: %s", featureType)
+		return nil, fmt.Errorf("%w: remote entity server feature not found: %s", spine.ErrFeatureNotSupported, featureType)
 	}
 
 	ctx := c.context(nil)
@@ -199,6 +285,12 @@ func (c *ConnectionController) sendRequestToEVForFeatureAndFunction(featureType
 
 func (c *ConnectionController) cmdDetails(cmd model.CmdType) string {
 	switch {
+	case cmd.BillConstraintsListData != nil:
+		return "BillConstraintsListData"
+	case cmd.BillDescriptionListData != nil:
+		return "BillDescriptionListData"
+	case cmd.BillListData != nil:
+		return "BillListData"
 	case cmd.DeviceClassificationManufacturerData != nil:
 		return "DeviceClassificationManufacturerData"
 	case cmd.DeviceConfigurationKeyValueDescriptionListData != nil:
@@ -248,6 +340,8 @@ func (c *ConnectionController) cmdDetails(cmd model.CmdType) string {
 	case cmd.MeasurementListData != nil:
 		return "MeasurementListData"
 	case cmd.TimeSeriesConstraintsData != nil:
+		return "TimeSeriesConstraintsData"
+	case cmd.TimeSeriesConstraintsListData != nil:
 		return "TimeSeriesConstraintsListData"
 	case cmd.TimeSeriesDescriptionListData != nil:
 		return "TimeSeriesDescriptionListData"
@@ -260,35 +354,47 @@ func (c *ConnectionController) cmdDetails(cmd model.CmdType) string {
 	return "unknown"
 }
 
-func (c *ConnectionController) processCmd(datagram model.DatagramType, localEntity spine.Entity, localFeature spine.Feature) error {
+func (c *ConnectionController) processCmd(datagram model.DatagramType, localEntity spine.Entity, localFeature spine.Feature, cmd model.CmdType, partialRead bool) error {
 	cmdClassifier := datagram.Header.CmdClassifier
-	if cmdClassifier == nil {
-		return errors.New("processCmd: invalid datagram.Header.CmdClassifier")
+
+	c.log.Printf("recv: %s %s:%s %s", *cmdClassifier, localEntity.GetType(), localFeature.GetType(), c.cmdDetails(cmd))
+
+	filterPartial, _ := cmd.ExtractFilter()
+
+	if filterPartial == nil && partialRead {
+		filterPartial = model.NewFilterTypePartial()
+		cmd.Filter = append(cmd.Filter, *filterPartial)
 	}
 
-	if len(datagram.Payload.Cmd) != 1 {
-		return errors.New("processCmd: invalid datagram.Payload.Cmd")
+	isPartial := filterPartial != nil
+
+	return localFeature.Handle(c.context(&datagram), *datagram.Header.AddressSource, *cmdClassifier, cmd, isPartial)
+}
+
+// aggregateErrors combines the errors of multiple cmds into a single error using the first error's number
+func aggregateErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
 	}
-	cmd := datagram.Payload.Cmd[0]
 
-	c.log.Printf("recv: %s %s:%s %s", *cmdClassifier, localEntity.GetType(), localFeature.GetType(), c.cmdDetails(cmd))
+	errorNumber := model.ErrorNumberTypeGeneralError
+	var errType *model.ErrorType
+	if errors.As(errs[0], &errType) {
+		errorNumber = errType.ErrorNumber
+	}
 
-	// isPartial
-	isPartial := false
-	functionCmd := cmd.Function
-	filterCmd := cmd.Filter
-
-	if functionCmd != nil && filterCmd != nil {
-		// TODO check if the function is the same as the provided cmd value
-		if len(filterCmd) > 0 {
-			for _, filter := range filterCmd {
-				if filter.CmdControl.Partial != nil {
-					isPartial = true
-					break
-				}
-			}
+	descriptions := make([]string, 0, len(errs))
+	for _, err := range errs {
+		// the error number is not repeated in the description
+		if errors.As(err, &errType) {
+			descriptions = append(descriptions, string(errType.Description))
+			continue
 		}
+		descriptions = append(descriptions, err.Error())
 	}
 
-	return localFeature.Handle(c.context(&datagram), *datagram.Header.AddressSource, *cmdClassifier, cmd, isPartial)
+	return model.NewErrorType(errorNumber, strings.Join(descriptions, "; "))
 }
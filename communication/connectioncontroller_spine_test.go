@@ -0,0 +1,177 @@
+package communication
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestProcessDatagramErrorResultAddress(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		header  string
+		results int
+	}{
+		{
+			"missing source",
+			`{"specificationVersion":"1.2.0"},{"addressDestination":[{"entity":[9]},{"feature":1}]},{"msgCounter":1},{"cmdClassifier":"read"}`,
+			0,
+		},
+		{
+			"unknown source feature",
+			`{"specificationVersion":"1.2.0"},{"addressSource":[{"entity":[1,1]},{"feature":99}]},{"addressDestination":[{"entity":[9]},{"feature":1}]},{"msgCounter":2},{"cmdClassifier":"read"}`,
+			1,
+		},
+	} {
+		c, conn := testController(t)
+		datagram := testDatagram(t, tc.header, `{"cmd":[[{"loadControlLimitListData":[]}]]}`)
+
+		if err := c.processDatagram(datagram); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+
+		if res := conn.written(); len(res) != tc.results {
+			t.Errorf("%s: expected %d results, got %d", tc.name, tc.results, len(res))
+		}
+	}
+}
+
+func TestProcessDatagramMultipleCmds(t *testing.T) {
+	c, conn := testController(t)
+
+	limitId := model.LoadControlLimitIdType(1)
+	ackRequest := true
+
+	header := testHeader(c, model.CmdClassifierTypeNotify, 1, model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	header.AckRequest = &ackRequest
+
+	datagram := model.DatagramType{
+		Header: header,
+		Payload: model.PayloadType{Cmd: []model.CmdType{
+			{LoadControlLimitDescriptionListData: &model.LoadControlLimitDescriptionListDataType{
+				LoadControlLimitDescriptionData: []model.LoadControlLimitDescriptionDataType{{LimitId: &limitId}},
+			}},
+			{MeasurementListData: &model.MeasurementListDataType{}},
+			{LoadControlLimitListData: &model.LoadControlLimitListDataType{
+				LoadControlLimitData: []model.LoadControlLimitDataType{{LimitId: &limitId, Value: model.NewScaledNumberType(16)}},
+			}},
+			{IdentificationListData: &model.IdentificationListDataType{}},
+		}},
+	}
+
+	err := c.processDatagram(datagram)
+
+	var errType *model.ErrorType
+	if !errors.As(err, &errType) || strings.Count(string(errType.Description), "not implemented") != 2 {
+		t.Errorf("expected aggregated error, got %v", err)
+	}
+
+	// cmds following a failed cmd are processed
+	lc := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM)).FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	for _, function := range []model.FunctionEnumType{model.FunctionEnumTypeLoadControlLimitDescriptionListData, model.FunctionEnumTypeLoadControlLimitListData} {
+		if lc.Data(function) == nil {
+			t.Errorf("%s: not processed", function)
+		}
+	}
+
+	// a single result answers all cmds
+	res := conn.written()
+	if len(res) != 1 || res[0].Payload.Cmd[0].ResultData == nil || *res[0].Header.MsgCounterReference != 1 {
+		t.Fatalf("expected single result, got %v", res)
+	}
+	if result := model.NewErrorTypeFromResult(res[0].Payload.Cmd[0].ResultData); result == nil || result.Description != errType.Description {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestProcessDatagramDuplicateMsgCounter(t *testing.T) {
+	c, _ := testController(t)
+
+	datagram := model.DatagramType{
+		Header: testHeader(c, model.CmdClassifierTypeNotify, 5, model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient),
+		Payload: model.PayloadType{Cmd: []model.CmdType{
+			{LoadControlLimitListData: &model.LoadControlLimitListDataType{}},
+		}},
+	}
+
+	if err := c.processDatagram(datagram); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.processDatagram(datagram); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	single := errors.New("single")
+	if err := aggregateErrors(nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := aggregateErrors([]error{single}); err != single {
+		t.Errorf("expected single error unchanged, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		errs        []error
+		errorNumber model.ErrorNumberType
+	}{
+		{[]error{model.NewErrorType(model.ErrorNumberTypeCommandRejected, "a"), errors.New("b")}, model.ErrorNumberTypeCommandRejected},
+		{[]error{errors.New("a"), model.NewErrorType(model.ErrorNumberTypeCommandRejected, "b")}, model.ErrorNumberTypeGeneralError},
+	} {
+		var errType *model.ErrorType
+		if err := aggregateErrors(tc.errs); !errors.As(err, &errType) || errType.ErrorNumber != tc.errorNumber || errType.Description != "a; b" {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestPartialReads(t *testing.T) {
+	c, _ := testController(t)
+
+	c.addPartialRead(1)
+	c.partialReads[1] = time.Now().Add(-2 * partialReadTimeout)
+	c.addPartialRead(2)
+
+	if _, ok := c.partialReads[1]; ok {
+		t.Error("expected expired partial read removed")
+	}
+
+	ref := model.MsgCounterType(2)
+	if !c.isPartialRead(&ref) || c.isPartialRead(&ref) {
+		t.Error("expected partial read matched once")
+	}
+
+	c.addPartialRead(3)
+	c.clearPartialReads()
+
+	if ref := model.MsgCounterType(3); c.isPartialRead(&ref) {
+		t.Error("expected partial reads cleared")
+	}
+}
+
+func TestSendSpineMessageInvalidAddress(t *testing.T) {
+	c, conn := testController(t)
+
+	cmdClassifier := model.CmdClassifierTypeRead
+	for _, destination := range []*model.FeatureAddressType{nil, {Entity: []model.AddressEntityType{9}}} {
+		datagram := model.DatagramType{
+			Header: model.HeaderType{
+				CmdClassifier:      &cmdClassifier,
+				AddressDestination: destination,
+			},
+		}
+
+		if err := c.sendSpineMessage(datagram); !errors.Is(err, spine.ErrNotConnected) {
+			t.Errorf("%v: expected not connected, got %v", destination, err)
+		}
+	}
+
+	if res := conn.written(); len(res) != 0 {
+		t.Errorf("expected nothing written, got %v", res)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"sync/atomic"
 
+	"github.com/evcc-io/eebus/spine"
 	"github.com/evcc-io/eebus/spine/model"
 )
 
@@ -85,20 +86,18 @@ func (c *ConnectionController) addSubscription(data model.SubscriptionManagement
 		ServerAddress:  data.ServerAddress,
 	}
 
+	c.subscriptionMux.Lock()
 	c.subscriptionEntries = append(c.subscriptionEntries, subscriptionEntry)
+	c.subscriptionMux.Unlock()
 
 	if model.FeatureTypeEnumType(*data.ServerFeatureType) == model.FeatureTypeEnumTypeDeviceDiagnosis {
-		c.startHeartBeatSend()
+		c.startHeartbeat()
 	}
 
 	return nil
 }
 
 func (c *ConnectionController) removeSubscription(data model.SubscriptionManagementDeleteCallType) error {
-	// TODO: test this!!!
-
-	var newSubscriptionEntries []model.SubscriptionManagementEntryDataType
-
 	// according to the spec 7.4.4
 	// a. The absence of "subscriptionDelete. clientAddress. device" SHALL be treated as if it was
 	//    present and set to the sender's "device" address part.
@@ -106,31 +105,105 @@ func (c *ConnectionController) removeSubscription(data model.SubscriptionManagem
 	//    present and set to the recipient's "device" address part.
 
 	clientAddress := data.ClientAddress
-	if data.ClientAddress.Device == nil {
-		clientAddress.Device = c.remoteDevice.Information().Description.DeviceAddress.Device
+	if clientAddress != nil && clientAddress.Device == nil && c.remoteDevice != nil {
+		device := c.remoteDevice.GetAddress()
+		clientAddress = &model.FeatureAddressType{Device: &device, Entity: clientAddress.Entity, Feature: clientAddress.Feature}
 	}
 
 	serverAddress := data.ServerAddress
-	if data.ServerAddress.Device == nil {
-		serverAddress.Device = c.localDevice.Information().Description.DeviceAddress.Device
+	if serverAddress != nil && serverAddress.Device == nil {
+		device := c.localDevice.GetAddress()
+		serverAddress = &model.FeatureAddressType{Device: &device, Entity: serverAddress.Entity, Feature: serverAddress.Feature}
 	}
 
-	for _, item := range c.subscriptionEntries {
+	// absent elements match any subscription
+	removed := c.removeSubscriptionEntries(func(item model.SubscriptionManagementEntryDataType) bool {
+		return (data.SubscriptionId == nil || item.SubscriptionId != nil && *item.SubscriptionId == *data.SubscriptionId) &&
+			(clientAddress == nil || featureAddressMatches(item.ClientAddress, clientAddress)) &&
+			(serverAddress == nil || featureAddressMatches(item.ServerAddress, serverAddress))
+	})
 
-		if reflect.DeepEqual(item.ClientAddress, clientAddress) {
-			newSubscriptionEntries = append(newSubscriptionEntries, item)
+	if removed == 0 {
+		return errors.New("could not find requested SubscriptionId to be removed")
+	}
+
+	return nil
+}
+
+// removeSubscriptionsForDiscoveryChange removes subscriptions to a removed local entity or feature
+func (c *ConnectionController) removeSubscriptionsForDiscoveryChange(change spine.DiscoveryChange) {
+	entityAddress := change.Entity.GetAddress()
+
+	removed := c.removeSubscriptionEntries(func(item model.SubscriptionManagementEntryDataType) bool {
+		address := item.ServerAddress
+		if address == nil || len(address.Entity) < len(entityAddress) || !reflect.DeepEqual(address.Entity[:len(entityAddress)], entityAddress) {
+			return false
 		}
+
+		if change.Feature == nil {
+			return true
+		}
+
+		return reflect.DeepEqual(address.Entity, entityAddress) && address.Feature != nil && uint(*address.Feature) == change.Feature.GetID()
+	})
+
+	if removed > 0 {
+		c.log.Printf("removed %d subscriptions", removed)
 	}
+}
 
-	if len(newSubscriptionEntries) == len(c.subscriptionEntries) {
-		return errors.New("could not find requested SubscriptionId to be removed")
+// removeSubscriptionEntries removes all subscriptions matching and returns their number
+func (c *ConnectionController) removeSubscriptionEntries(match func(model.SubscriptionManagementEntryDataType) bool) int {
+	c.subscriptionMux.Lock()
+	defer c.subscriptionMux.Unlock()
+
+	var newSubscriptionEntries []model.SubscriptionManagementEntryDataType
+	for _, item := range c.subscriptionEntries {
+		if !match(item) {
+			newSubscriptionEntries = append(newSubscriptionEntries, item)
+		}
 	}
 
+	removed := len(c.subscriptionEntries) - len(newSubscriptionEntries)
 	c.subscriptionEntries = newSubscriptionEntries
 
-	return nil
+	return removed
+}
+
+// featureAddressMatches compares entity and feature of both addresses and the device if present in both
+func featureAddressMatches(a, b *model.FeatureAddressType) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if a.Device != nil && b.Device != nil && *a.Device != *b.Device {
+		return false
+	}
+
+	return reflect.DeepEqual(a.Entity, b.Entity) && reflect.DeepEqual(a.Feature, b.Feature)
 }
 
 func (c *ConnectionController) subscriptions() []model.SubscriptionManagementEntryDataType {
-	return c.subscriptionEntries
+	c.subscriptionMux.Lock()
+	defer c.subscriptionMux.Unlock()
+
+	return append([]model.SubscriptionManagementEntryDataType(nil), c.subscriptionEntries...)
+}
+
+// NotifySubscribers sends the cmd to all remote subscribers of the local server feature
+func (c *ConnectionController) NotifySubscribers(lf spine.Feature, cmd []model.CmdType) error {
+	ctx := c.context(nil)
+	address := spine.FeatureAddressType(lf)
+
+	for _, item := range c.subscriptions() {
+		if !featureAddressMatches(item.ServerAddress, address) {
+			continue
+		}
+
+		if err := ctx.Notify(item.ServerAddress, item.ClientAddress, cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,192 @@
+package communication
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+	"github.com/evcc-io/eebus/util"
+)
+
+// testConn records the written datagrams
+type testConn struct {
+	mux       sync.Mutex
+	datagrams []model.DatagramType
+	closed    bool
+	fail      int // number of next writes failing with errTestWrite
+}
+
+var errTestWrite = errors.New("write failed")
+
+func (c *testConn) Read() (json.RawMessage, error) {
+	select {}
+}
+
+func (c *testConn) Write(data json.RawMessage) error {
+	var datagram model.CmiDatagramType
+	if err := json.Unmarshal(data, &datagram); err != nil {
+		return err
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.fail > 0 {
+		c.fail--
+		return errTestWrite
+	}
+
+	c.datagrams = append(c.datagrams, datagram.Datagram)
+	return nil
+}
+
+func (c *testConn) Close() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.closed = true
+	return nil
+}
+
+func (c *testConn) IsConnectionClosed() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.closed
+}
+
+func (c *testConn) written() []model.DatagramType {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return append([]model.DatagramType(nil), c.datagrams...)
+}
+
+// testController returns a controller of a CEM connected to an EVSE with an EV
+func testController(t *testing.T) (*ConnectionController, *testConn) {
+	t.Helper()
+
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	local.Add(entity.CEM())
+
+	var data model.NodeManagementDetailedDiscoveryDataType
+	if err := json.Unmarshal([]byte(`[
+		{"deviceInformation":[{"description":[{"deviceAddress":[{"device":"d:_i:EVSE"}]},{"deviceType":"ChargingStation"}]}]},
+		{"entityInformation":[
+			[{"description":[{"entityAddress":[{"entity":[1]}]},{"entityType":"EVSE"}]}],
+			[{"description":[{"entityAddress":[{"entity":[1,1]}]},{"entityType":"EV"}]}]
+		]},
+		{"featureInformation":[
+			[{"description":[{"featureAddress":[{"entity":[1,1]},{"feature":1}]},{"featureType":"LoadControl"},{"role":"server"},{"supportedFunction":[[{"function":"loadControlLimitListData"},{"possibleOperations":[{"read":[]},{"write":[]}]}]]}]}],
+			[{"description":[{"featureAddress":[{"entity":[1,1]},{"feature":2}]},{"featureType":"DeviceConfiguration"},{"role":"server"},{"supportedFunction":[[{"function":"deviceConfigurationKeyValueListData"},{"possibleOperations":[{"read":[]}]}]]}]}]
+		]}
+	]`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := spine.UnmarshalDevice(data)
+	for _, ei := range data.EntityInformation {
+		remote.Add(spine.UnmarshalEntity(remote.GetAddress(), ei))
+	}
+	for _, fi := range data.FeatureInformation {
+		remote.Entity(fi.Description.FeatureAddress.Entity).Add(spine.UnmarshalFeature(fi))
+	}
+
+	conn := new(testConn)
+	c := NewConnectionController(&util.NopLogger{}, conn, local)
+	c.SetDevice(remote)
+
+	return c, conn
+}
+
+// testDatagram parses the header and payload of a datagram received from the EVSE
+func testDatagram(t *testing.T, header, payload string) model.DatagramType {
+	t.Helper()
+
+	var datagram model.CmiDatagramType
+	if err := json.Unmarshal([]byte(`{"datagram":[{"header":[`+header+`]},{"payload":[`+payload+`]}]}`), &datagram); err != nil {
+		t.Fatal(err)
+	}
+
+	return datagram.Datagram
+}
+
+// testHeader returns the header of a datagram sent by the EV's LoadControl server to the local CEM feature
+func testHeader(c *ConnectionController, cmdClassifier model.CmdClassifierType, msgCounter model.MsgCounterType, featureType model.FeatureTypeEnumType, role model.RoleType) model.HeaderType {
+	version := model.SpecificationVersionType("1.2.0")
+	remoteDevice := model.AddressDeviceType("d:_i:EVSE")
+	remoteFeature := model.AddressFeatureType(1)
+
+	cem := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
+
+	return model.HeaderType{
+		SpecificationVersion: &version,
+		AddressSource: &model.FeatureAddressType{
+			Device:  &remoteDevice,
+			Entity:  []model.AddressEntityType{1, 1},
+			Feature: &remoteFeature,
+		},
+		AddressDestination: cem.FeatureByProps(featureType, role).GetAddress(),
+		MsgCounter:         &msgCounter,
+		CmdClassifier:      &cmdClassifier,
+	}
+}
+
+// TestWriteCurrentLimitDataConcurrentNotify writes limits while the EVSE notifies the descriptions, run with -race
+func TestWriteCurrentLimitDataConcurrentNotify(t *testing.T) {
+	c, _ := testController(t)
+
+	notifies := []struct {
+		featureType model.FeatureTypeEnumType
+		payload     string
+	}{
+		{model.FeatureTypeEnumTypeElectricalConnection, `{"cmd":[[{"electricalConnectionParameterDescriptionListData":[{"electricalConnectionParameterDescriptionData":[[{"electricalConnectionId":0},{"parameterId":1},{"measurementId":1},{"acMeasuredPhases":"a"}],[{"electricalConnectionId":0},{"parameterId":2},{"measurementId":2},{"acMeasuredPhases":"b"}],[{"electricalConnectionId":0},{"parameterId":3},{"measurementId":3},{"acMeasuredPhases":"c"}]]}]}]]}`},
+		{model.FeatureTypeEnumTypeMeasurement, `{"cmd":[[{"measurementDescriptionListData":[{"measurementDescriptionData":[[{"measurementId":1},{"measurementType":"current"},{"scopeType":"acCurrent"}],[{"measurementId":2},{"measurementType":"current"},{"scopeType":"acCurrent"}],[{"measurementId":3},{"measurementType":"current"},{"scopeType":"acCurrent"}]]}]}]]}`},
+		{model.FeatureTypeEnumTypeLoadControl, `{"cmd":[[{"loadControlLimitDescriptionListData":[{"loadControlLimitDescriptionData":[[{"limitId":1},{"limitType":"maxValueLimit"},{"measurementId":1},{"scopeType":"overloadProtection"}],[{"limitId":2},{"limitType":"maxValueLimit"},{"measurementId":2},{"scopeType":"overloadProtection"}],[{"limitId":3},{"limitType":"maxValueLimit"},{"measurementId":3},{"scopeType":"overloadProtection"}]]}]}]]}`},
+	}
+
+	var msgCounter model.MsgCounterType
+	notify := func() {
+		for _, n := range notifies {
+			msgCounter++
+			header, err := json.Marshal(testHeader(c, model.CmdClassifierTypeNotify, msgCounter, n.featureType, model.RoleTypeClient))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := c.processDatagram(testDatagram(t, string(header[1:len(header)-1]), n.payload)); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	notify()
+
+	limits := map[uint]EVCurrentLimitType{}
+	for phase := uint(1); phase <= 3; phase++ {
+		limits[phase] = EVCurrentLimitType{Min: 6, Max: 16, Default: 0}
+	}
+	evData := &EVDataType{ConnectedPhases: 3, Limits: limits}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			notify()
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		if err := c.WriteCurrentLimitData([]float64{10, 10, 10}, nil, evData); err != nil {
+			t.Error(err)
+		}
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,154 @@
+package communication
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+var validCmdClassifiers = map[model.CmdClassifierType]bool{
+	model.CmdClassifierTypeRead:   true,
+	model.CmdClassifierTypeReply:  true,
+	model.CmdClassifierTypeNotify: true,
+	model.CmdClassifierTypeWrite:  true,
+	model.CmdClassifierTypeCall:   true,
+	model.CmdClassifierTypeResult: true,
+}
+
+// validateDatagram checks the datagram and returns the addressed local entity and feature.
+// Errors are of type *model.ErrorType carrying the SPINE error number to be reported to the sender.
+func (c *ConnectionController) validateDatagram(datagram model.DatagramType) (spine.Entity, spine.Feature, error) {
+	if err := c.validateHeader(datagram.Header); err != nil {
+		return nil, nil, err
+	}
+
+	destAddr := datagram.Header.AddressDestination
+
+	entity := c.localDevice.Entity(destAddr.Entity)
+	if entity == nil {
+		return nil, nil, model.NewErrorType(model.ErrorNumberTypeDestinationUnknown, "invalid entity address")
+	}
+
+	feature := entity.Feature(uint(*destAddr.Feature))
+	if feature == nil {
+		return nil, nil, model.NewErrorType(model.ErrorNumberTypeDestinationUnknown, "invalid feature address")
+	}
+
+	if len(datagram.Payload.Cmd) == 0 {
+		return nil, nil, model.NewErrorType(model.ErrorNumberTypeGeneralError, "missing cmd")
+	}
+
+	for _, cmd := range datagram.Payload.Cmd {
+		if err := c.validateCmd(*datagram.Header.CmdClassifier, cmd, feature); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return entity, feature, nil
+}
+
+// validateHeader checks the datagram header for completeness, a compatible specification version and valid addressing
+func (c *ConnectionController) validateHeader(header model.HeaderType) error {
+	if header.SpecificationVersion == nil {
+		return model.NewErrorType(model.ErrorNumberTypeGeneralError, "missing specificationVersion")
+	}
+
+	if majorVersion(string(*header.SpecificationVersion)) != majorVersion(string(c.specificationVersion)) {
+		return model.NewErrorType(model.ErrorNumberTypeGeneralError, fmt.Sprintf("unsupported specificationVersion %s", *header.SpecificationVersion))
+	}
+
+	if header.MsgCounter == nil {
+		return model.NewErrorType(model.ErrorNumberTypeGeneralError, "missing msgCounter")
+	}
+
+	if header.CmdClassifier == nil || !validCmdClassifiers[*header.CmdClassifier] {
+		return model.NewErrorType(model.ErrorNumberTypeCommandNotSupported, "invalid cmdClassifier")
+	}
+
+	switch *header.CmdClassifier {
+	case model.CmdClassifierTypeReply, model.CmdClassifierTypeResult:
+		if header.MsgCounterReference == nil {
+			return model.NewErrorType(model.ErrorNumberTypeGeneralError, fmt.Sprintf("missing msgCounterReference for %s", *header.CmdClassifier))
+		}
+	}
+
+	src := header.AddressSource
+	if src == nil || src.Entity == nil || src.Feature == nil {
+		return model.NewErrorType(model.ErrorNumberTypeGeneralError, "invalid addressSource")
+	}
+
+	if c.remoteDevice != nil && c.remoteDevice.GetAddress() != "" && src.Device != nil && *src.Device != c.remoteDevice.GetAddress() {
+		return model.NewErrorType(model.ErrorNumberTypeCommandRejected, fmt.Sprintf("unknown source device %s", *src.Device))
+	}
+
+	dest := header.AddressDestination
+	if dest == nil || dest.Entity == nil || dest.Feature == nil {
+		return model.NewErrorType(model.ErrorNumberTypeDestinationUnknown, "invalid addressDestination")
+	}
+
+	// if destDevice is empty assume it's us
+	if dest.Device != nil && *dest.Device != c.localDevice.GetAddress() {
+		return model.NewErrorType(model.ErrorNumberTypeDestinationUnknown, fmt.Sprintf("unknown destination device %s", *dest.Device))
+	}
+
+	return nil
+}
+
+// validateCmd checks that the cmd's data and function are consistent with the cmdClassifier and supported by the feature
+func (c *ConnectionController) validateCmd(cmdClassifier model.CmdClassifierType, cmd model.CmdType, localFeature spine.Feature) error {
+	dataName := cmd.DataName()
+	if dataName == "" {
+		return model.NewErrorType(model.ErrorNumberTypeCommandNotSupported, "missing cmd data")
+	}
+
+	if cmd.Function != nil && string(*cmd.Function) != dataName {
+		return model.NewErrorType(model.ErrorNumberTypeGeneralError, fmt.Sprintf("function %s does not match cmd data %s", *cmd.Function, dataName))
+	}
+
+	isResult := cmd.ResultData != nil
+	if isResult != (cmdClassifier == model.CmdClassifierTypeResult) {
+		return model.NewErrorType(model.ErrorNumberTypeCommandNotSupported, fmt.Sprintf("%s not allowed for %s", dataName, cmdClassifier))
+	}
+
+	isCall := strings.HasSuffix(dataName, "Call")
+	if isCall != (cmdClassifier == model.CmdClassifierTypeCall) {
+		return model.NewErrorType(model.ErrorNumberTypeCommandNotSupported, fmt.Sprintf("%s not allowed for %s", dataName, cmdClassifier))
+	}
+
+	// requests need to be supported by the local feature
+	switch cmdClassifier {
+	case model.CmdClassifierTypeRead, model.CmdClassifierTypeWrite, model.CmdClassifierTypeCall:
+		if localFeature.GetRole() == model.RoleTypeClient {
+			return model.NewErrorType(model.ErrorNumberTypeCommandNotSupported, fmt.Sprintf("%s not supported by client feature", cmdClassifier))
+		}
+
+		rw, ok := localFeature.FunctionOperations(model.FunctionEnumType(dataName))
+		switch {
+		case !ok:
+			return model.NewErrorType(model.ErrorNumberTypeCommandNotSupported, fmt.Sprintf("function %s not supported", dataName))
+		case cmdClassifier == model.CmdClassifierTypeRead && !rw.Read:
+			return model.NewErrorType(model.ErrorNumberTypeCommandRejected, fmt.Sprintf("function %s not readable", dataName))
+		case cmdClassifier == model.CmdClassifierTypeWrite && !rw.Write:
+			return model.NewErrorType(model.ErrorNumberTypeCommandRejected, fmt.Sprintf("function %s not writable", dataName))
+		}
+	}
+
+	return nil
+}
+
+// expectsErrorResult checks if a failed datagram needs to be answered with a result
+func expectsErrorResult(header model.HeaderType) bool {
+	if header.CmdClassifier == nil || *header.CmdClassifier == model.CmdClassifierTypeResult {
+		return false
+	}
+
+	ackRequest := header.AckRequest != nil && *header.AckRequest
+
+	return ackRequest || *header.CmdClassifier == model.CmdClassifierTypeRead || *header.CmdClassifier == model.CmdClassifierTypeCall
+}
+
+func majorVersion(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
@@ -0,0 +1,119 @@
+package communication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestValidateDatagram(t *testing.T) {
+	c, _ := testController(t)
+
+	notify := func(modify func(*model.DatagramType)) model.DatagramType {
+		datagram := model.DatagramType{
+			Header: testHeader(c, model.CmdClassifierTypeNotify, 1, model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient),
+			Payload: model.PayloadType{Cmd: []model.CmdType{
+				{LoadControlLimitListData: &model.LoadControlLimitListDataType{}},
+			}},
+		}
+		if modify != nil {
+			modify(&datagram)
+		}
+		return datagram
+	}
+
+	for _, tc := range []struct {
+		name        string
+		datagram    model.DatagramType
+		errorNumber model.ErrorNumberType
+	}{
+		{"valid", notify(nil), model.ErrorNumberTypeNoError},
+		{"missing specificationVersion", notify(func(d *model.DatagramType) {
+			d.Header.SpecificationVersion = nil
+		}), model.ErrorNumberTypeGeneralError},
+		{"unsupported specificationVersion", notify(func(d *model.DatagramType) {
+			version := model.SpecificationVersionType("2.0.0")
+			d.Header.SpecificationVersion = &version
+		}), model.ErrorNumberTypeGeneralError},
+		{"missing msgCounter", notify(func(d *model.DatagramType) {
+			d.Header.MsgCounter = nil
+		}), model.ErrorNumberTypeGeneralError},
+		{"invalid cmdClassifier", notify(func(d *model.DatagramType) {
+			cmdClassifier := model.CmdClassifierType("invalid")
+			d.Header.CmdClassifier = &cmdClassifier
+		}), model.ErrorNumberTypeCommandNotSupported},
+		{"reply without msgCounterReference", notify(func(d *model.DatagramType) {
+			cmdClassifier := model.CmdClassifierTypeReply
+			d.Header.CmdClassifier = &cmdClassifier
+		}), model.ErrorNumberTypeGeneralError},
+		{"missing addressSource", notify(func(d *model.DatagramType) {
+			d.Header.AddressSource = nil
+		}), model.ErrorNumberTypeGeneralError},
+		{"unknown source device", notify(func(d *model.DatagramType) {
+			device := model.AddressDeviceType("d:_i:other")
+			d.Header.AddressSource.Device = &device
+		}), model.ErrorNumberTypeCommandRejected},
+		{"unknown destination device", notify(func(d *model.DatagramType) {
+			device := model.AddressDeviceType("d:_i:other")
+			d.Header.AddressDestination.Device = &device
+		}), model.ErrorNumberTypeDestinationUnknown},
+		{"unknown destination entity", notify(func(d *model.DatagramType) {
+			d.Header.AddressDestination = &model.FeatureAddressType{Entity: []model.AddressEntityType{9}, Feature: d.Header.AddressDestination.Feature}
+		}), model.ErrorNumberTypeDestinationUnknown},
+		{"unknown destination feature", notify(func(d *model.DatagramType) {
+			feature := model.AddressFeatureType(99)
+			d.Header.AddressDestination = &model.FeatureAddressType{Entity: d.Header.AddressDestination.Entity, Feature: &feature}
+		}), model.ErrorNumberTypeDestinationUnknown},
+		{"missing cmd", notify(func(d *model.DatagramType) {
+			d.Payload.Cmd = nil
+		}), model.ErrorNumberTypeGeneralError},
+		{"function not matching data", notify(func(d *model.DatagramType) {
+			function := model.FunctionType(model.FunctionEnumTypeMeasurementListData)
+			d.Payload.Cmd[0].Function = &function
+		}), model.ErrorNumberTypeGeneralError},
+		{"result data without result", notify(func(d *model.DatagramType) {
+			d.Payload.Cmd = []model.CmdType{{ResultData: &model.ResultDataType{}}}
+		}), model.ErrorNumberTypeCommandNotSupported},
+		{"read from client feature", notify(func(d *model.DatagramType) {
+			cmdClassifier := model.CmdClassifierTypeRead
+			d.Header.CmdClassifier = &cmdClassifier
+		}), model.ErrorNumberTypeCommandNotSupported},
+	} {
+		_, _, err := c.validateDatagram(tc.datagram)
+
+		if tc.errorNumber == model.ErrorNumberTypeNoError {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+
+		var errType *model.ErrorType
+		if !errors.As(err, &errType) || errType.ErrorNumber != tc.errorNumber {
+			t.Errorf("%s: expected error number %d, got %v", tc.name, tc.errorNumber, err)
+		}
+	}
+}
+
+func TestExpectsErrorResult(t *testing.T) {
+	ackRequest := true
+
+	for _, tc := range []struct {
+		cmdClassifier model.CmdClassifierType
+		ackRequest    *bool
+		res           bool
+	}{
+		{model.CmdClassifierTypeRead, nil, true},
+		{model.CmdClassifierTypeCall, nil, true},
+		{model.CmdClassifierTypeWrite, nil, false},
+		{model.CmdClassifierTypeWrite, &ackRequest, true},
+		{model.CmdClassifierTypeNotify, &ackRequest, true},
+		{model.CmdClassifierTypeResult, &ackRequest, false},
+	} {
+		cmdClassifier := tc.cmdClassifier
+		if res := expectsErrorResult(model.HeaderType{CmdClassifier: &cmdClassifier, AckRequest: tc.ackRequest}); res != tc.res {
+			t.Errorf("%s: expected %v, got %v", tc.cmdClassifier, tc.res, res)
+		}
+	}
+}
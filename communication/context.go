@@ -29,8 +29,8 @@ func (c *contextImpl) RemoveSubscription(data model.SubscriptionManagementDelete
 	return c.removeSubscription(data)
 }
 
-func (c *contextImpl) HeartbeatCounter() *uint64 {
-	return c.heartBeatCounter()
+func (c *contextImpl) HeartbeatData() model.DeviceDiagnosisHeartbeatDataType {
+	return c.heartbeatData(nil)
 }
 
 func (c *contextImpl) Subscriptions() []model.SubscriptionManagementEntryDataType {
@@ -90,6 +90,12 @@ func (c *contextImpl) ProcessSequenceFlowRequest(featureType model.FeatureTypeEn
 
 // Sends read request
 func (c *contextImpl) Request(cmdClassifier model.CmdClassifierType, senderAddress, destinationAddress model.FeatureAddressType, ackRequest bool, cmd []model.CmdType) (*model.MsgCounterType, error) {
+	if cmdClassifier == model.CmdClassifierTypeRead || cmdClassifier == model.CmdClassifierTypeWrite {
+		if err := c.checkRemoteOperation(cmdClassifier, &destinationAddress, cmd); err != nil {
+			return nil, err
+		}
+	}
+
 	msgCounter := c.msgCounter()
 
 	datagram := model.DatagramType{
@@ -109,6 +115,14 @@ func (c *contextImpl) Request(cmdClassifier model.CmdClassifierType, senderAddre
 		datagram.Header.AckRequest = &ackRequest
 	}
 
+	if cmdClassifier == model.CmdClassifierTypeRead {
+		for _, item := range cmd {
+			if filterPartial, _ := item.ExtractFilter(); filterPartial != nil {
+				c.addPartialRead(*msgCounter)
+			}
+		}
+	}
+
 	return msgCounter, c.sendSpineMessage(datagram)
 }
 
@@ -160,14 +174,19 @@ func (c *contextImpl) Notify(senderAddress, destinationAddress *model.FeatureAdd
 // Write sends notification to destination
 func (c *contextImpl) Write(senderAddress, destinationAddress *model.FeatureAddressType, cmd []model.CmdType) error {
 	cmdClassifier := model.CmdClassifierTypeWrite
+	if err := c.checkRemoteOperation(cmdClassifier, destinationAddress, cmd); err != nil {
+		return err
+	}
+
 	ackRequest := true
+	msgCounter := c.msgCounter()
 
 	datagram := model.DatagramType{
 		Header: model.HeaderType{
 			SpecificationVersion: &c.specificationVersion,
 			AddressSource:        senderAddress,
 			AddressDestination:   destinationAddress,
-			MsgCounter:           c.msgCounter(),
+			MsgCounter:           msgCounter,
 			CmdClassifier:        &cmdClassifier,
 			AckRequest:           &ackRequest,
 		},
@@ -176,5 +195,13 @@ func (c *contextImpl) Write(senderAddress, destinationAddress *model.FeatureAddr
 		},
 	}
 
-	return c.sendSpineMessage(datagram)
+	// the result is reported as error if the write is rejected
+	c.addPendingWrite(*msgCounter, cmd)
+
+	if err := c.sendSpineMessage(datagram); err != nil {
+		c.removePendingWrite(*msgCounter)
+		return err
+	}
+
+	return nil
 }
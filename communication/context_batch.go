@@ -0,0 +1,102 @@
+package communication
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+type batchWrite struct {
+	senderAddress, destinationAddress *model.FeatureAddressType
+	cmd                               []model.CmdType
+}
+
+// BatchError reports the datagrams of a batch that could not be sent, all other datagrams have been sent
+type BatchError struct {
+	Errs []error // one error per datagram naming its destination feature
+}
+
+func (e *BatchError) Error() string {
+	errs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		errs = append(errs, err.Error())
+	}
+
+	return "write batch: " + strings.Join(errs, "; ")
+}
+
+// Unwrap returns the errors of the datagrams
+func (e *BatchError) Unwrap() []error {
+	return e.Errs
+}
+
+// batchContext collects writes instead of sending them immediately
+type batchContext struct {
+	*contextImpl
+	writes []batchWrite
+}
+
+// Write adds the cmds to the batch of the same sender and destination feature
+func (c *batchContext) Write(senderAddress, destinationAddress *model.FeatureAddressType, cmd []model.CmdType) error {
+	if err := c.checkRemoteOperation(model.CmdClassifierTypeWrite, destinationAddress, cmd); err != nil {
+		return err
+	}
+
+	for i, w := range c.writes {
+		if reflect.DeepEqual(w.senderAddress, senderAddress) && reflect.DeepEqual(w.destinationAddress, destinationAddress) {
+			c.writes[i].cmd = append(c.writes[i].cmd, cmd...)
+			return nil
+		}
+	}
+
+	c.writes = append(c.writes, batchWrite{
+		senderAddress:      senderAddress,
+		destinationAddress: destinationAddress,
+		cmd:                cmd,
+	})
+
+	return nil
+}
+
+// flush sends one datagram per sender and destination feature. All datagrams are sent even if one of them fails.
+func (c *batchContext) flush() error {
+	batchErr := &BatchError{}
+	for _, w := range c.writes {
+		if err := c.contextImpl.Write(w.senderAddress, w.destinationAddress, w.cmd); err != nil {
+			batchErr.Errs = append(batchErr.Errs, fmt.Errorf("%s: %w", writeDestination(w.destinationAddress), err))
+		}
+	}
+
+	if len(batchErr.Errs) > 0 {
+		return batchErr
+	}
+
+	return nil
+}
+
+// writeDestination describes the destination feature of a write
+func writeDestination(address *model.FeatureAddressType) string {
+	if address == nil || address.Feature == nil {
+		return "unknown feature"
+	}
+
+	return fmt.Sprintf("entity %v feature %d", address.Entity, *address.Feature)
+}
+
+// WriteBatch calls fn with a context collecting all writes and sends them afterwards.
+// Writes between the same local and remote feature are combined into a single datagram.
+// Nothing is sent if fn returns an error. If sending fails a BatchError reports the datagrams that have not been sent.
+func (c *ConnectionController) WriteBatch(fn func(ctx spine.Context) error) error {
+	ctx := &batchContext{
+		contextImpl: &contextImpl{ConnectionController: c},
+	}
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+
+	return ctx.flush()
+}
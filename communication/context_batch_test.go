@@ -0,0 +1,72 @@
+package communication
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestWriteBatch(t *testing.T) {
+	c, conn := testController(t)
+
+	cem := c.localDevice.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
+	sender := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient).GetAddress()
+
+	loadControl := c.remoteDevice.Entity([]model.AddressEntityType{1, 1}).Feature(1).GetAddress()
+	deviceConfiguration := c.remoteDevice.Entity([]model.AddressEntityType{1, 1}).Feature(2).GetAddress()
+
+	limits := []model.CmdType{{LoadControlLimitListData: &model.LoadControlLimitListDataType{}}}
+
+	// nothing is sent if a write fails
+	err := c.WriteBatch(func(ctx spine.Context) error {
+		if err := ctx.Write(sender, loadControl, limits); err != nil {
+			return err
+		}
+		return ctx.Write(sender, deviceConfiguration, []model.CmdType{{DeviceConfigurationKeyValueListData: &model.DeviceConfigurationKeyValueListDataType{}}})
+	})
+	if !errors.Is(err, spine.ErrFeatureNotSupported) || len(conn.written()) != 0 {
+		t.Errorf("expected unsupported write without datagrams, got %v and %d datagrams", err, len(conn.written()))
+	}
+
+	// writes to the same feature are combined
+	if err := c.WriteBatch(func(ctx spine.Context) error {
+		for i := 0; i < 2; i++ {
+			if err := ctx.Write(sender, loadControl, limits); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	res := conn.written()
+	if len(res) != 1 || len(res[0].Payload.Cmd) != 2 || *res[0].Header.CmdClassifier != model.CmdClassifierTypeWrite {
+		t.Errorf("expected single write with 2 cmds, got %v", res)
+	}
+
+	// all datagrams are sent even if one of them fails
+	dc := cem.FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeClient).GetAddress()
+
+	conn.mux.Lock()
+	conn.fail = 1
+	conn.mux.Unlock()
+
+	err = c.WriteBatch(func(ctx spine.Context) error {
+		if err := ctx.Write(sender, loadControl, limits); err != nil {
+			return err
+		}
+		return ctx.Write(dc, loadControl, limits)
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errs) != 1 || !errors.Is(err, errTestWrite) {
+		t.Errorf("expected single failed datagram, got %v", err)
+	}
+	if res := conn.written(); len(res) != 2 || !reflect.DeepEqual(res[1].Header.AddressSource, dc) {
+		t.Errorf("expected remaining datagram sent, got %v", res)
+	}
+}
@@ -0,0 +1,46 @@
+package communication
+
+import (
+	"sync"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// msgCounterWindowSize is the number of recently received msgCounters remembered for detecting duplicates
+const msgCounterWindowSize = 256
+
+// msgCounterWindow detects duplicate and replayed msgCounters of a remote device
+type msgCounterWindow struct {
+	highest model.MsgCounterType
+	seen    map[model.MsgCounterType]bool
+	mux     sync.Mutex
+}
+
+// add returns false if the msgCounter has already been received or is older than the window
+func (w *msgCounterWindow) add(counter model.MsgCounterType) bool {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.seen == nil {
+		w.seen = make(map[model.MsgCounterType]bool)
+	}
+
+	if w.seen[counter] || w.highest > msgCounterWindowSize && counter <= w.highest-msgCounterWindowSize {
+		return false
+	}
+
+	w.seen[counter] = true
+
+	if counter > w.highest {
+		w.highest = counter
+
+		// forget counters outside the window
+		for item := range w.seen {
+			if w.highest > msgCounterWindowSize && item <= w.highest-msgCounterWindowSize {
+				delete(w.seen, item)
+			}
+		}
+	}
+
+	return true
+}
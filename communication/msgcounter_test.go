@@ -0,0 +1,29 @@
+package communication
+
+import (
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestMsgCounterWindow(t *testing.T) {
+	var w msgCounterWindow
+
+	for _, tc := range []struct {
+		counter model.MsgCounterType
+		res     bool
+	}{
+		{1, true},
+		{1, false}, // duplicate
+		{3, true},
+		{2, true}, // out of order within the window
+		{2, false},
+		{1000, true},
+		{1000 - msgCounterWindowSize, false}, // outside the window
+		{1000 - msgCounterWindowSize + 1, true},
+	} {
+		if res := w.add(tc.counter); res != tc.res {
+			t.Errorf("%d: expected %v, got %v", tc.counter, tc.res, res)
+		}
+	}
+}
@@ -115,6 +115,10 @@ func (s *SequencesController) setupEVConfigurationSequences() *SequenceFlow {
 		element := s.newElement(model.FeatureTypeEnumTypeTimeSeries, model.FunctionEnumTypeTimeSeriesDescriptionListData, model.CmdClassifierTypeRead)
 		newSequenceFlow.elements = append(newSequenceFlow.elements, element)
 	}
+	{
+		element := s.newElement(model.FeatureTypeEnumTypeTimeSeries, model.FunctionEnumTypeTimeSeriesConstraintsListData, model.CmdClassifierTypeRead)
+		newSequenceFlow.elements = append(newSequenceFlow.elements, element)
+	}
 	{
 		element := s.newElement(model.FeatureTypeEnumTypeTimeSeries, model.FunctionEnumTypeTimeSeriesListData, model.CmdClassifierTypeRead)
 		newSequenceFlow.elements = append(newSequenceFlow.elements, element)
@@ -140,6 +144,21 @@ func (s *SequencesController) processStepInSequenceFlow(ctx spine.Context, seque
 	sequenceElement := sequenceFlow.elements[sequenceFlow.currentId]
 
 	msgCounter, err := ctx.ProcessSequenceFlowRequest(sequenceElement.featureType, sequenceElement.functionType, sequenceElement.cmdClassifier)
+
+	// skip steps not supported by the remote device instead of stopping the sequence
+	for errors.Is(err, spine.ErrFeatureNotSupported) {
+		s.log.Println("skipping sequence step: ", err)
+
+		sequenceFlow.currentId += 1
+		if sequenceFlow.currentId >= len(sequenceFlow.elements) {
+			sequenceFlow.currentId = 0
+			return nil
+		}
+
+		sequenceElement = sequenceFlow.elements[sequenceFlow.currentId]
+		msgCounter, err = ctx.ProcessSequenceFlowRequest(sequenceElement.featureType, sequenceElement.functionType, sequenceElement.cmdClassifier)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -19,61 +19,59 @@ import (
 //   e[1] f-7 client.LoadControl - LoadControl client for CEM
 //   e[1] f-8 client.Identification - EV identification
 //   e[1] f-9 client.ElectricalConnection - Electrical Connection
+//   e[1] server.Bill - EV charging summary
+//    {RO} billDescriptionListData
+//    {RO} billConstraintsListData
+//    {RO} billListData
 func CEM() spine.Entity {
 	var entityType model.EntityTypeType = model.EntityTypeType(model.EntityTypeEnumTypeCEM)
 	entity := &spine.EntityImpl{
 		Type: entityType,
 	}
 
-	fid := FeatureNumerator(1)
+	// feature ids are allocated when the entity is added to the device
 	{
 		f := feature.NewDeviceClassificationClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewDeviceDiagnosisClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewMeasurementClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewDeviceConfigurationClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewDeviceDiagnosisServer()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewLoadControlClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewIdentificationClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewElectricalConnectionClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewTimeSeriesClient()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewIncentiveTableClient()
-		f.SetID(fid())
+		entity.Add(f)
+	}
+	{
+		f := feature.NewBillServer()
 		entity.Add(f)
 	}
 
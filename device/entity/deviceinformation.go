@@ -26,16 +26,13 @@ func DeviceInformation() spine.Entity {
 		Type: entityType,
 	}
 
-	fid := FeatureNumerator(0)
-
+	// feature ids are allocated when the entity is added to the device
 	{
 		f := feature.NewNodeManagement()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 	{
 		f := feature.NewDeviceClassificationServer()
-		f.SetID(fid())
 		entity.Add(f)
 	}
 
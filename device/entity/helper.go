@@ -2,6 +2,7 @@ package entity
 
 import "github.com/evcc-io/eebus/spine/model"
 
+// Deprecated: entity addresses are allocated by spine.Device.Add
 func Numerator(ids []uint) func() []model.AddressEntityType {
 	id := ids[len(ids)-1]
 	start := make([]model.AddressEntityType, len(ids))
@@ -22,6 +23,7 @@ func Numerator(ids []uint) func() []model.AddressEntityType {
 	}
 }
 
+// Deprecated: feature ids are allocated by spine.Device.Add and spine.Device.AddFeature
 func FeatureNumerator(id uint) func() uint {
 	return func() uint {
 		defer func() { id += 1 }()
@@ -0,0 +1,122 @@
+package feature
+
+import (
+	"fmt"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// Bill provides the charging summaries of the local device to the remote device
+type Bill struct {
+	*spine.FeatureImpl
+}
+
+func NewBillServer() spine.Feature {
+	f := &Bill{
+		FeatureImpl: &spine.FeatureImpl{
+			Type: model.FeatureTypeEnumTypeBill,
+			Role: model.RoleTypeServer,
+		},
+	}
+
+	f.Add(model.FunctionEnumTypeBillDescriptionListData, true, false)
+	f.Add(model.FunctionEnumTypeBillConstraintsListData, true, false)
+	f.Add(model.FunctionEnumTypeBillListData, true, false)
+
+	return f
+}
+
+// ListData returns the bills provided by the feature
+func (f *Bill) ListData() []model.BillDataType {
+	if data := spine.FeatureData[model.BillListDataType](f, model.FunctionEnumTypeBillListData); data != nil {
+		return data.BillData
+	}
+	return nil
+}
+
+// DescriptionListData returns the bill descriptions provided by the feature
+func (f *Bill) DescriptionListData() []model.BillDescriptionDataType {
+	if data := spine.FeatureData[model.BillDescriptionListDataType](f, model.FunctionEnumTypeBillDescriptionListData); data != nil {
+		return data.BillDescriptionData
+	}
+	return nil
+}
+
+// ConstraintsListData returns the bill constraints provided by the feature
+func (f *Bill) ConstraintsListData() []model.BillConstraintsDataType {
+	if data := spine.FeatureData[model.BillConstraintsListDataType](f, model.FunctionEnumTypeBillConstraintsListData); data != nil {
+		return data.BillConstraintsData
+	}
+	return nil
+}
+
+// readListData replies with the bills matching the selectors of a partial read
+func (f *Bill) readListData(ctrl spine.Context, cmd model.CmdType) error {
+	filterPartial, _ := cmd.ExtractFilter()
+
+	res := model.CmdType{
+		BillListData: &model.BillListDataType{
+			BillData: spine.FilterList(f.ListData(), filterPartial),
+		},
+	}
+	if filterPartial != nil {
+		res.Filter = []model.FilterType{*filterPartial}
+	}
+
+	return ctrl.Reply(model.CmdClassifierTypeReply, res)
+}
+
+func (f *Bill) readDescriptionListData(ctrl spine.Context) error {
+	res := model.CmdType{
+		BillDescriptionListData: &model.BillDescriptionListDataType{
+			BillDescriptionData: f.DescriptionListData(),
+		},
+	}
+
+	return ctrl.Reply(model.CmdClassifierTypeReply, res)
+}
+
+func (f *Bill) readConstraintsListData(ctrl spine.Context) error {
+	res := model.CmdType{
+		BillConstraintsListData: &model.BillConstraintsListDataType{
+			BillConstraintsData: f.ConstraintsListData(),
+		},
+	}
+
+	return ctrl.Reply(model.CmdClassifierTypeReply, res)
+}
+
+func (f *Bill) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	switch {
+	case cmd.BillListData != nil:
+		switch op {
+		case model.CmdClassifierTypeRead:
+			return f.readListData(ctrl, cmd)
+
+		default:
+			return fmt.Errorf("bill.Handle: BillListData CmdClassifierType not implemented: %s", op)
+		}
+
+	case cmd.BillDescriptionListData != nil:
+		switch op {
+		case model.CmdClassifierTypeRead:
+			return f.readDescriptionListData(ctrl)
+
+		default:
+			return fmt.Errorf("bill.Handle: BillDescriptionListData CmdClassifierType not implemented: %s", op)
+		}
+
+	case cmd.BillConstraintsListData != nil:
+		switch op {
+		case model.CmdClassifierTypeRead:
+			return f.readConstraintsListData(ctrl)
+
+		default:
+			return fmt.Errorf("bill.Handle: BillConstraintsListData CmdClassifierType not implemented: %s", op)
+		}
+
+	default:
+		return fmt.Errorf("bill.Handle: CmdType not implemented: %s", populatedFields(cmd))
+	}
+}
@@ -40,6 +40,11 @@ func NewDeviceClassificationClient() spine.Feature {
 	return f
 }
 
+// ManufacturerData returns the cached manufacturer data of the remote device
+func (f *DeviceClassification) ManufacturerData() *model.DeviceClassificationManufacturerDataType {
+	return spine.FeatureData[model.DeviceClassificationManufacturerDataType](f, model.FunctionEnumTypeDeviceClassificationManufacturerData)
+}
+
 func (f *DeviceClassification) requestManufacturerData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		DeviceClassificationManufacturerData: &model.DeviceClassificationManufacturerDataType{},
@@ -61,6 +66,13 @@ func (f *DeviceClassification) readManufacturerData(ctrl spine.Context, data mod
 }
 
 func (f *DeviceClassification) replyManufacturerData(ctrl spine.Context, rf model.FeatureAddressType, data model.DeviceClassificationManufacturerDataType) error {
+	// the manufacturer data is kept per remote entity, e.g. for EVSE and EV
+	if re := remoteEntity(ctrl, rf); re != nil {
+		re.SetManufacturerData(data)
+	}
+
+	f.SetData(model.FunctionEnumTypeDeviceClassificationManufacturerData, &data)
+
 	if f.Delegate != nil {
 		f.Delegate.UpdateDeviceClassificationData(f, rf, data)
 	}
@@ -3,6 +3,8 @@ package feature
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"sync"
 
 	"github.com/evcc-io/eebus/spine"
 	"github.com/evcc-io/eebus/spine/model"
@@ -25,11 +27,74 @@ type DeviceConfigurationDelegate interface {
 	UpdateDeviceConfigurationData(*DeviceConfiguration, []DeviceConfigurationDatasetDataType)
 }
 
+// DeviceConfigurationKeyValues caches the key value descriptions and key values of a single remote entity
+type DeviceConfigurationKeyValues struct {
+	descriptions []model.DeviceConfigurationKeyValueDescriptionDataType
+	values       []model.DeviceConfigurationKeyValueDataType
+	handlers     []spine.DataChangeHandler
+	mux          sync.Mutex
+}
+
+// KeyValueDescriptionListData returns the cached key value descriptions
+func (c *DeviceConfigurationKeyValues) KeyValueDescriptionListData() []model.DeviceConfigurationKeyValueDescriptionDataType {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return append([]model.DeviceConfigurationKeyValueDescriptionDataType(nil), c.descriptions...)
+}
+
+// KeyValueListData returns the cached key values
+func (c *DeviceConfigurationKeyValues) KeyValueListData() []model.DeviceConfigurationKeyValueDataType {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return append([]model.DeviceConfigurationKeyValueDataType(nil), c.values...)
+}
+
+// AddDataChangeHandler registers a handler for changes of the cached key value descriptions and key values
+func (c *DeviceConfigurationKeyValues) AddDataChangeHandler(handler spine.DataChangeHandler) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.handlers = append(c.handlers, handler)
+}
+
+func (c *DeviceConfigurationKeyValues) updateDescriptions(data []model.DeviceConfigurationKeyValueDescriptionDataType, filterPartial, filterDelete *model.FilterType) {
+	c.mux.Lock()
+	res := spine.UpdateList(c.descriptions, data, filterPartial, filterDelete)
+	changed := !reflect.DeepEqual(c.descriptions, res)
+	c.descriptions = res
+	handlers := c.handlers
+	c.mux.Unlock()
+
+	if changed {
+		for _, handler := range handlers {
+			handler(model.FunctionEnumTypeDeviceConfigurationKeyValueDescriptionListData)
+		}
+	}
+}
+
+func (c *DeviceConfigurationKeyValues) updateValues(data []model.DeviceConfigurationKeyValueDataType, filterPartial, filterDelete *model.FilterType) {
+	c.mux.Lock()
+	res := spine.UpdateList(c.values, data, filterPartial, filterDelete)
+	changed := !reflect.DeepEqual(c.values, res)
+	c.values = res
+	handlers := c.handlers
+	c.mux.Unlock()
+
+	if changed {
+		for _, handler := range handlers {
+			handler(model.FunctionEnumTypeDeviceConfigurationKeyValueListData)
+		}
+	}
+}
+
 type DeviceConfiguration struct {
 	*spine.FeatureImpl
 	Delegate        DeviceConfigurationDelegate
 	descriptionData []DeviceConfigurationDescriptionDataType
 	datasetData     []DeviceConfigurationDatasetDataType
+	evse            *DeviceConfigurationKeyValues
 }
 
 func NewDeviceConfigurationClient() spine.Feature {
@@ -38,16 +103,52 @@ func NewDeviceConfigurationClient() spine.Feature {
 			Type: model.FeatureTypeEnumTypeDeviceConfiguration,
 			Role: model.RoleTypeClient,
 		},
+		evse: &DeviceConfigurationKeyValues{},
 	}
 
 	return f
 }
 
-func (f *DeviceConfiguration) EVDisconnectEvent() {
+// EVSE returns the key values of the EVSE entity. They are cached apart from the EV's key values
+// provided by the feature itself, as both entities use their own key ids.
+func (f *DeviceConfiguration) EVSE() *DeviceConfigurationKeyValues {
+	return f.evse
+}
+
+// fromEVSE returns if the remote feature address belongs to the EVSE entity
+func fromEVSE(ctrl spine.Context, rf model.FeatureAddressType) bool {
+	device := ctrl.GetDevice()
+	if device == nil {
+		return false
+	}
+
+	entity := device.Entity(rf.Entity)
+	return entity != nil && model.EntityTypeEnumType(entity.GetType()) == model.EntityTypeEnumTypeEVSE
+}
+
+// EVDisconnect clears the key values so that they are not used for the next EV, the EVSE's key values are kept
+func (f *DeviceConfiguration) EVDisconnect() {
+	f.ClearData()
 	f.descriptionData = nil
 	f.datasetData = nil
 }
 
+// KeyValueDescriptionListData returns the cached key value descriptions
+func (f *DeviceConfiguration) KeyValueDescriptionListData() []model.DeviceConfigurationKeyValueDescriptionDataType {
+	if data := spine.FeatureData[model.DeviceConfigurationKeyValueDescriptionListDataType](f, model.FunctionEnumTypeDeviceConfigurationKeyValueDescriptionListData); data != nil {
+		return data.DeviceConfigurationKeyValueDescriptionData
+	}
+	return nil
+}
+
+// KeyValueListData returns the cached key values
+func (f *DeviceConfiguration) KeyValueListData() []model.DeviceConfigurationKeyValueDataType {
+	if data := spine.FeatureData[model.DeviceConfigurationKeyValueListDataType](f, model.FunctionEnumTypeDeviceConfigurationKeyValueListData); data != nil {
+		return data.DeviceConfigurationKeyValueData
+	}
+	return nil
+}
+
 func (f *DeviceConfiguration) requestKeyValueDescriptionListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		DeviceConfigurationKeyValueDescriptionListData: &model.DeviceConfigurationKeyValueDescriptionListDataType{},
@@ -56,12 +157,20 @@ func (f *DeviceConfiguration) requestKeyValueDescriptionListData(ctrl spine.Cont
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *DeviceConfiguration) replyKeyValueDescriptionListData(ctrl spine.Context, data model.DeviceConfigurationKeyValueDescriptionListDataType) error {
+func (f *DeviceConfiguration) replyKeyValueDescriptionListData(ctrl spine.Context, data model.DeviceConfigurationKeyValueDescriptionListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":5}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":4}]},{"msgCounter":23313},{"msgCounterReference":19},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"deviceConfigurationKeyValueDescriptionListData":[{"deviceConfigurationKeyValueDescriptionData":[[{"keyId":1},{"keyName":"asymmetricChargingSupported"},{"valueType":"boolean"}],[{"keyId":2},{"keyName":"communicationsStandard"},{"valueType":"string"}]]}]}]]}]}]}}]}
 
+	f.SetData(model.FunctionEnumTypeDeviceConfigurationKeyValueDescriptionListData, &model.DeviceConfigurationKeyValueDescriptionListDataType{
+		DeviceConfigurationKeyValueDescriptionData: spine.UpdateList(f.KeyValueDescriptionListData(), data.DeviceConfigurationKeyValueDescriptionData, filterPartial, filterDelete),
+	})
+
 	f.descriptionData = nil
-	for _, item := range data.DeviceConfigurationKeyValueDescriptionData {
+	for _, item := range f.KeyValueDescriptionListData() {
+		if item.KeyId == nil || item.KeyName == nil || item.ValueType == nil {
+			continue
+		}
+
 		newItem := DeviceConfigurationDescriptionDataType{
 			KeyId:        uint(*item.KeyId),
 			KeyName:      model.DeviceConfigurationKeyNameEnumType(*item.KeyName),
@@ -74,14 +183,29 @@ func (f *DeviceConfiguration) replyKeyValueDescriptionListData(ctrl spine.Contex
 }
 
 func (f *DeviceConfiguration) requestKeyValueListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
+	return f.RequestKeyValueListData(ctrl, rf, nil, nil)
+}
+
+// RequestKeyValueListData reads the key values, optionally restricted by selectors and elements
+func (f *DeviceConfiguration) RequestKeyValueListData(ctrl spine.Context, rf spine.Feature, selectors *model.DeviceConfigurationKeyValueListDataSelectorsType, elements *model.DeviceConfigurationKeyValueDataElementsType) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		DeviceConfigurationKeyValueListData: &model.DeviceConfigurationKeyValueListDataType{},
 	}}
 
+	if selectors != nil || elements != nil {
+		filter := model.NewFilterTypePartial()
+		filter.DeviceConfigurationKeyValueListDataSelectors = selectors
+		filter.DeviceConfigurationKeyValueDataElements = elements
+
+		function := model.FunctionType(model.FunctionEnumTypeDeviceConfigurationKeyValueListData)
+		res[0].Function = &function
+		res[0].Filter = []model.FilterType{*filter}
+	}
+
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *DeviceConfiguration) replyKeyValueListData(ctrl spine.Context, data model.DeviceConfigurationKeyValueListDataType, isPartialForCmd bool) error {
+func (f *DeviceConfiguration) replyKeyValueListData(ctrl spine.Context, data model.DeviceConfigurationKeyValueListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":5}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":4}]},{"msgCounter":24307},{"msgCounterReference":34},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"deviceConfigurationKeyValueListData":[{"deviceConfigurationKeyValueData":[[{"keyId":1},{"value":[{"boolean":false}]}],[{"keyId":2},{"value":[{"string":"iso15118-2ed2"}]}]]}]}]]}]}]}}]}
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.3.0"},{"addressSource":[{"device":"d:_i:47859_Elli-Wallbox-2019A0OV8H"},{"entity":[1,1]},{"feature":24}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":4}]},{"msgCounter":767},{"cmdClassifier":"notify"}]},{"payload":[{"cmd":[[{"function":"deviceConfigurationKeyValueListData"},{"filter":[[{"cmdControl":[{"partial":[]}]}]]},{"deviceConfigurationKeyValueListData":[{"deviceConfigurationKeyValueData":[[{"keyId":1},{"value":[{"string":"iec61851"}]}]]}]}]]}]}]}}]}
@@ -90,11 +214,12 @@ func (f *DeviceConfiguration) replyKeyValueListData(ctrl spine.Context, data mod
 		return errors.New("deviceconfiguration.replyKeyValueListData: descriptionData is not set, needs to be requested first")
 	}
 
-	if !isPartialForCmd {
-		f.datasetData = nil
-	}
+	f.SetData(model.FunctionEnumTypeDeviceConfigurationKeyValueListData, &model.DeviceConfigurationKeyValueListDataType{
+		DeviceConfigurationKeyValueData: spine.UpdateList(f.KeyValueListData(), data.DeviceConfigurationKeyValueData, filterPartial, filterDelete),
+	})
 
-	for _, item := range data.DeviceConfigurationKeyValueData {
+	f.datasetData = nil
+	for _, item := range f.KeyValueListData() {
 		if item.KeyId == nil || item.Value == nil {
 			continue
 		}
@@ -122,30 +247,18 @@ func (f *DeviceConfiguration) replyKeyValueListData(ctrl spine.Context, data mod
 			KeyValueType: valueTypeForKeyID,
 		}
 
-		replaceIndex := -1
-		for index, datasetItem := range f.datasetData {
-			if datasetItem.KeyName == nameForKeyID {
-				replaceIndex = index
-				break
-			}
-		}
-
 		valid := true
-		switch valueTypeForKeyID {
-		case model.DeviceConfigurationKeyValueTypeTypeBoolean:
+		switch {
+		case valueTypeForKeyID == model.DeviceConfigurationKeyValueTypeTypeBoolean && item.Value.Boolean != nil:
 			newItem.KeyValueBoolean = *item.Value.Boolean
-		case model.DeviceConfigurationKeyValueTypeTypeString:
+		case valueTypeForKeyID == model.DeviceConfigurationKeyValueTypeTypeString && item.Value.String != nil:
 			newItem.KeyValueString = string(*item.Value.String)
 		default:
 			valid = false
 		}
 
 		if valid {
-			if replaceIndex != -1 {
-				f.datasetData[replaceIndex] = newItem
-			} else {
-				f.datasetData = append(f.datasetData, newItem)
-			}
+			f.datasetData = append(f.datasetData, newItem)
 		}
 	}
 
@@ -156,6 +269,24 @@ func (f *DeviceConfiguration) replyKeyValueListData(ctrl spine.Context, data mod
 	return nil
 }
 
+// WriteKeyValueListData writes the values of changeable keys
+func (f *DeviceConfiguration) WriteKeyValueListData(ctrl spine.Context, rf spine.Feature, data []model.DeviceConfigurationKeyValueDataType) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeDeviceConfigurationKeyValueListData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
+	function := model.FunctionType(model.FunctionEnumTypeDeviceConfigurationKeyValueListData)
+	res := []model.CmdType{{
+		Function: &function,
+		Filter:   []model.FilterType{*model.NewFilterTypePartial()},
+		DeviceConfigurationKeyValueListData: &model.DeviceConfigurationKeyValueListDataType{
+			DeviceConfigurationKeyValueData: data,
+		},
+	}}
+
+	return ctrl.Write(spine.FeatureAddressType(f), spine.FeatureAddressType(rf), res)
+}
+
 func (f *DeviceConfiguration) HandleRequest(ctrl spine.Context, fct model.FunctionEnumType, op model.CmdClassifierType, rf spine.Feature) (*model.MsgCounterType, error) {
 	switch fct {
 	case model.FunctionEnumTypeDeviceConfigurationKeyValueDescriptionListData:
@@ -175,14 +306,27 @@ func (f *DeviceConfiguration) HandleRequest(ctrl spine.Context, fct model.Functi
 }
 
 func (f *DeviceConfiguration) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	filterPartial, filterDelete := cmd.ExtractFilter()
+
+	if (op == model.CmdClassifierTypeReply || op == model.CmdClassifierTypeNotify) && fromEVSE(ctrl, rf) {
+		switch {
+		case cmd.DeviceConfigurationKeyValueDescriptionListData != nil:
+			f.evse.updateDescriptions(cmd.DeviceConfigurationKeyValueDescriptionListData.DeviceConfigurationKeyValueDescriptionData, filterPartial, filterDelete)
+			return nil
+		case cmd.DeviceConfigurationKeyValueListData != nil:
+			f.evse.updateValues(cmd.DeviceConfigurationKeyValueListData.DeviceConfigurationKeyValueData, filterPartial, filterDelete)
+			return nil
+		}
+	}
+
 	switch {
 	case cmd.DeviceConfigurationKeyValueDescriptionListData != nil:
 		data := cmd.DeviceConfigurationKeyValueDescriptionListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyKeyValueDescriptionListData(ctrl, *data)
+			return f.replyKeyValueDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyKeyValueDescriptionListData(ctrl, *data)
+			return f.replyKeyValueDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("deviceconfiguration.Handle: DeviceConfigurationKeyValueDescriptionListData CmdClassifierType not implemented: %s", op)
@@ -191,9 +335,9 @@ func (f *DeviceConfiguration) Handle(ctrl spine.Context, rf model.FeatureAddress
 		data := cmd.DeviceConfigurationKeyValueListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyKeyValueListData(ctrl, *data, isPartialForCmd)
+			return f.replyKeyValueListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyKeyValueListData(ctrl, *data, isPartialForCmd)
+			return f.replyKeyValueListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("deviceconfiguration.Handle: DeviceConfigurationKeyValueListData CmdClassifierType not implemented: %s", op)
@@ -207,5 +351,19 @@ func (f *DeviceConfiguration) Handle(ctrl spine.Context, rf model.FeatureAddress
 }
 
 func (f *DeviceConfiguration) ServerFound(ctrl spine.Context, rf spine.Feature) error {
-	return ctrl.Subscribe(f, rf, model.FeatureTypeType(f.Type))
+	if err := ctrl.Subscribe(f, rf, model.FeatureTypeType(f.Type)); err != nil {
+		return err
+	}
+
+	// the EV's key values are read by the EV sequence, the EVSE's as soon as its feature is found
+	if e := rf.GetEntity(); e == nil || model.EntityTypeEnumType(e.GetType()) != model.EntityTypeEnumTypeEVSE {
+		return nil
+	}
+
+	if _, err := f.requestKeyValueDescriptionListData(ctrl, rf); err != nil {
+		return err
+	}
+
+	_, err := f.requestKeyValueListData(ctrl, rf)
+	return err
 }
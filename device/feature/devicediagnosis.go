@@ -17,6 +17,7 @@ type DeviceDiagnosisData interface {
 
 type DeviceDiagnosisDelegate interface {
 	UpdateDeviceDiagnosisData(*DeviceDiagnosis, model.FeatureAddressType, DeviceDiagnosisDataType)
+	UpdateHeartbeatData(*DeviceDiagnosis, model.FeatureAddressType, model.DeviceDiagnosisHeartbeatDataType)
 }
 
 type DeviceDiagnosis struct {
@@ -54,23 +55,45 @@ func (f *DeviceDiagnosis) GetDeviceDiagnosisDataData() DeviceDiagnosisDataType {
 	return f.data
 }
 
-func (f *DeviceDiagnosis) readHeartbeatData(ctrl spine.Context, data model.DeviceDiagnosisHeartbeatDataType) error {
-	// TODO is this all we need here?
+// StateData returns the cached state data of the remote device
+func (f *DeviceDiagnosis) StateData() *model.DeviceDiagnosisStateDataType {
+	return spine.FeatureData[model.DeviceDiagnosisStateDataType](f, model.FunctionEnumTypeDeviceDiagnosisStateData)
+}
+
+// HeartbeatData returns the last heartbeat data received from the remote device
+func (f *DeviceDiagnosis) HeartbeatData() *model.DeviceDiagnosisHeartbeatDataType {
+	return spine.FeatureData[model.DeviceDiagnosisHeartbeatDataType](f, model.FunctionEnumTypeDeviceDiagnosisHeartbeatData)
+}
 
-	var heartBeatTimeout string = "PT4S"
+func (f *DeviceDiagnosis) readHeartbeatData(ctrl spine.Context, data model.DeviceDiagnosisHeartbeatDataType) error {
+	hb := ctrl.HeartbeatData()
 
 	res := model.CmdType{
-		DeviceDiagnosisHeartbeatData: &model.DeviceDiagnosisHeartbeatDataType{
-			HeartbeatCounter: ctrl.HeartbeatCounter(),
-			HeartbeatTimeout: &heartBeatTimeout,
-		},
+		DeviceDiagnosisHeartbeatData: &hb,
 	}
 
-	err := ctrl.Reply(model.CmdClassifierTypeReply, res)
+	return ctrl.Reply(model.CmdClassifierTypeReply, res)
+}
+
+func (f *DeviceDiagnosis) requestHeartbeatData(ctrl spine.Context, rf spine.Feature) error {
+	res := []model.CmdType{{
+		DeviceDiagnosisHeartbeatData: &model.DeviceDiagnosisHeartbeatDataType{},
+	}}
 
+	_, err := ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 	return err
 }
 
+func (f *DeviceDiagnosis) replyHeartbeatData(ctrl spine.Context, rf model.FeatureAddressType, data model.DeviceDiagnosisHeartbeatDataType) error {
+	f.SetData(model.FunctionEnumTypeDeviceDiagnosisHeartbeatData, &data)
+
+	if f.Delegate != nil {
+		f.Delegate.UpdateHeartbeatData(f, rf, data)
+	}
+
+	return nil
+}
+
 func (f *DeviceDiagnosis) requestStateData(ctrl spine.Context, rf spine.Feature) error {
 	res := []model.CmdType{{
 		DeviceDiagnosisStateData: &model.DeviceDiagnosisStateDataType{},
@@ -97,7 +120,14 @@ func (f *DeviceDiagnosis) readStateData(ctrl spine.Context, data model.DeviceDia
 }
 
 func (f *DeviceDiagnosis) replyStateData(ctrl spine.Context, rf model.FeatureAddressType, data model.DeviceDiagnosisStateDataType) error {
-	if f.Delegate != nil {
+	// the operating state is kept per remote entity, e.g. for EVSE and EV
+	if re := remoteEntity(ctrl, rf); re != nil && data.OperatingState != nil {
+		re.SetOperationState(*data.OperatingState)
+	}
+
+	f.SetData(model.FunctionEnumTypeDeviceDiagnosisStateData, &data)
+
+	if f.Delegate != nil && data.OperatingState != nil {
 		f.Delegate.UpdateDeviceDiagnosisData(f, rf, DeviceDiagnosisDataType{OperationState: model.DeviceDiagnosisOperatingStateEnumType(*data.OperatingState)})
 	}
 
@@ -126,6 +156,12 @@ func (f *DeviceDiagnosis) Handle(ctrl spine.Context, rf model.FeatureAddressType
 		case model.CmdClassifierTypeRead:
 			return f.readHeartbeatData(ctrl, *data)
 
+		case model.CmdClassifierTypeReply:
+			return f.replyHeartbeatData(ctrl, rf, *data)
+
+		case model.CmdClassifierTypeNotify:
+			return f.replyHeartbeatData(ctrl, rf, *data)
+
 		default:
 			return fmt.Errorf("devicediagnosis.Handle: DeviceDiagnosisHeartbeatData CmdClassifierType not implemented. %s", op)
 		}
@@ -160,5 +196,13 @@ func (f *DeviceDiagnosis) ServerFound(ctrl spine.Context, rf spine.Feature) erro
 	if err != nil {
 		return err
 	}
+
+	// the initial heartbeat starts supervising the remote device before the first notify
+	if rf.SupportForFunctionAvailable(model.FunctionEnumTypeDeviceDiagnosisHeartbeatData) {
+		if err := f.requestHeartbeatData(ctrl, rf); err != nil {
+			return err
+		}
+	}
+
 	return f.requestStateData(ctrl, rf)
 }
@@ -2,6 +2,7 @@ package feature
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/evcc-io/eebus/spine"
 	"github.com/evcc-io/eebus/spine/model"
@@ -44,6 +45,7 @@ type ElectricalConnection struct {
 	parameterDescriptionData []ElectricalConnectionParameterDescriptionDataType
 	descriptionData          []ElectricalConnectionDatasetDataType
 	permittedData            []ElectricalConnectionPermittedDataType
+	mux                      sync.Mutex
 }
 
 func NewElectricalConnectionClient() spine.Feature {
@@ -57,22 +59,61 @@ func NewElectricalConnectionClient() spine.Feature {
 	return f
 }
 
-func (f *ElectricalConnection) EVDisconnectEvent() {
+// EVDisconnect clears the electrical connection data so that they are not used for the next EV
+func (f *ElectricalConnection) EVDisconnect() {
+	f.ClearData()
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
 	f.parameterDescriptionData = nil
 	f.descriptionData = nil
 	f.permittedData = nil
 }
 
+// ParameterDescriptionListData returns the cached parameter descriptions
+func (f *ElectricalConnection) ParameterDescriptionListData() []model.ElectricalConnectionParameterDescriptionDataType {
+	if data := spine.FeatureData[model.ElectricalConnectionParameterDescriptionListDataType](f, model.FunctionEnumTypeElectricalConnectionParameterDescriptionListData); data != nil {
+		return data.ElectricalConnectionParameterDescriptionData
+	}
+	return nil
+}
+
+// DescriptionListData returns the cached electrical connection descriptions
+func (f *ElectricalConnection) DescriptionListData() []model.ElectricalConnectionDescriptionDataType {
+	if data := spine.FeatureData[model.ElectricalConnectionDescriptionListDataType](f, model.FunctionEnumTypeElectricalConnectionDescriptionListData); data != nil {
+		return data.ElectricalConnectionDescriptionData
+	}
+	return nil
+}
+
+// PermittedValueSetListData returns the cached permitted value sets
+func (f *ElectricalConnection) PermittedValueSetListData() []model.ElectricalConnectionPermittedValueSetDataType {
+	if data := spine.FeatureData[model.ElectricalConnectionPermittedValueSetListDataType](f, model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData); data != nil {
+		return data.ElectricalConnectionPermittedValueSetData
+	}
+	return nil
+}
+
 func (f *ElectricalConnection) GetElectricalConnectionDescription() []ElectricalConnectionParameterDescriptionDataType {
-	return f.parameterDescriptionData
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]ElectricalConnectionParameterDescriptionDataType(nil), f.parameterDescriptionData...)
 }
 
 func (f *ElectricalConnection) GetElectricalConnectionData() []ElectricalConnectionDatasetDataType {
-	return f.descriptionData
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]ElectricalConnectionDatasetDataType(nil), f.descriptionData...)
 }
 
 func (f *ElectricalConnection) GetElectricalConnectionPermittedData() []ElectricalConnectionPermittedDataType {
-	return f.permittedData
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]ElectricalConnectionPermittedDataType(nil), f.permittedData...)
 }
 
 func (f *ElectricalConnection) requestParameterDescriptionListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
@@ -83,7 +124,7 @@ func (f *ElectricalConnection) requestParameterDescriptionListData(ctrl spine.Co
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *ElectricalConnection) replyParameterDescriptionListData(ctrl spine.Context, data model.ElectricalConnectionParameterDescriptionListDataType) error {
+func (f *ElectricalConnection) replyParameterDescriptionListData(ctrl spine.Context, data model.ElectricalConnectionParameterDescriptionListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":2}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":8}]},{"msgCounter":15976},{"msgCounterReference":34},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"electricalConnectionParameterDescriptionListData":[{"electricalConnectionParameterDescriptionData":[[{"electricalConnectionId":0},{"parameterId":1},{"measurementId":1},{"voltageType":"ac"},{"acMeasuredPhases":"a"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":2},{"measurementId":4},{"voltageType":"ac"},{"acMeasuredPhases":"a"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":3},{"measurementId":2},{"voltageType":"ac"},{"acMeasuredPhases":"b"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":4},{"measurementId":5},{"voltageType":"ac"},{"acMeasuredPhases":"b"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":5},{"measurementId":3},{"voltageType":"ac"},{"acMeasuredPhases":"c"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":6},{"measurementId":6},{"voltageType":"ac"},{"acMeasuredPhases":"c"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":7},{"measurementId":7},{"voltageType":"ac"},{"acMeasuredPhases":"abc"},{"acMeasuredInReferenceTo":"neutral"},{"acMeasurementType":"real"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":8},{"acMeasuredPhases":"abc"},{"scopeType":"acPowerTotal"}]]}]}]]}]}]}}]}
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.3.0"},{"addressSource":[{"device":"d:_i:47859_Elli-Wallbox-2019A0OV8H"},{"entity":[1,1]},{"feature":7}]},{"addressDestination":[{"device":"d:_i:EVCC_HEMS"},{"entity":[1]},{"feature":8}]},{"msgCounter":105},{"msgCounterReference":46},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"electricalConnectionParameterDescriptionListData":[{"electricalConnectionParameterDescriptionData":[[{"electricalConnectionId":0},{"parameterId":0},{"acMeasuredPhases":"abc"},{"scopeType":"acPowerTotal"}],[{"electricalConnectionId":0},{"parameterId":1},{"measurementId":0},{"acMeasuredPhases":"a"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":2},{"measurementId":1},{"acMeasuredPhases":"b"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":3},{"measurementId":2},{"acMeasuredPhases":"c"},{"acMeasurementVariant":"rms"}],[{"electricalConnectionId":0},{"parameterId":4},{"measurementId":3},{"acMeasuredPhases":"a"}],[{"electricalConnectionId":0},{"parameterId":5},{"measurementId":4},{"acMeasuredPhases":"b"}],[{"electricalConnectionId":0},{"parameterId":6},{"measurementId":5},{"acMeasuredPhases":"c"}],[{"electricalConnectionId":0},{"parameterId":7},{"measurementId":6},{"voltageType":"ac"},{"acMeasuredPhases":"abc"},{"acMeasurementType":"real"}]]}]}]]}]}]}}]}
@@ -95,8 +136,12 @@ func (f *ElectricalConnection) replyParameterDescriptionListData(ctrl spine.Cont
 		"c": 3,
 	}
 
-	f.parameterDescriptionData = nil
-	for _, item := range data.ElectricalConnectionParameterDescriptionData {
+	f.SetData(model.FunctionEnumTypeElectricalConnectionParameterDescriptionListData, &model.ElectricalConnectionParameterDescriptionListDataType{
+		ElectricalConnectionParameterDescriptionData: spine.UpdateList(f.ParameterDescriptionListData(), data.ElectricalConnectionParameterDescriptionData, filterPartial, filterDelete),
+	})
+
+	var parameterDescriptionData []ElectricalConnectionParameterDescriptionDataType
+	for _, item := range f.ParameterDescriptionListData() {
 		if item.ElectricalConnectionId == nil || item.ParameterId == nil || item.AcMeasuredPhases == nil {
 			continue
 		}
@@ -116,10 +161,14 @@ func (f *ElectricalConnection) replyParameterDescriptionListData(ctrl spine.Cont
 			if phaseValue, ok := phases[phasesValue]; ok {
 				newItem.Phase = phaseValue
 			}
-			f.parameterDescriptionData = append(f.parameterDescriptionData, newItem)
+			parameterDescriptionData = append(parameterDescriptionData, newItem)
 		}
 	}
 
+	f.mux.Lock()
+	f.parameterDescriptionData = parameterDescriptionData
+	f.mux.Unlock()
+
 	return nil
 }
 
@@ -131,13 +180,17 @@ func (f *ElectricalConnection) requestDescriptionListData(ctrl spine.Context, rf
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *ElectricalConnection) replyDescriptionListData(ctrl spine.Context, data model.ElectricalConnectionDescriptionListDataType) error {
+func (f *ElectricalConnection) replyDescriptionListData(ctrl spine.Context, data model.ElectricalConnectionDescriptionListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":2}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":8}]},{"msgCounter":15981},{"msgCounterReference":35},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"electricalConnectionDescriptionListData":[{"electricalConnectionDescriptionData":[[{"electricalConnectionId":0},{"powerSupplyType":"ac"},{"acConnectedPhases":3},{"positiveEnergyDirection":"consume"}]]}]}]]}]}]}}]}
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.3.0"},{"addressSource":[{"device":"d:_i:47859_Elli-Wallbox-2019A0OV8H"},{"entity":[1,1]},{"feature":7}]},{"addressDestination":[{"device":"d:_i:EVCC_HEMS"},{"entity":[1]},{"feature":8}]},{"msgCounter":114},{"msgCounterReference":54},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"electricalConnectionDescriptionListData":[{"electricalConnectionDescriptionData":[[{"electricalConnectionId":0},{"powerSupplyType":"ac"},{"positiveEnergyDirection":"consume"}]]}]}]]}]}]}}]}
 
-	f.descriptionData = nil
-	for _, item := range data.ElectricalConnectionDescriptionData {
+	f.SetData(model.FunctionEnumTypeElectricalConnectionDescriptionListData, &model.ElectricalConnectionDescriptionListDataType{
+		ElectricalConnectionDescriptionData: spine.UpdateList(f.DescriptionListData(), data.ElectricalConnectionDescriptionData, filterPartial, filterDelete),
+	})
+
+	var descriptionData []ElectricalConnectionDatasetDataType
+	for _, item := range f.DescriptionListData() {
 		if item.ElectricalConnectionId == nil {
 			continue
 		}
@@ -152,9 +205,13 @@ func (f *ElectricalConnection) replyDescriptionListData(ctrl spine.Context, data
 			// Assume this
 			newItem.ConnectedPhases = 3
 		}
-		f.descriptionData = append(f.descriptionData, newItem)
+		descriptionData = append(descriptionData, newItem)
 	}
 
+	f.mux.Lock()
+	f.descriptionData = descriptionData
+	f.mux.Unlock()
+
 	if f.Delegate != nil {
 		f.Delegate.UpdateElectricalConnectionData(f)
 	}
@@ -163,14 +220,29 @@ func (f *ElectricalConnection) replyDescriptionListData(ctrl spine.Context, data
 }
 
 func (f *ElectricalConnection) requestPermittedValueSetData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
+	return f.RequestPermittedValueSetListData(ctrl, rf, nil, nil)
+}
+
+// RequestPermittedValueSetListData reads the permitted values, optionally restricted by selectors and elements
+func (f *ElectricalConnection) RequestPermittedValueSetListData(ctrl spine.Context, rf spine.Feature, selectors *model.ElectricalConnectionPermittedValueSetListDataSelectorsType, elements *model.ElectricalConnectionPermittedValueSetDataElementsType) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		ElectricalConnectionPermittedValueSetListData: &model.ElectricalConnectionPermittedValueSetListDataType{},
 	}}
 
+	if selectors != nil || elements != nil {
+		filter := model.NewFilterTypePartial()
+		filter.ElectricalConnectionPermittedValueSetListDataSelectors = selectors
+		filter.ElectricalConnectionPermittedValueSetDataElements = elements
+
+		function := model.FunctionType(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData)
+		res[0].Function = &function
+		res[0].Filter = []model.FilterType{*filter}
+	}
+
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *ElectricalConnection) replyPermittedValueSetData(ctrl spine.Context, data model.ElectricalConnectionPermittedValueSetListDataType, isPartialForCmd bool, filter []model.FilterType) error {
+func (f *ElectricalConnection) replyPermittedValueSetData(ctrl spine.Context, data model.ElectricalConnectionPermittedValueSetListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":2}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":8}]},{"msgCounter":1793},{"msgCounterReference":35},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"electricalConnectionPermittedValueSetListData":[{"electricalConnectionPermittedValueSetData":[[{"electricalConnectionId":0},{"parameterId":1},{"permittedValueSet":[[{"value":[[{"number":100},{"scale":-3}]]},{"range":[[{"min":[{"number":2},{"scale":0}]},{"max":[{"number":16},{"scale":0}]}]]}]]}],[{"electricalConnectionId":0},{"parameterId":8},{"permittedValueSet":[[{"value":[[{"number":100},{"scale":-3}]]},{"range":[[{"min":[{"number":490},{"scale":0}]},{"max":[{"number":3920},{"scale":0}]}]]}]]}]]}]}]]}]}]}}]}
 	// {"cmd":[[
@@ -239,30 +311,12 @@ func (f *ElectricalConnection) replyPermittedValueSetData(ctrl spine.Context, da
 	// 			{"permittedValueSet":[[{"range":[[{"min":[{"number":1},{"scale":0}]}]]}]]}
 	// ]]}]}]]}]}]}}]}
 
-	if !isPartialForCmd {
-		f.permittedData = nil
-	}
-
-	for _, filterItem := range filter {
-		if filterItem.CmdControl != nil && filterItem.CmdControl.Delete != nil {
-			selector := filterItem.ElectricalConnectionPermittedValueSetListDataSelectors
-			if selector != nil && selector.ElectricalConnectionId != nil && selector.ParameterId != nil {
-
-				connectionID := uint(*selector.ElectricalConnectionId)
-				parameterID := uint(*selector.ParameterId)
-
-				newValueSetData := make([]ElectricalConnectionPermittedDataType, 0)
-				for _, item := range f.permittedData {
-					if connectionID != uint(item.ElectricalConnectionId) || parameterID != uint(item.ParameterId) {
-						newValueSetData = append(newValueSetData, item)
-					}
-				}
-				f.permittedData = newValueSetData
-			}
-		}
-	}
+	f.SetData(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData, &model.ElectricalConnectionPermittedValueSetListDataType{
+		ElectricalConnectionPermittedValueSetData: spine.UpdateList(f.PermittedValueSetListData(), data.ElectricalConnectionPermittedValueSetData, filterPartial, filterDelete),
+	})
 
-	for _, item := range data.ElectricalConnectionPermittedValueSetData {
+	var permittedData []ElectricalConnectionPermittedDataType
+	for _, item := range f.PermittedValueSetListData() {
 		if item.ElectricalConnectionId == nil || item.ParameterId == nil {
 			continue
 		}
@@ -272,16 +326,6 @@ func (f *ElectricalConnection) replyPermittedValueSetData(ctrl spine.Context, da
 			ParameterId:            uint(*item.ParameterId),
 		}
 
-		replaceIndex := -1
-		if isPartialForCmd {
-			for index, datasetItem := range f.permittedData {
-				if datasetItem.ElectricalConnectionId == uint(*item.ElectricalConnectionId) && datasetItem.ParameterId == uint(*item.ParameterId) {
-					replaceIndex = index
-					break
-				}
-			}
-		}
-
 		if len(item.PermittedValueSet) > 0 {
 			valueData := item.PermittedValueSet[0].Value
 			if len(valueData) > 0 {
@@ -299,13 +343,13 @@ func (f *ElectricalConnection) replyPermittedValueSetData(ctrl spine.Context, da
 				}
 			}
 		}
-		if replaceIndex != -1 {
-			f.permittedData[replaceIndex] = dataSetItem
-		} else {
-			f.permittedData = append(f.permittedData, dataSetItem)
-		}
+		permittedData = append(permittedData, dataSetItem)
 	}
 
+	f.mux.Lock()
+	f.permittedData = permittedData
+	f.mux.Unlock()
+
 	if f.Delegate != nil {
 		f.Delegate.UpdateElectricalConnectionData(f)
 	}
@@ -339,15 +383,17 @@ func (f *ElectricalConnection) HandleRequest(ctrl spine.Context, fct model.Funct
 }
 
 func (f *ElectricalConnection) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	filterPartial, filterDelete := cmd.ExtractFilter()
+
 	switch {
 	case cmd.ElectricalConnectionParameterDescriptionListData != nil:
 		data := cmd.ElectricalConnectionParameterDescriptionListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyParameterDescriptionListData(ctrl, *data)
+			return f.replyParameterDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyParameterDescriptionListData(ctrl, *data)
+			return f.replyParameterDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("electricalconnection.Handle: ElectricalConnectionParameterDescriptionListData CmdClassifierType not implemented: %s", op)
@@ -357,10 +403,10 @@ func (f *ElectricalConnection) Handle(ctrl spine.Context, rf model.FeatureAddres
 		data := cmd.ElectricalConnectionDescriptionListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyDescriptionListData(ctrl, *data)
+			return f.replyDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyDescriptionListData(ctrl, *data)
+			return f.replyDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("electricalconnection.Handle: ElectricalConnectionDescriptionListData CmdClassifierType not implemented: %s", op)
@@ -370,10 +416,10 @@ func (f *ElectricalConnection) Handle(ctrl spine.Context, rf model.FeatureAddres
 		data := cmd.ElectricalConnectionPermittedValueSetListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyPermittedValueSetData(ctrl, *data, isPartialForCmd, cmd.Filter)
+			return f.replyPermittedValueSetData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyPermittedValueSetData(ctrl, *data, isPartialForCmd, cmd.Filter)
+			return f.replyPermittedValueSetData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("electricalconnection.Handle: ElectricalConnectionPermittedValueSetListData CmdClassifierType not implemented: %s", op)
@@ -0,0 +1,399 @@
+package feature
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// NominalVoltage is the phase voltage used for deriving power limits if no voltage is measured
+const NominalVoltage = 230.0
+
+// LimitSource is the provenance of a power limit
+type LimitSource string
+
+const (
+	LimitSourceReported LimitSource = "reported" // permitted value set of a power parameter
+	LimitSourceMeasured LimitSource = "measured" // current limit multiplied by the measured voltage
+	LimitSourceDerived  LimitSource = "derived"  // current limit multiplied by the nominal voltage
+	LimitSourceMinimum  LimitSource = "minimum"  // raised to the minimum current of the communication standard
+)
+
+// minimum charging currents of the communication standards
+const (
+	MinimumCurrentIEC61851  = 6.0 // A
+	MinimumCurrentISO151182 = 2.2 // A, 3-phase charging
+)
+
+// implausiblePowerLimit is the power limit up to which reported power limits are not trusted
+const implausiblePowerLimit = 100.0 // W
+
+// limitSourceAccuracy orders the sources from most to least accurate
+var limitSourceAccuracy = map[LimitSource]int{
+	LimitSourceReported: 0,
+	LimitSourceMeasured: 1,
+	LimitSourceDerived:  2,
+	LimitSourceMinimum:  3,
+}
+
+// MinimumCurrent returns the minimum charging current per phase of the communication standard, e.g. iso15118-2ed1.
+// Unknown standards are treated as IEC 61851.
+func MinimumCurrent(standard string, phases uint) float64 {
+	if strings.HasPrefix(standard, "iso15118-2") && phases == 3 {
+		return MinimumCurrentISO151182
+	}
+
+	return MinimumCurrentIEC61851
+}
+
+// PhaseLimits are the permitted currents and powers of a single phase
+type PhaseLimits struct {
+	Phase          uint
+	CurrentMin     float64 // A
+	CurrentMax     float64 // A
+	CurrentDefault float64 // A, used for pausing the charging process
+	PowerMin       float64 // W
+	PowerMax       float64 // W
+	Voltage        float64 // V, used for deriving the power limits
+	Source         LimitSource
+}
+
+// ElectricalLimits are the permitted charging limits per phase and in total
+type ElectricalLimits struct {
+	Phases          []PhaseLimits // ordered by phase
+	PowerMin        float64       // W
+	PowerMax        float64       // W
+	Source          LimitSource   // reported if the total is reported, else the least accurate source of the phases
+	ConnectedPhases uint
+}
+
+var phaseNames = map[model.ElectricalConnectionPhaseNameEnumType][]uint{
+	model.ElectricalConnectionPhaseNameEnumTypeA:   {1},
+	model.ElectricalConnectionPhaseNameEnumTypeB:   {2},
+	model.ElectricalConnectionPhaseNameEnumTypeC:   {3},
+	model.ElectricalConnectionPhaseNameEnumTypeAb:  {1, 2},
+	model.ElectricalConnectionPhaseNameEnumTypeBc:  {2, 3},
+	model.ElectricalConnectionPhaseNameEnumTypeAc:  {1, 3},
+	model.ElectricalConnectionPhaseNameEnumTypeAbc: {1, 2, 3},
+}
+
+var voltageScopes = map[model.ScopeTypeEnumType]uint{
+	model.ScopeTypeEnumTypeACVoltageA: 1,
+	model.ScopeTypeEnumTypeACVoltageB: 2,
+	model.ScopeTypeEnumTypeACVoltageC: 3,
+}
+
+// permittedRange combines all permitted value sets into the first value and the widest range
+type permittedRange struct {
+	value, min, max float64
+}
+
+func newPermittedRange(sets []model.ScaledNumberSetType) (permittedRange, bool) {
+	var res permittedRange
+	var hasValue, hasRange bool
+
+	for _, set := range sets {
+		if len(set.Value) > 0 && !hasValue {
+			res.value = set.Value[0].GetValue()
+			hasValue = true
+		}
+
+		for _, r := range set.Range {
+			if r.Min != nil && (!hasRange || r.Min.GetValue() < res.min) {
+				res.min = r.Min.GetValue()
+			}
+			if r.Max != nil && (!hasRange || r.Max.GetValue() > res.max) {
+				res.max = r.Max.GetValue()
+			}
+			hasRange = hasRange || r.Min != nil || r.Max != nil
+		}
+	}
+
+	return res, hasValue || hasRange
+}
+
+// ElectricalLimits calculates the charging limits from the permitted value sets of all parameters.
+// Power limits not reported by the remote device are derived from the current limits using the measured
+// voltage of the phase if available, the nominal voltage otherwise. The measurement feature is optional.
+func (f *ElectricalConnection) ElectricalLimits(m *Measurement, nominalVoltage float64) (ElectricalLimits, bool) {
+	params := f.ParameterDescriptionListData()
+
+	scopes := make(map[model.MeasurementIdType]model.ScopeTypeEnumType)
+	if m != nil {
+		for _, item := range m.DescriptionListData() {
+			if item.MeasurementId != nil && item.ScopeType != nil {
+				scopes[*item.MeasurementId] = model.ScopeTypeEnumType(*item.ScopeType)
+			}
+		}
+	}
+
+	limits := make(map[uint]*PhaseLimits)
+	phase := func(p uint) *PhaseLimits {
+		if _, ok := limits[p]; !ok {
+			limits[p] = &PhaseLimits{Phase: p}
+		}
+		return limits[p]
+	}
+
+	hasCurrent := make(map[uint]bool)
+	hasPower := make(map[uint]bool)
+	singlePhase := make(map[uint]bool) // single phase parameters take precedence over phase combinations
+
+	var total *permittedRange
+
+	for _, item := range f.PermittedValueSetListData() {
+		if item.ParameterId == nil {
+			continue
+		}
+
+		r, ok := newPermittedRange(item.PermittedValueSet)
+		if !ok {
+			continue
+		}
+
+		for _, param := range params {
+			if param.ParameterId == nil || *param.ParameterId != *item.ParameterId {
+				continue
+			}
+
+			var scope model.ScopeTypeEnumType
+			if param.ScopeType != nil {
+				scope = model.ScopeTypeEnumType(*param.ScopeType)
+			} else if param.MeasurementId != nil {
+				scope = scopes[*param.MeasurementId]
+			}
+
+			var ps []uint
+			if param.AcMeasuredPhases != nil {
+				ps = phaseNames[model.ElectricalConnectionPhaseNameEnumType(*param.AcMeasuredPhases)]
+			}
+
+			switch {
+			case scope == model.ScopeTypeEnumTypeACPowerTotal,
+				scope == model.ScopeTypeEnumTypeACPower && len(ps) == len(phaseNames[model.ElectricalConnectionPhaseNameEnumTypeAbc]):
+				r := r
+				total = &r
+
+			case scope == model.ScopeTypeEnumTypeACPower:
+				for _, p := range ps {
+					if len(ps) > 1 && singlePhase[p] {
+						continue
+					}
+
+					l := phase(p)
+					l.PowerMin, l.PowerMax = r.min/float64(len(ps)), r.max/float64(len(ps))
+					l.Source = LimitSourceReported
+					hasPower[p] = true
+				}
+
+			case scope == model.ScopeTypeEnumTypeACCurrent:
+				for _, p := range ps {
+					if len(ps) > 1 && singlePhase[p] {
+						continue
+					}
+
+					l := phase(p)
+					l.CurrentMin, l.CurrentMax, l.CurrentDefault = r.min, r.max, r.value
+					hasCurrent[p] = true
+				}
+			}
+
+			if len(ps) == 1 {
+				singlePhase[ps[0]] = true
+			}
+		}
+	}
+
+	voltages := measuredVoltages(m, params, scopes)
+
+	var res ElectricalLimits
+	for p, l := range limits {
+		if !hasPower[p] {
+			if !hasCurrent[p] {
+				continue
+			}
+
+			l.Voltage, l.Source = nominalVoltage, LimitSourceDerived
+			if v, ok := voltages[p]; ok {
+				l.Voltage, l.Source = v, LimitSourceMeasured
+			}
+
+			l.PowerMin, l.PowerMax = l.CurrentMin*l.Voltage, l.CurrentMax*l.Voltage
+		}
+
+		res.Phases = append(res.Phases, *l)
+	}
+
+	sort.Slice(res.Phases, func(i, j int) bool {
+		return res.Phases[i].Phase < res.Phases[j].Phase
+	})
+
+	// only the connected phases contribute to the total
+	res.ConnectedPhases = uint(len(phaseNames[model.ElectricalConnectionPhaseNameEnumTypeAbc]))
+	for _, item := range f.DescriptionListData() {
+		if item.AcConnectedPhases != nil && *item.AcConnectedPhases > 0 {
+			res.ConnectedPhases = *item.AcConnectedPhases
+		}
+	}
+
+	if total != nil && total.max > 0 {
+		res.PowerMin, res.PowerMax, res.Source = total.min, total.max, LimitSourceReported
+		return res, true
+	}
+
+	if len(res.Phases) == 0 {
+		return res, false
+	}
+
+	res.Source = LimitSourceReported
+	for _, l := range res.Phases {
+		if l.Phase > res.ConnectedPhases {
+			continue
+		}
+
+		res.PowerMin += l.PowerMin
+		res.PowerMax += l.PowerMax
+
+		if limitSourceAccuracy[l.Source] > limitSourceAccuracy[res.Source] {
+			res.Source = l.Source
+		}
+	}
+
+	return res, true
+}
+
+// ApplyMinimumCurrent raises the minimum currents to minCurrent, as the EV's minimum currents are only reliable
+// with ISO 15118-2 VAS. A reported minimum power above the resulting minimum takes precedence. Minimums are
+// raised up to the maximum of the phase only, a phase permitting no current stays at 0 A. Power limits
+// of 100 W or less are replaced by the totals of the phases. Raised limits have the source LimitSourceMinimum.
+func (l ElectricalLimits) ApplyMinimumCurrent(minCurrent, nominalVoltage float64) ElectricalLimits {
+	res := l
+	res.Phases = append([]PhaseLimits(nil), l.Phases...)
+
+	voltage := func(p PhaseLimits) float64 {
+		if p.Voltage > 0 {
+			return p.Voltage
+		}
+		return nominalVoltage
+	}
+
+	var connected []*PhaseLimits
+	for i := range res.Phases {
+		if res.Phases[i].Phase <= res.ConnectedPhases {
+			connected = append(connected, &res.Phases[i])
+		}
+	}
+
+	if len(connected) == 0 {
+		return res
+	}
+
+	// the minimum current matching the reported minimum power
+	if res.PowerMin > implausiblePowerLimit && res.Source == LimitSourceReported {
+		var minPower float64
+		for _, p := range connected {
+			minPower += p.CurrentMin * voltage(*p)
+		}
+
+		if minPower < res.PowerMin {
+			if current := res.PowerMin / nominalVoltage / float64(len(connected)); current > minCurrent {
+				minCurrent = current
+			}
+		}
+	}
+
+	raised := false
+	for i := range res.Phases {
+		p := &res.Phases[i]
+
+		// the minimum never exceeds the maximum of the phase
+		current := math.Min(minCurrent, p.CurrentMax)
+		if p.CurrentMin >= current {
+			continue
+		}
+
+		p.CurrentMin = current
+		if power := math.Min(current*voltage(*p), p.PowerMax); p.PowerMin < power {
+			p.PowerMin = power
+		}
+		p.Source = LimitSourceMinimum
+		raised = true
+	}
+
+	var minPower, maxPower float64
+	source := LimitSourceReported
+	for _, p := range connected {
+		minPower += p.PowerMin
+		maxPower += p.PowerMax
+
+		if limitSourceAccuracy[p.Source] > limitSourceAccuracy[source] {
+			source = p.Source
+		}
+	}
+
+	if res.PowerMin < minPower && (raised || res.PowerMin <= implausiblePowerLimit) {
+		res.PowerMin = minPower
+		res.Source = source
+	}
+	if res.PowerMax <= implausiblePowerLimit && maxPower > res.PowerMax {
+		res.PowerMax = maxPower
+		if limitSourceAccuracy[source] > limitSourceAccuracy[res.Source] {
+			res.Source = source
+		}
+	}
+	if res.PowerMin > res.PowerMax {
+		res.PowerMin = res.PowerMax
+	}
+
+	return res
+}
+
+// measuredVoltages returns the valid phase to neutral voltages per phase
+func measuredVoltages(m *Measurement, params []model.ElectricalConnectionParameterDescriptionDataType, scopes map[model.MeasurementIdType]model.ScopeTypeEnumType) map[uint]float64 {
+	res := make(map[uint]float64)
+	if m == nil {
+		return res
+	}
+
+	for _, item := range m.ListData() {
+		if item.MeasurementId == nil || item.Value == nil || item.Value.GetValue() <= 0 {
+			continue
+		}
+		if item.ValueState != nil && model.MeasurementValueStateEnumType(*item.ValueState) != model.MeasurementValueStateEnumTypeNormal {
+			continue
+		}
+
+		scope := scopes[*item.MeasurementId]
+		if p, ok := voltageScopes[scope]; ok {
+			res[p] = item.Value.GetValue()
+			continue
+		}
+
+		if scope != model.ScopeTypeEnumTypeACVoltage {
+			continue
+		}
+
+		for _, param := range params {
+			if param.MeasurementId == nil || *param.MeasurementId != *item.MeasurementId || param.AcMeasuredPhases == nil {
+				continue
+			}
+
+			ps := phaseNames[model.ElectricalConnectionPhaseNameEnumType(*param.AcMeasuredPhases)]
+			switch len(ps) {
+			case 1:
+				res[ps[0]] = item.Value.GetValue()
+			case 2:
+				// phase to phase voltage
+				for _, p := range ps {
+					if _, ok := res[p]; !ok {
+						res[p] = item.Value.GetValue() / math.Sqrt(3)
+					}
+				}
+			}
+		}
+	}
+
+	return res
+}
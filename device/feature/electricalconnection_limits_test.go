@@ -0,0 +1,227 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestElectricalLimits(t *testing.T) {
+	ec := NewElectricalConnectionClient().(*ElectricalConnection)
+	m := NewMeasurementClient().(*Measurement)
+
+	param := func(id uint, measurement *uint, phases string, scope model.ScopeTypeEnumType) model.ElectricalConnectionParameterDescriptionDataType {
+		paramId := model.ElectricalConnectionParameterIdType(id)
+		phaseName := model.ElectricalConnectionPhaseNameType(phases)
+
+		res := model.ElectricalConnectionParameterDescriptionDataType{ParameterId: &paramId}
+		if measurement != nil {
+			measurementId := model.MeasurementIdType(*measurement)
+			res.MeasurementId = &measurementId
+		}
+		if phases != "" {
+			res.AcMeasuredPhases = &phaseName
+		}
+		if scope != "" {
+			scopeType := model.ScopeTypeType(scope)
+			res.ScopeType = &scopeType
+		}
+		return res
+	}
+
+	permitted := func(id uint, value float64, ranges ...[2]float64) model.ElectricalConnectionPermittedValueSetDataType {
+		paramId := model.ElectricalConnectionParameterIdType(id)
+
+		set := model.ScaledNumberSetType{Value: []model.ScaledNumberType{*model.NewScaledNumberType(value)}}
+		for _, r := range ranges {
+			set.Range = append(set.Range, model.ScaledNumberRangeType{Min: model.NewScaledNumberType(r[0]), Max: model.NewScaledNumberType(r[1])})
+		}
+		return model.ElectricalConnectionPermittedValueSetDataType{ParameterId: &paramId, PermittedValueSet: []model.ScaledNumberSetType{set}}
+	}
+
+	description := func(id uint, scope model.ScopeTypeEnumType) model.MeasurementDescriptionDataType {
+		measurementId := model.MeasurementIdType(id)
+		scopeType := model.ScopeTypeType(scope)
+		return model.MeasurementDescriptionDataType{MeasurementId: &measurementId, ScopeType: &scopeType}
+	}
+
+	measurement := func(id uint, value float64, state model.MeasurementValueStateEnumType) model.MeasurementDataType {
+		measurementId := model.MeasurementIdType(id)
+		valueState := model.MeasurementValueStateType(state)
+		return model.MeasurementDataType{MeasurementId: &measurementId, Value: model.NewScaledNumberType(value), ValueState: &valueState}
+	}
+
+	id := func(i uint) *uint { return &i }
+
+	params := []model.ElectricalConnectionParameterDescriptionDataType{
+		param(1, id(1), "a", ""),
+		param(2, id(2), "b", ""),
+		param(3, id(3), "c", ""),
+		param(4, id(4), "abc", ""), // combined current limit, overridden by the single phase limits
+		param(5, id(5), "a", ""),
+		param(6, id(6), "b", ""),
+		param(7, id(7), "c", ""),
+	}
+
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionParameterDescriptionListData, &model.ElectricalConnectionParameterDescriptionListDataType{
+		ElectricalConnectionParameterDescriptionData: params,
+	})
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData, &model.ElectricalConnectionPermittedValueSetListDataType{
+		ElectricalConnectionPermittedValueSetData: []model.ElectricalConnectionPermittedValueSetDataType{
+			permitted(1, 0, [2]float64{6, 10}, [2]float64{8, 16}), // ranges are combined
+			permitted(2, 0, [2]float64{6, 16}),
+			permitted(3, 0, [2]float64{6, 16}),
+			permitted(4, 0, [2]float64{1, 32}),
+		},
+	})
+
+	m.SetData(model.FunctionEnumTypeMeasurementDescriptionListData, &model.MeasurementDescriptionListDataType{
+		MeasurementDescriptionData: []model.MeasurementDescriptionDataType{
+			description(1, model.ScopeTypeEnumTypeACCurrent),
+			description(2, model.ScopeTypeEnumTypeACCurrent),
+			description(3, model.ScopeTypeEnumTypeACCurrent),
+			description(4, model.ScopeTypeEnumTypeACCurrent),
+			description(5, model.ScopeTypeEnumTypeACVoltage),
+			description(6, model.ScopeTypeEnumTypeACVoltage),
+			description(7, model.ScopeTypeEnumTypeACVoltage),
+		},
+	})
+	m.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{
+		MeasurementData: []model.MeasurementDataType{
+			measurement(5, 240, model.MeasurementValueStateEnumTypeNormal),
+			measurement(6, 235, model.MeasurementValueStateEnumTypeNormal),
+			measurement(7, 500, model.MeasurementValueStateEnumTypeError), // ignored
+		},
+	})
+
+	limits, ok := ec.ElectricalLimits(m, NominalVoltage)
+	if !ok {
+		t.Fatal("limits not available")
+	}
+
+	expected := []PhaseLimits{
+		{Phase: 1, CurrentMin: 6, CurrentMax: 16, PowerMin: 6 * 240, PowerMax: 16 * 240, Voltage: 240, Source: LimitSourceMeasured},
+		{Phase: 2, CurrentMin: 6, CurrentMax: 16, PowerMin: 6 * 235, PowerMax: 16 * 235, Voltage: 235, Source: LimitSourceMeasured},
+		{Phase: 3, CurrentMin: 6, CurrentMax: 16, PowerMin: 6 * 230, PowerMax: 16 * 230, Voltage: 230, Source: LimitSourceDerived},
+	}
+
+	if len(limits.Phases) != len(expected) {
+		t.Fatalf("expected %d phases, got %+v", len(expected), limits.Phases)
+	}
+	for i, l := range limits.Phases {
+		if l != expected[i] {
+			t.Errorf("phase %d: expected %+v, got %+v", i+1, expected[i], l)
+		}
+	}
+
+	if limits.PowerMin != 6*(240+235+230) || limits.PowerMax != 16*(240+235+230) || limits.Source != LimitSourceDerived {
+		t.Errorf("unexpected total limits: %+v", limits)
+	}
+
+	// a reported total power limit takes precedence
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionParameterDescriptionListData, &model.ElectricalConnectionParameterDescriptionListDataType{
+		ElectricalConnectionParameterDescriptionData: append(params, param(8, nil, "", model.ScopeTypeEnumTypeACPowerTotal)),
+	})
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData, &model.ElectricalConnectionPermittedValueSetListDataType{
+		ElectricalConnectionPermittedValueSetData: []model.ElectricalConnectionPermittedValueSetDataType{
+			permitted(1, 0, [2]float64{6, 16}),
+			permitted(2, 0, [2]float64{6, 16}),
+			permitted(3, 0, [2]float64{6, 16}),
+			permitted(8, 0, [2]float64{4200, 11000}),
+		},
+	})
+
+	if limits, _ = ec.ElectricalLimits(m, NominalVoltage); limits.PowerMin != 4200 || limits.PowerMax != 11000 || limits.Source != LimitSourceReported {
+		t.Errorf("unexpected total limits: %+v", limits)
+	}
+
+	// without measured voltages the nominal voltage is used
+	m.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{})
+
+	if limits, _ = ec.ElectricalLimits(m, NominalVoltage); limits.Phases[0].Source != LimitSourceDerived || limits.Phases[0].PowerMax != 16*230 {
+		t.Errorf("unexpected phase limits: %+v", limits.Phases[0])
+	}
+}
+
+func TestMinimumCurrent(t *testing.T) {
+	for _, tc := range []struct {
+		standard string
+		phases   uint
+		expected float64
+	}{
+		{"iec61851", 3, MinimumCurrentIEC61851},
+		{"iso15118-2ed1", 1, MinimumCurrentIEC61851},
+		{"iso15118-2ed1", 3, MinimumCurrentISO151182},
+		{"iso15118-2ed2", 3, MinimumCurrentISO151182},
+	} {
+		if res := MinimumCurrent(tc.standard, tc.phases); res != tc.expected {
+			t.Errorf("%s/%d: expected %.1fA, got %.1fA", tc.standard, tc.phases, tc.expected, res)
+		}
+	}
+}
+
+func TestApplyMinimumCurrent(t *testing.T) {
+	phase := func(phase uint, min, max float64) PhaseLimits {
+		return PhaseLimits{Phase: phase, CurrentMin: min, CurrentMax: max, PowerMin: min * NominalVoltage, PowerMax: max * NominalVoltage, Source: LimitSourceDerived}
+	}
+
+	limits := ElectricalLimits{
+		Phases:          []PhaseLimits{phase(1, 0, 16), phase(2, 0, 16), phase(3, 0, 16)},
+		PowerMax:        3 * 16 * NominalVoltage,
+		Source:          LimitSourceDerived,
+		ConnectedPhases: 3,
+	}
+
+	res := limits.ApplyMinimumCurrent(MinimumCurrentIEC61851, NominalVoltage)
+
+	for _, p := range res.Phases {
+		if p.CurrentMin != MinimumCurrentIEC61851 || p.PowerMin != MinimumCurrentIEC61851*NominalVoltage || p.Source != LimitSourceMinimum {
+			t.Errorf("unexpected phase limits: %+v", p)
+		}
+	}
+	if res.PowerMin != 3*MinimumCurrentIEC61851*NominalVoltage || res.PowerMax != limits.PowerMax || res.Source != LimitSourceMinimum {
+		t.Errorf("unexpected total limits: %+v", res)
+	}
+
+	// the original limits are not modified
+	if limits.Phases[0].CurrentMin != 0 {
+		t.Errorf("unexpected original phase limits: %+v", limits.Phases[0])
+	}
+
+	// implausible reported power limits are replaced by the sum of the phases
+	limits = ElectricalLimits{
+		Phases:          []PhaseLimits{phase(1, 2.2, 16), phase(2, 2.2, 16), phase(3, 2.2, 16)},
+		PowerMin:        0,
+		PowerMax:        100,
+		Source:          LimitSourceReported,
+		ConnectedPhases: 3,
+	}
+
+	res = limits.ApplyMinimumCurrent(MinimumCurrentISO151182, NominalVoltage)
+
+	if res.Phases[0].CurrentMin != 2.2 || res.Phases[0].Source != LimitSourceDerived {
+		t.Errorf("unexpected phase limits: %+v", res.Phases[0])
+	}
+	if res.PowerMin != 3*res.Phases[0].PowerMin || res.PowerMax != 3*16*NominalVoltage || res.Source != LimitSourceDerived {
+		t.Errorf("unexpected total limits: %+v", res)
+	}
+
+	// minimums are not raised above the maximums
+	limits = ElectricalLimits{
+		Phases:          []PhaseLimits{phase(1, 0, 16), phase(2, 0, 4), phase(3, 0, 0)},
+		PowerMax:        20 * NominalVoltage,
+		Source:          LimitSourceDerived,
+		ConnectedPhases: 3,
+	}
+
+	res = limits.ApplyMinimumCurrent(MinimumCurrentIEC61851, NominalVoltage)
+
+	for i, min := range []float64{MinimumCurrentIEC61851, 4, 0} {
+		if p := res.Phases[i]; p.CurrentMin != min || p.CurrentMin > p.CurrentMax || p.PowerMin > p.PowerMax {
+			t.Errorf("unexpected phase limits: %+v", p)
+		}
+	}
+	if res.PowerMin > res.PowerMax {
+		t.Errorf("unexpected total limits: %+v", res)
+	}
+}
@@ -1,6 +1,11 @@
 package feature
 
-import "reflect"
+import (
+	"reflect"
+
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
 
 // populatedFields finds the first non-nil field name for given struct
 func populatedFields(cmd interface{}) string {
@@ -17,3 +22,13 @@ func populatedFields(cmd interface{}) string {
 
 	return res
 }
+
+// remoteEntity returns the entity of the remote feature address, nil if unknown
+func remoteEntity(ctrl spine.Context, rf model.FeatureAddressType) spine.Entity {
+	remoteDevice := ctrl.GetDevice()
+	if remoteDevice == nil {
+		return nil
+	}
+
+	return remoteDevice.Entity(rf.Entity)
+}
@@ -34,10 +34,20 @@ func NewIdentificationClient() spine.Feature {
 	return f
 }
 
-func (f *Identification) EVDisconnectEvent() {
+// EVDisconnect clears the identifications so that they are not used for the next EV
+func (f *Identification) EVDisconnect() {
+	f.ClearData()
 	f.datasetData = nil
 }
 
+// ListData returns the cached identifications
+func (f *Identification) ListData() []model.IdentificationDataType {
+	if data := spine.FeatureData[model.IdentificationListDataType](f, model.FunctionEnumTypeIdentificationListData); data != nil {
+		return data.IdentificationData
+	}
+	return nil
+}
+
 func (f *Identification) requestListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		IdentificationListData: &model.IdentificationListDataType{},
@@ -46,13 +56,17 @@ func (f *Identification) requestListData(ctrl spine.Context, rf spine.Feature) (
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *Identification) replyListData(ctrl spine.Context, data model.IdentificationListDataType) error {
+func (f *Identification) replyListData(ctrl spine.Context, data model.IdentificationListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.1.1"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":10}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":7}]},{"msgCounter":21495},{"cmdClassifier":"notify"}]},{"payload":[{"cmd":[[{"identificationListData":[{"identificationData":[[{"identificationId":0},{"identificationType":"eui48"},{"identificationValue":"F0:7F:0C:07:9B:C7"}]]}]}]]}]}]}}]}
 
+	f.SetData(model.FunctionEnumTypeIdentificationListData, &model.IdentificationListDataType{
+		IdentificationData: spine.UpdateList(f.ListData(), data.IdentificationData, filterPartial, filterDelete),
+	})
+
 	f.datasetData = nil
-	for _, item := range data.IdentificationData {
-		if item.IdentificationId != nil || item.IdentificationType != nil || item.IdentificationValue != nil {
+	for _, item := range f.ListData() {
+		if item.IdentificationId == nil || item.IdentificationType == nil || item.IdentificationValue == nil {
 			continue
 		}
 		newItem := IdentificationDatasetDataType{
@@ -83,15 +97,17 @@ func (f *Identification) HandleRequest(ctrl spine.Context, fct model.FunctionEnu
 }
 
 func (f *Identification) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	filterPartial, filterDelete := cmd.ExtractFilter()
+
 	switch {
 	case cmd.IdentificationListData != nil:
 		data := cmd.IdentificationListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyListData(ctrl, *data)
+			return f.replyListData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyListData(ctrl, *data)
+			return f.replyListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("identification.Handle: IdentificationListData CmdClassifierType not implemented: %s", op)
@@ -0,0 +1,39 @@
+package feature
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+type identificationDelegate struct {
+	data []IdentificationDatasetDataType
+}
+
+func (d *identificationDelegate) UpdateIdentificationData(_ *Identification, data []IdentificationDatasetDataType) {
+	d.data = data
+}
+
+func TestIdentificationIncomplete(t *testing.T) {
+	delegate := new(identificationDelegate)
+	f := NewIdentificationClient().(*Identification)
+	f.Delegate = delegate
+
+	var cmd model.CmdType
+	if err := json.Unmarshal([]byte(`[{"identificationListData":[{"identificationData":[
+		[{"identificationId":0},{"identificationType":"eui48"},{"identificationValue":"F0:7F:0C:07:9B:C7"}],
+		[{"identificationId":1},{"identificationType":"eui64"}]
+	]}]}]`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Handle(nil, model.FeatureAddressType{}, model.CmdClassifierTypeNotify, cmd, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// identifications without value are skipped
+	if len(delegate.data) != 1 || delegate.data[0].IdentificationValue != "F0:7F:0C:07:9B:C7" {
+		t.Errorf("unexpected identifications: %v", delegate.data)
+	}
+}
@@ -53,7 +53,9 @@ func NewIncentiveTableClient() spine.Feature {
 	return f
 }
 
-func (f *IncentiveTable) EVDisconnectEvent() {
+// EVDisconnect clears the incentive tables so that they are not used for the next EV
+func (f *IncentiveTable) EVDisconnect() {
+	f.ClearData()
 	f.constraintsData = nil
 }
 
@@ -61,6 +63,21 @@ func (f *IncentiveTable) GetIncentiveTableConstraintsDataType() *IncentiveConstr
 	return f.constraintsData
 }
 
+// DescriptionData returns the cached incentive table descriptions
+func (f *IncentiveTable) DescriptionData() *model.IncentiveTableDescriptionDataType {
+	return spine.FeatureData[model.IncentiveTableDescriptionDataType](f, model.FunctionEnumTypeIncentiveTableDescriptionData)
+}
+
+// ConstraintsData returns the cached incentive table constraints
+func (f *IncentiveTable) ConstraintsData() *model.IncentiveTableConstraintsDataType {
+	return spine.FeatureData[model.IncentiveTableConstraintsDataType](f, model.FunctionEnumTypeIncentiveTableConstraintsData)
+}
+
+// IncentiveTableData returns the cached incentive tables
+func (f *IncentiveTable) IncentiveTableData() *model.IncentiveTableDataType {
+	return spine.FeatureData[model.IncentiveTableDataType](f, model.FunctionEnumTypeIncentiveTableData)
+}
+
 func (f *IncentiveTable) requestDescriptionData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		IncentiveTableDescriptionData: &model.IncentiveTableDescriptionDataType{},
@@ -89,6 +106,8 @@ func (f *IncentiveTable) replyDescriptionData(ctrl spine.Context, data model.Inc
 	// 	]}
 	// ]]}
 
+	f.SetData(model.FunctionEnumTypeIncentiveTableDescriptionData, &data)
+
 	// f.descriptionData = nil
 	// for _, item := range data.TimeSeriesDescriptionListData {
 	// 	newItem := TimeSeriesDescriptionDataType{
@@ -108,6 +127,10 @@ func (f *IncentiveTable) replyDescriptionData(ctrl spine.Context, data model.Inc
 }
 
 func (f *IncentiveTable) WriteDescriptionData(ctrl spine.Context, rf spine.Feature) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeIncentiveTableDescriptionData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
 	// example data:
 	// {"datagram":[{"header":[{"specificationVersion":"1.1.0"},{"addressSource":[{"device":"EVCC_HEMS"},{"entity":[0]},{"feature":0}]},{"addressDestination":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":8}]},{"msgCounter":3016},{"cmdClassifier":"write"},{"ackRequest":true}]},{"payload":[
 	// {"cmd":[[
@@ -203,6 +226,8 @@ func (f *IncentiveTable) replyConstraintsData(ctrl spine.Context, data model.Inc
 	// 	]}
 	// ]]}
 
+	f.SetData(model.FunctionEnumTypeIncentiveTableConstraintsData, &data)
+
 	for _, constraints := range data.IncentiveTableConstraints {
 		if constraints.Tariff == nil || constraints.Tariff.TariffId == nil || constraints.TariffConstraints == nil || constraints.TariffConstraints.MaxTiersPerTariff == nil || constraints.TariffConstraints.MaxBoundariesPerTier == nil || constraints.TariffConstraints.MaxIncentivesPerTier == nil || constraints.IncentiveSlotConstraints.SlotCountMax == nil {
 			continue
@@ -240,6 +265,8 @@ func (f *IncentiveTable) replyData(ctrl spine.Context, data model.IncentiveTable
 	// 	]}
 	// ]]}
 
+	f.SetData(model.FunctionEnumTypeIncentiveTableData, &data)
+
 	// f.limitData = nil
 	// for _, item := range data.LoadControlLimitData {
 	// 	newItem := LoadControlLimitDatasetType{
@@ -259,6 +286,10 @@ func (f *IncentiveTable) replyData(ctrl spine.Context, data model.IncentiveTable
 }
 
 func (f *IncentiveTable) WriteIncentiveTablePlanData(ctrl spine.Context, rf spine.Feature, chargingPlan IncentiveChargingPlan) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeIncentiveTableData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
 	tariffId := model.TariffIdType(1)
 	var incentiveSlots []model.IncentiveTableIncentiveSlotType
 
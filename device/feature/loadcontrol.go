@@ -2,6 +2,7 @@ package feature
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/evcc-io/eebus/spine"
 	"github.com/evcc-io/eebus/spine/model"
@@ -36,6 +37,7 @@ type LoadControl struct {
 	Delegate             LoadControlDelegate
 	limitDescriptionData []LoadControlLimitDescriptionDataType
 	limitData            []LoadControlLimitDatasetType
+	mux                  sync.Mutex
 }
 
 func NewLoadControlClient() spine.Feature {
@@ -49,17 +51,45 @@ func NewLoadControlClient() spine.Feature {
 	return f
 }
 
-func (f *LoadControl) EVDisconnectEvent() {
+// EVDisconnect clears the limits so that they are not used for the next EV
+func (f *LoadControl) EVDisconnect() {
+	f.ClearData()
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
 	f.limitDescriptionData = nil
 	f.limitData = nil
 }
 
+// LimitDescriptionListData returns the cached limit descriptions
+func (f *LoadControl) LimitDescriptionListData() []model.LoadControlLimitDescriptionDataType {
+	if data := spine.FeatureData[model.LoadControlLimitDescriptionListDataType](f, model.FunctionEnumTypeLoadControlLimitDescriptionListData); data != nil {
+		return data.LoadControlLimitDescriptionData
+	}
+	return nil
+}
+
+// LimitListData returns the cached limits
+func (f *LoadControl) LimitListData() []model.LoadControlLimitDataType {
+	if data := spine.FeatureData[model.LoadControlLimitListDataType](f, model.FunctionEnumTypeLoadControlLimitListData); data != nil {
+		return data.LoadControlLimitData
+	}
+	return nil
+}
+
 func (f *LoadControl) GetLoadControlLimitDescriptionData() []LoadControlLimitDescriptionDataType {
-	return f.limitDescriptionData
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]LoadControlLimitDescriptionDataType(nil), f.limitDescriptionData...)
 }
 
 func (f *LoadControl) GetLoadControlLimitData() []LoadControlLimitDatasetType {
-	return f.limitData
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]LoadControlLimitDatasetType(nil), f.limitData...)
 }
 
 func (f *LoadControl) requestLimitDescriptionListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
@@ -70,7 +100,7 @@ func (f *LoadControl) requestLimitDescriptionListData(ctrl spine.Context, rf spi
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *LoadControl) replyLimitDescriptionListData(ctrl spine.Context, data model.LoadControlLimitDescriptionListDataType) error {
+func (f *LoadControl) replyLimitDescriptionListData(ctrl spine.Context, data model.LoadControlLimitDescriptionListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":1}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":6}]},{"msgCounter":6898},{"msgCounterReference":18},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"loadControlLimitDescriptionListData":[{"loadControlLimitDescriptionData":[[{"limitId":1},{"limitType":"maxValueLimit"},{"limitCategory":"obligation"},{"limitDirection":"consume"},{"measurementId":1},{"unit":"A"},{"scopeType":"overloadProtection"}],[{"limitId":2},{"limitType":"maxValueLimit"},{"limitCategory":"recommendation"},{"limitDirection":"consume"},{"measurementId":1},{"unit":"A"},{"scopeType":"selfConsumption"}]]}]}]]}]}]}}]}
 	// {"cmd":[[
@@ -98,17 +128,28 @@ func (f *LoadControl) replyLimitDescriptionListData(ctrl spine.Context, data mod
 	// 	]}
 	// ]]}
 
-	f.limitDescriptionData = nil
-	for _, item := range data.LoadControlLimitDescriptionData {
+	f.SetData(model.FunctionEnumTypeLoadControlLimitDescriptionListData, &model.LoadControlLimitDescriptionListDataType{
+		LoadControlLimitDescriptionData: spine.UpdateList(f.LimitDescriptionListData(), data.LoadControlLimitDescriptionData, filterPartial, filterDelete),
+	})
+
+	var limitDescriptionData []LoadControlLimitDescriptionDataType
+	for _, item := range f.LimitDescriptionListData() {
+		if item.LimitId == nil || item.LimitType == nil || item.MeasurementId == nil || item.ScopeType == nil {
+			continue
+		}
 		newItem := LoadControlLimitDescriptionDataType{
 			LimitId:       uint(*item.LimitId),
 			LimitType:     model.LoadControlLimitTypeEnumType(*item.LimitType),
 			MeasurementId: uint(*item.MeasurementId),
 			ScopeType:     model.ScopeTypeEnumType(*item.ScopeType),
 		}
-		f.limitDescriptionData = append(f.limitDescriptionData, newItem)
+		limitDescriptionData = append(limitDescriptionData, newItem)
 	}
 
+	f.mux.Lock()
+	f.limitDescriptionData = limitDescriptionData
+	f.mux.Unlock()
+
 	if f.Delegate != nil {
 		f.Delegate.UpdateLoadControlLimitData(f)
 	}
@@ -117,14 +158,29 @@ func (f *LoadControl) replyLimitDescriptionListData(ctrl spine.Context, data mod
 }
 
 func (f *LoadControl) requestLimitListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
+	return f.RequestLimitListData(ctrl, rf, nil, nil)
+}
+
+// RequestLimitListData reads the limits, optionally restricted by selectors and elements
+func (f *LoadControl) RequestLimitListData(ctrl spine.Context, rf spine.Feature, selectors *model.LoadControlLimitListDataSelectorsType, elements *model.LoadControlLimitDataElementsType) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		LoadControlLimitListData: &model.LoadControlLimitListDataType{},
 	}}
 
+	if selectors != nil || elements != nil {
+		filter := model.NewFilterTypePartial()
+		filter.LoadControlLimitListDataSelectors = selectors
+		filter.LoadControlLimitDataElements = elements
+
+		function := model.FunctionType(model.FunctionEnumTypeLoadControlLimitListData)
+		res[0].Function = &function
+		res[0].Filter = []model.FilterType{*filter}
+	}
+
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *LoadControl) replyLimitListData(ctrl spine.Context, data model.LoadControlLimitListDataType) error {
+func (f *LoadControl) replyLimitListData(ctrl spine.Context, data model.LoadControlLimitListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":1}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":6}]},{"msgCounter":6928},{"msgCounterReference":34},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"loadControlLimitListData":[{"loadControlLimitData":[[{"limitId":1},{"isLimitChangeable":true},{"isLimitActive":false},{"value":[{"number":0},{"scale":0}]}],[{"limitId":2},{"isLimitChangeable":true},{"isLimitActive":false},{"value":[{"number":0},{"scale":0}]}]]}]}]]}]}]}}]}
 	// {"cmd":[[
@@ -146,8 +202,12 @@ func (f *LoadControl) replyLimitListData(ctrl spine.Context, data model.LoadCont
 	// 	]}
 	// ]]}
 
-	f.limitData = nil
-	for _, item := range data.LoadControlLimitData {
+	f.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{
+		LoadControlLimitData: spine.UpdateList(f.LimitListData(), data.LoadControlLimitData, filterPartial, filterDelete),
+	})
+
+	var limitData []LoadControlLimitDatasetType
+	for _, item := range f.LimitListData() {
 		if item.Value == nil || item.LimitId == nil || item.IsLimitActive == nil {
 			continue
 		}
@@ -159,9 +219,13 @@ func (f *LoadControl) replyLimitListData(ctrl spine.Context, data model.LoadCont
 		if item.IsLimitChangeable != nil {
 			newItem.IsLimitChangeable = *item.IsLimitChangeable
 		}
-		f.limitData = append(f.limitData, newItem)
+		limitData = append(limitData, newItem)
 	}
 
+	f.mux.Lock()
+	f.limitData = limitData
+	f.mux.Unlock()
+
 	if f.Delegate != nil {
 		f.Delegate.UpdateLoadControlLimitData(f)
 	}
@@ -170,6 +234,10 @@ func (f *LoadControl) replyLimitListData(ctrl spine.Context, data model.LoadCont
 }
 
 func (f *LoadControl) WriteLoadControlLimitListData(ctrl spine.Context, rf spine.Feature, limits []LoadControlLimitDatasetType) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeLoadControlLimitListData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
 	var data []model.LoadControlLimitDataType
 
 	for _, item := range limits {
@@ -188,7 +256,28 @@ func (f *LoadControl) WriteLoadControlLimitListData(ctrl spine.Context, rf spine
 		data = append(data, newItem)
 	}
 
+	function := model.FunctionType(model.FunctionEnumTypeLoadControlLimitListData)
 	res := []model.CmdType{{
+		Function: &function,
+		Filter:   []model.FilterType{*model.NewFilterTypePartial()},
+		LoadControlLimitListData: &model.LoadControlLimitListDataType{
+			LoadControlLimitData: data,
+		},
+	}}
+
+	return ctrl.Write(spine.FeatureAddressType(f), spine.FeatureAddressType(rf), res)
+}
+
+// WriteLimitListData writes the limits as given, e.g. for activating or deactivating limits without changing their values
+func (f *LoadControl) WriteLimitListData(ctrl spine.Context, rf spine.Feature, data []model.LoadControlLimitDataType) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeLoadControlLimitListData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
+	function := model.FunctionType(model.FunctionEnumTypeLoadControlLimitListData)
+	res := []model.CmdType{{
+		Function: &function,
+		Filter:   []model.FilterType{*model.NewFilterTypePartial()},
 		LoadControlLimitListData: &model.LoadControlLimitListDataType{
 			LoadControlLimitData: data,
 		},
@@ -216,14 +305,16 @@ func (f *LoadControl) HandleRequest(ctrl spine.Context, fct model.FunctionEnumTy
 }
 
 func (f *LoadControl) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	filterPartial, filterDelete := cmd.ExtractFilter()
+
 	switch {
 	case cmd.LoadControlLimitDescriptionListData != nil:
 		data := cmd.LoadControlLimitDescriptionListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyLimitDescriptionListData(ctrl, *data)
+			return f.replyLimitDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyLimitDescriptionListData(ctrl, *data)
+			return f.replyLimitDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 		default:
 			return fmt.Errorf("loadcontrol.handle: LoadControlLimitDescriptionListData CmdClassifierType not implemented: %s", op)
 		}
@@ -232,9 +323,9 @@ func (f *LoadControl) Handle(ctrl spine.Context, rf model.FeatureAddressType, op
 		data := cmd.LoadControlLimitListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyLimitListData(ctrl, *data)
+			return f.replyLimitListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyLimitListData(ctrl, *data)
+			return f.replyLimitListData(ctrl, *data, filterPartial, filterDelete)
 		default:
 			return fmt.Errorf("loadcontrol.handle: LoadControlLimitListData CmdClassifierType not implemented: %s", op)
 		}
@@ -2,6 +2,7 @@ package feature
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/evcc-io/eebus/spine"
@@ -42,6 +43,7 @@ type Measurement struct {
 	datasetDefinitions     []MeasurementDatasetDefinitionsType
 	constraintsDefinitions []MeasurementConstraintsDefinitionsType
 	datasetData            []MeasurementDatasetDataType
+	mux                    sync.Mutex
 }
 
 func NewMeasurementClient() spine.Feature {
@@ -55,18 +57,54 @@ func NewMeasurementClient() spine.Feature {
 	return f
 }
 
-func (f *Measurement) EVDisconnectEvent() {
+// EVDisconnect clears the measurements so that they are not used for the next EV
+func (f *Measurement) EVDisconnect() {
+	f.ClearData()
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
 	f.datasetDefinitions = nil
 	f.constraintsDefinitions = nil
 	f.datasetData = nil
 }
 
+// DescriptionListData returns the cached measurement descriptions
+func (f *Measurement) DescriptionListData() []model.MeasurementDescriptionDataType {
+	if data := spine.FeatureData[model.MeasurementDescriptionListDataType](f, model.FunctionEnumTypeMeasurementDescriptionListData); data != nil {
+		return data.MeasurementDescriptionData
+	}
+	return nil
+}
+
+// ConstraintsListData returns the cached measurement constraints
+func (f *Measurement) ConstraintsListData() []model.MeasurementConstraintsDataType {
+	if data := spine.FeatureData[model.MeasurementConstraintsListDataType](f, model.FunctionEnumTypeMeasurementConstraintsListData); data != nil {
+		return data.MeasurementConstraintsData
+	}
+	return nil
+}
+
+// ListData returns the cached measurement values
+func (f *Measurement) ListData() []model.MeasurementDataType {
+	if data := spine.FeatureData[model.MeasurementListDataType](f, model.FunctionEnumTypeMeasurementListData); data != nil {
+		return data.MeasurementData
+	}
+	return nil
+}
+
 func (f *Measurement) GetMeasurementDescription() []MeasurementDatasetDefinitionsType {
-	return f.datasetDefinitions
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]MeasurementDatasetDefinitionsType(nil), f.datasetDefinitions...)
 }
 
 func (f *Measurement) GetMeasurementData() []MeasurementDatasetDataType {
-	return f.datasetData
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]MeasurementDatasetDataType(nil), f.datasetData...)
 }
 
 func (f *Measurement) requestDescriptionListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
@@ -77,20 +115,32 @@ func (f *Measurement) requestDescriptionListData(ctrl spine.Context, rf spine.Fe
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *Measurement) replyDescriptionListData(ctrl spine.Context, data model.MeasurementDescriptionListDataType) error {
+func (f *Measurement) replyDescriptionListData(ctrl spine.Context, data model.MeasurementDescriptionListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":3}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":3}]},{"msgCounter":6977},{"msgCounterReference":15},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"measurementDescriptionListData":[{"measurementDescriptionData":[[{"measurementId":1},{"measurementType":"current"},{"commodityType":"electricity"},{"unit":"A"},{"scopeType":"acCurrent"}],[{"measurementId":4},{"measurementType":"power"},{"commodityType":"electricity"},{"unit":"W"},{"scopeType":"acPower"}],[{"measurementId":7},{"measurementType":"energy"},{"commodityType":"electricity"},{"unit":"Wh"},{"scopeType":"charge"}]]}]}]]}]}]}}]}
 
-	f.datasetDefinitions = nil
-	for _, item := range data.MeasurementDescriptionData {
+	f.SetData(model.FunctionEnumTypeMeasurementDescriptionListData, &model.MeasurementDescriptionListDataType{
+		MeasurementDescriptionData: spine.UpdateList(f.DescriptionListData(), data.MeasurementDescriptionData, filterPartial, filterDelete),
+	})
+
+	var datasetDefinitions []MeasurementDatasetDefinitionsType
+	for _, item := range f.DescriptionListData() {
+		if item.MeasurementId == nil || item.MeasurementType == nil || item.ScopeType == nil {
+			continue
+		}
+
 		newItem := MeasurementDatasetDefinitionsType{
 			MeasurementId:   uint(*item.MeasurementId),
 			MeasurementType: model.MeasurementTypeEnumType(*item.MeasurementType),
 			ScopeType:       model.ScopeTypeEnumType(*item.ScopeType),
 		}
-		f.datasetDefinitions = append(f.datasetDefinitions, newItem)
+		datasetDefinitions = append(datasetDefinitions, newItem)
 	}
 
+	f.mux.Lock()
+	f.datasetDefinitions = datasetDefinitions
+	f.mux.Unlock()
+
 	return nil
 }
 
@@ -102,41 +152,73 @@ func (f *Measurement) requestConstraintsListData(ctrl spine.Context, rf spine.Fe
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *Measurement) replyConstraintsListData(ctrl spine.Context, data model.MeasurementConstraintsListDataType) error {
-	// example data:
+func (f *Measurement) replyConstraintsListData(ctrl spine.Context, data model.MeasurementConstraintsListDataType, filterPartial, filterDelete *model.FilterType) error {
+	f.SetData(model.FunctionEnumTypeMeasurementConstraintsListData, &model.MeasurementConstraintsListDataType{
+		MeasurementConstraintsData: spine.UpdateList(f.ConstraintsListData(), data.MeasurementConstraintsData, filterPartial, filterDelete),
+	})
+
+	var constraintsDefinitions []MeasurementConstraintsDefinitionsType
+	for _, item := range f.ConstraintsListData() {
+		if item.MeasurementId == nil {
+			continue
+		}
 
-	f.datasetDefinitions = nil
-	for _, item := range data.MeasurementConstraintsData {
 		newItem := MeasurementConstraintsDefinitionsType{
 			MeasurementId: uint(*item.MeasurementId),
-			MinValue:      item.ValueRangeMin.GetValue(),
-			MaxValue:      item.ValueRangeMax.GetValue(),
-			StepSize:      item.ValueStepSize.GetValue(),
 		}
-		f.constraintsDefinitions = append(f.constraintsDefinitions, newItem)
+		if item.ValueRangeMin != nil {
+			newItem.MinValue = item.ValueRangeMin.GetValue()
+		}
+		if item.ValueRangeMax != nil {
+			newItem.MaxValue = item.ValueRangeMax.GetValue()
+		}
+		if item.ValueStepSize != nil {
+			newItem.StepSize = item.ValueStepSize.GetValue()
+		}
+		constraintsDefinitions = append(constraintsDefinitions, newItem)
 	}
 
+	f.mux.Lock()
+	f.constraintsDefinitions = constraintsDefinitions
+	f.mux.Unlock()
+
 	return nil
 }
 
 func (f *Measurement) requestListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
+	return f.RequestListData(ctrl, rf, nil, nil)
+}
+
+// RequestListData reads the measurement values, optionally restricted by selectors and elements
+func (f *Measurement) RequestListData(ctrl spine.Context, rf spine.Feature, selectors *model.MeasurementListDataSelectorsType, elements *model.MeasurementDataElementsType) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		MeasurementListData: &model.MeasurementListDataType{},
 	}}
 
+	if selectors != nil || elements != nil {
+		filter := model.NewFilterTypePartial()
+		filter.MeasurementListDataSelectors = selectors
+		filter.MeasurementDataElements = elements
+
+		function := model.FunctionType(model.FunctionEnumTypeMeasurementListData)
+		res[0].Function = &function
+		res[0].Filter = []model.FilterType{*filter}
+	}
+
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *Measurement) replyListData(ctrl spine.Context, data model.MeasurementListDataType, isPartialForCmd bool) error {
+func (f *Measurement) replyListData(ctrl spine.Context, data model.MeasurementListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":3}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":3}]},{"msgCounter":15971},{"msgCounterReference":33},{"cmdClassifier":"reply"}]},{"payload":[{"cmd":[[{"measurementListData":[{"measurementData":[[{"measurementId":1},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}],[{"measurementId":4},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}],[{"measurementId":2},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}],[{"measurementId":5},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}],[{"measurementId":3},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}],[{"measurementId":6},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}],[{"measurementId":7},{"valueType":"value"},{"timestamp":"2021-04-23T12:39:19.037Z"},{"value":[{"number":0},{"scale":0}]},{"valueSource":"measuredValue"}]]}]}]]}]}]}}]}
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.3.0"},{"addressSource":[{"device":"d:_i:47859_Elli-Wallbox-2019A0OV8H"},{"entity":[1,1]},{"feature":11}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":3}]},{"msgCounter":811},{"cmdClassifier":"notify"}]},{"payload":[{"cmd":[[{"function":"measurementListData"},{"filter":[[{"cmdControl":[{"partial":[]}]}]]},{"measurementListData":[{"measurementData":[[{"measurementId":0},{"valueType":"value"},{"value":[{"number":608},{"scale":-2}]}],[{"measurementId":1},{"valueType":"value"},{"value":[{"number":587},{"scale":-2}]}],[{"measurementId":2},{"valueType":"value"},{"value":[{"number":604},{"scale":-2}]}]]}]}]]}]}]}}]}
 
-	if !isPartialForCmd {
-		f.datasetData = nil
-	}
+	f.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{
+		MeasurementData: spine.UpdateList(f.ListData(), data.MeasurementData, filterPartial, filterDelete),
+	})
 
-	for _, item := range data.MeasurementData {
+	var datasetData []MeasurementDatasetDataType
+	for _, item := range f.ListData() {
 		if item.MeasurementId == nil || item.Value == nil {
 			continue
 		}
@@ -154,22 +236,13 @@ func (f *Measurement) replyListData(ctrl spine.Context, data model.MeasurementLi
 			Timestamp:     timestamp,
 			Value:         item.Value.GetValue(),
 		}
-
-		replaceIndex := -1
-		for index, datasetItem := range f.datasetData {
-			if datasetItem.MeasurementId == uint(*item.MeasurementId) {
-				replaceIndex = index
-				break
-			}
-		}
-
-		if replaceIndex != -1 {
-			f.datasetData[replaceIndex] = newItem
-		} else {
-			f.datasetData = append(f.datasetData, newItem)
-		}
+		datasetData = append(datasetData, newItem)
 	}
 
+	f.mux.Lock()
+	f.datasetData = datasetData
+	f.mux.Unlock()
+
 	if f.Delegate != nil {
 		f.Delegate.UpdateMeasurementData(f)
 	}
@@ -202,15 +275,17 @@ func (f *Measurement) HandleRequest(ctrl spine.Context, fct model.FunctionEnumTy
 }
 
 func (f *Measurement) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	filterPartial, filterDelete := cmd.ExtractFilter()
+
 	switch {
 	case cmd.MeasurementDescriptionListData != nil:
 		data := cmd.MeasurementDescriptionListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyDescriptionListData(ctrl, *data)
+			return f.replyDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyDescriptionListData(ctrl, *data)
+			return f.replyDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("measurement.handle: MeasurementDescriptionListData CmdClassifierType not implemented: %s", op)
@@ -220,10 +295,10 @@ func (f *Measurement) Handle(ctrl spine.Context, rf model.FeatureAddressType, op
 		data := cmd.MeasurementConstraintsListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyConstraintsListData(ctrl, *data)
+			return f.replyConstraintsListData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyConstraintsListData(ctrl, *data)
+			return f.replyConstraintsListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("measurement.handle: MeasurementConstraintsListData CmdClassifierType not implemented: %s", op)
@@ -233,10 +308,10 @@ func (f *Measurement) Handle(ctrl spine.Context, rf model.FeatureAddressType, op
 		data := cmd.MeasurementListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyListData(ctrl, *data, isPartialForCmd)
+			return f.replyListData(ctrl, *data, filterPartial, filterDelete)
 
 		case model.CmdClassifierTypeNotify:
-			return f.replyListData(ctrl, *data, isPartialForCmd)
+			return f.replyListData(ctrl, *data, filterPartial, filterDelete)
 
 		default:
 			return fmt.Errorf("measurement.handle: MeasurementListData CmdClassifierType not implemented: %s", op)
@@ -8,7 +8,7 @@ import (
 )
 
 type NodeManagementDelegate interface {
-	UpdateUseCaseSupportData(*NodeManagement, model.UseCaseNameType, bool)
+	UpdateUseCaseSupportData(*NodeManagement, spine.UseCaseEvent)
 }
 
 type NodeManagement struct {
@@ -25,6 +25,7 @@ func NewNodeManagement() spine.Feature {
 	}
 
 	f.Add(model.FunctionEnumTypeNodeManagementDetailedDiscoveryData, true, false)
+	f.Add(model.FunctionEnumTypeNodeManagementDestinationListData, true, false)
 	f.Add(model.FunctionEnumTypeNodeManagementSubscriptionRequestCall, false, false)
 	f.Add(model.FunctionEnumTypeNodeManagementBindingRequestCall, false, false)
 	f.Add(model.FunctionEnumTypeNodeManagementSubscriptionDeleteCall, false, false)
@@ -65,18 +65,28 @@ func (f *NodeManagement) addEntityAndFeatures(ctrl spine.Context, remoteDevice s
 
 		entity := remoteDevice.Entity(entityAddress)
 		if entity == nil {
-			entity = spine.UnmarshalEntity(remoteDevice.GetAddress(), ei)
+			newEntity := spine.UnmarshalEntity(remoteDevice.GetAddress(), ei)
+			if newEntity == nil {
+				return errors.New("nodemanagement.replyDetailedDiscoveryData: invalid EntityInformation.Description.EntityAddress.Device")
+			}
+
+			// add entity before its features to link it with parent and sub-entities
+			remoteDevice.Add(newEntity)
+			entity = newEntity
 		}
 
 		for _, fi := range data.FeatureInformation {
-			if reflect.DeepEqual(fi.Description.FeatureAddress.Entity, entityAddress) {
-				if f := spine.UnmarshalFeature(fi); f != nil {
-					entity.Add(f)
-				}
+			if fi.Description == nil || fi.Description.FeatureAddress == nil || !reflect.DeepEqual(fi.Description.FeatureAddress.Entity, entityAddress) {
+				continue
 			}
-		}
 
-		remoteDevice.Add(entity)
+			rf := spine.UnmarshalFeature(fi)
+			if rf == nil || entity.Feature(rf.GetID()) != nil {
+				continue
+			}
+
+			entity.Add(rf)
+		}
 
 		if err := f.announceFeatureDiscovery(ctrl, entity); err != nil {
 			return err
@@ -262,18 +272,12 @@ func (f *NodeManagement) notifyDetailedDiscoveryData(ctrl spine.Context, data mo
 }
 
 func (f *NodeManagement) announceFeatureDiscovery(ctrl spine.Context, e spine.Entity) error {
+	// without local device there are no client features to connect
 	entity := f.GetEntity()
-	if entity == nil {
-		return errors.New("announceFeatureDiscovery: entity not found")
-	}
-	device := entity.GetDevice()
-	if device == nil {
-		return errors.New("announceFeatureDiscovery: device not found")
-	}
-	entities := device.GetEntities()
-	if entities == nil {
-		return errors.New("announceFeatureDiscovery: entities not found")
+	if entity == nil || entity.GetDevice() == nil {
+		return nil
 	}
+	entities := entity.GetDevice().GetEntities()
 
 	for _, le := range entities {
 		for _, lf := range le.GetFeatures() {
@@ -20,11 +20,11 @@ func (f *NodeManagement) replySubscriptionData(ctrl spine.Context) error {
 
 func (f *NodeManagement) handleSubscriptionData(ctrl spine.Context, op model.CmdClassifierType, data *model.NodeManagementSubscriptionDataType, isPartialForCmd bool) error {
 	switch op {
-	case model.CmdClassifierTypeCall:
+	case model.CmdClassifierTypeRead:
 		return f.replySubscriptionData(ctrl)
 
 	default:
-		return fmt.Errorf("nodemanagement.handleSubscriptionDeleteCall: NodeManagementSubscriptionRequestCall CmdClassifierType not implemented: %s", op)
+		return fmt.Errorf("nodemanagement.handleSubscriptionData: NodeManagementSubscriptionData CmdClassifierType not implemented: %s", op)
 	}
 }
 
@@ -20,9 +20,9 @@ func (c *mockContext) AddressSource() *model.FeatureAddressType {
 	return &address
 }
 
-func (c *mockContext) HeartbeatCounter() *uint64 {
+func (c *mockContext) HeartbeatData() model.DeviceDiagnosisHeartbeatDataType {
 	var i uint64 = 0
-	return &i
+	return model.DeviceDiagnosisHeartbeatDataType{HeartbeatCounter: &i}
 }
 
 func (c *mockContext) LocalDeviceFeature(featureType model.FeatureTypeEnumType, role model.RoleType) (*spine.Feature, error) {
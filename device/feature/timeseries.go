@@ -63,6 +63,37 @@ func NewTimeSeriesClient() spine.Feature {
 	return f
 }
 
+// EVDisconnect clears the time series so that they are not used for the next EV
+func (f *TimeSeries) EVDisconnect() {
+	f.ClearData()
+	f.timeSeriesDescriptionData = nil
+	f.timeSeriesData = nil
+}
+
+// ConstraintsListData returns the cached time series constraints
+func (f *TimeSeries) ConstraintsListData() []model.TimeSeriesConstraintsDataType {
+	if data := spine.FeatureData[model.TimeSeriesConstraintsListDataType](f, model.FunctionEnumTypeTimeSeriesConstraintsListData); data != nil {
+		return data.TimeSeriesConstraintsData
+	}
+	return nil
+}
+
+// DescriptionListData returns the cached time series descriptions
+func (f *TimeSeries) DescriptionListData() []model.TimeSeriesDescriptionDataType {
+	if data := spine.FeatureData[model.TimeSeriesDescriptionListDataType](f, model.FunctionEnumTypeTimeSeriesDescriptionListData); data != nil {
+		return data.TimeSeriesDescriptionData
+	}
+	return nil
+}
+
+// ListData returns the cached time series
+func (f *TimeSeries) ListData() []model.TimeSeriesDataType {
+	if data := spine.FeatureData[model.TimeSeriesListDataType](f, model.FunctionEnumTypeTimeSeriesListData); data != nil {
+		return data.TimeSeriesData
+	}
+	return nil
+}
+
 func (f *TimeSeries) GetTimeSeriesDescriptionData() []TimeSeriesDescriptionListDatasetType {
 	return f.timeSeriesDescriptionData
 }
@@ -91,7 +122,7 @@ func (f *TimeSeries) getTimeSeriesIdForType(typeEnum model.TimeSeriesTypeEnumTyp
 	return 0
 }
 
-func (f *TimeSeries) replyConstraintsData(ctrl spine.Context, data model.TimeSeriesConstraintsDataType, isPartialCmd bool) error {
+func (f *TimeSeries) replyConstraintsData(ctrl spine.Context, data model.TimeSeriesConstraintsDataType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.1.1"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":7}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":9}]},{"msgCounter":1226},{"cmdClassifier":"notify"}]},{"payload":[
 	// {"cmd":[[
@@ -102,7 +133,31 @@ func (f *TimeSeries) replyConstraintsData(ctrl spine.Context, data model.TimeSer
 	//   ]}
 	// ]]}]}]}}]}
 
-	f.timeSeriesMaxCount = uint(*data.SlotCountMax)
+	if data.SlotCountMax != nil {
+		f.timeSeriesMaxCount = uint(*data.SlotCountMax)
+	}
+
+	return nil
+}
+
+func (f *TimeSeries) requestConstraintsListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
+	res := []model.CmdType{{
+		TimeSeriesConstraintsListData: &model.TimeSeriesConstraintsListDataType{},
+	}}
+
+	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
+}
+
+func (f *TimeSeries) replyConstraintsListData(ctrl spine.Context, data model.TimeSeriesConstraintsListDataType, filterPartial, filterDelete *model.FilterType) error {
+	f.SetData(model.FunctionEnumTypeTimeSeriesConstraintsListData, &model.TimeSeriesConstraintsListDataType{
+		TimeSeriesConstraintsData: spine.UpdateList(f.ConstraintsListData(), data.TimeSeriesConstraintsData, filterPartial, filterDelete),
+	})
+
+	for _, item := range f.ConstraintsListData() {
+		if err := f.replyConstraintsData(ctrl, item); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -115,7 +170,7 @@ func (f *TimeSeries) requestDescriptionListData(ctrl spine.Context, rf spine.Fea
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *TimeSeries) replyDescriptionListData(ctrl spine.Context, data model.TimeSeriesDescriptionListDataType, isPartialForCmd bool) error {
+func (f *TimeSeries) replyDescriptionListData(ctrl spine.Context, data model.TimeSeriesDescriptionListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":7}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":9}]},{"msgCounter":1593590},{"msgCounterReference":25},{"cmdClassifier":"reply"}]},{"payload":[
 	// {"cmd":[[
@@ -139,10 +194,12 @@ func (f *TimeSeries) replyDescriptionListData(ctrl spine.Context, data model.Tim
 	// 	]}
 	// ]]}]}]}}]}
 
-	if !isPartialForCmd {
-		f.timeSeriesDescriptionData = nil
-	}
-	for _, item := range data.TimeSeriesDescriptionData {
+	f.SetData(model.FunctionEnumTypeTimeSeriesDescriptionListData, &model.TimeSeriesDescriptionListDataType{
+		TimeSeriesDescriptionData: spine.UpdateList(f.DescriptionListData(), data.TimeSeriesDescriptionData, filterPartial, filterDelete),
+	})
+
+	f.timeSeriesDescriptionData = nil
+	for _, item := range f.DescriptionListData() {
 		if item.TimeSeriesId == nil || item.TimeSeriesType == nil || item.Unit == nil {
 			continue
 		}
@@ -165,21 +222,6 @@ func (f *TimeSeries) replyDescriptionListData(ctrl spine.Context, data model.Tim
 		}
 
 		f.timeSeriesDescriptionData = append(f.timeSeriesDescriptionData, newItem)
-		if !isPartialForCmd {
-			f.timeSeriesDescriptionData = append(f.timeSeriesDescriptionData, newItem)
-		} else {
-			replaceIndex := -1
-			for index, element := range f.timeSeriesDescriptionData {
-				if element.TimeSeriesId == newItem.TimeSeriesId {
-					replaceIndex = index
-				}
-			}
-			if replaceIndex != -1 {
-				f.timeSeriesDescriptionData[replaceIndex] = newItem
-			} else {
-				f.timeSeriesDescriptionData = append(f.timeSeriesDescriptionData, newItem)
-			}
-		}
 	}
 
 	if f.Delegate != nil {
@@ -190,6 +232,10 @@ func (f *TimeSeries) replyDescriptionListData(ctrl spine.Context, data model.Tim
 }
 
 func (f *TimeSeries) WriteTimeSeriesDescriptionData(ctrl spine.Context, rf spine.Feature) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeTimeSeriesDescriptionListData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
 	id1 := model.TimeSeriesIdType(1)
 	type1 := model.TimeSeriesTypeType(model.TimeSeriesTypeEnumTypeConstraints)
 	writable1 := false
@@ -234,14 +280,29 @@ func (f *TimeSeries) WriteTimeSeriesDescriptionData(ctrl spine.Context, rf spine
 }
 
 func (f *TimeSeries) requestListData(ctrl spine.Context, rf spine.Feature) (*model.MsgCounterType, error) {
+	return f.RequestListData(ctrl, rf, nil, nil)
+}
+
+// RequestListData reads the time series, optionally restricted by selectors and elements
+func (f *TimeSeries) RequestListData(ctrl spine.Context, rf spine.Feature, selectors *model.TimeSeriesListDataSelectorsType, elements *model.TimeSeriesDataElementsType) (*model.MsgCounterType, error) {
 	res := []model.CmdType{{
 		TimeSeriesListData: &model.TimeSeriesListDataType{},
 	}}
 
+	if selectors != nil || elements != nil {
+		filter := model.NewFilterTypePartial()
+		filter.TimeSeriesListDataSelectors = selectors
+		filter.TimeSeriesDataElements = elements
+
+		function := model.FunctionType(model.FunctionEnumTypeTimeSeriesListData)
+		res[0].Function = &function
+		res[0].Filter = []model.FilterType{*filter}
+	}
+
 	return ctrl.Request(model.CmdClassifierTypeRead, *spine.FeatureAddressType(f), *spine.FeatureAddressType(rf), true, res)
 }
 
-func (f *TimeSeries) replyListData(ctrl spine.Context, data model.TimeSeriesListDataType, isPartialForCmd bool) error {
+func (f *TimeSeries) replyListData(ctrl spine.Context, data model.TimeSeriesListDataType, filterPartial, filterDelete *model.FilterType) error {
 	// example data:
 	// {"data":[{"header":[{"protocolId":"ee1.0"}]},{"payload":{"datagram":[{"header":[{"specificationVersion":"1.2.0"},{"addressSource":[{"device":"d:_i:19667_PorscheEVSE-00016544"},{"entity":[1,1]},{"feature":7}]},{"addressDestination":[{"device":"EVCC_HEMS"},{"entity":[1]},{"feature":9}]},{"msgCounter":1593609},{"msgCounterReference":43},{"cmdClassifier":"reply"}]},{"payload":[
 	// {"cmd":[[
@@ -363,10 +424,16 @@ func (f *TimeSeries) replyListData(ctrl spine.Context, data model.TimeSeriesList
 	// ]]}
 	// ]}]}}]}
 
-	if !isPartialForCmd {
-		f.timeSeriesData = nil
-	}
-	for _, item := range data.TimeSeriesData {
+	f.SetData(model.FunctionEnumTypeTimeSeriesListData, &model.TimeSeriesListDataType{
+		TimeSeriesData: spine.UpdateList(f.ListData(), data.TimeSeriesData, filterPartial, filterDelete),
+	})
+
+	f.timeSeriesData = nil
+	for _, item := range f.ListData() {
+		if item.TimeSeriesId == nil {
+			continue
+		}
+
 		newItem := TimeSeriesDatasetType{
 			TimeSeriesId: uint(*item.TimeSeriesId),
 		}
@@ -377,23 +444,23 @@ func (f *TimeSeries) replyListData(ctrl spine.Context, data model.TimeSeriesList
 			newItem.TimeSeriesSlots = item.TimeSeriesSlot
 		}
 
-		if !isPartialForCmd {
-			f.timeSeriesData = append(f.timeSeriesData, newItem)
-		} else {
-			replaceIndex := -1
-			for index, element := range f.timeSeriesData {
-				if element.TimeSeriesId == newItem.TimeSeriesId {
-					replaceIndex = index
-				}
-			}
-			if replaceIndex != -1 {
-				f.timeSeriesData[replaceIndex] = newItem
-			} else {
-				f.timeSeriesData = append(f.timeSeriesData, newItem)
-			}
+		f.timeSeriesData = append(f.timeSeriesData, newItem)
+	}
+
+	if f.Delegate == nil {
+		return nil
+	}
+
+	// only report the updated time series
+	for _, item := range data.TimeSeriesData {
+		if item.TimeSeriesId == nil {
+			continue
 		}
-		if f.Delegate != nil {
-			f.Delegate.UpdateTimeSeriesData(f, newItem)
+
+		for _, dataset := range f.timeSeriesData {
+			if dataset.TimeSeriesId == uint(*item.TimeSeriesId) {
+				f.Delegate.UpdateTimeSeriesData(f, dataset)
+			}
 		}
 	}
 
@@ -403,6 +470,10 @@ func (f *TimeSeries) replyListData(ctrl spine.Context, data model.TimeSeriesList
 // sends a charging plan to the EVSE
 // duration is the duration of the charging plan in seconds
 func (f *TimeSeries) WriteTimeSeriesPlanData(ctrl spine.Context, rf spine.Feature, chargingPlan TimeSeriesChargingPlan) error {
+	if err := spine.CheckFunctionOperation(rf, model.FunctionEnumTypeTimeSeriesListData, model.CmdClassifierTypeWrite); err != nil {
+		return err
+	}
+
 	seriesId := model.TimeSeriesIdType(f.getTimeSeriesIdForType(model.TimeSeriesTypeEnumTypeConstraints))
 	startTime := model.NewISO8601Duration(time.Duration(0) * time.Second)
 	endTime := model.NewISO8601Duration(chargingPlan.Duration)
@@ -426,7 +497,10 @@ func (f *TimeSeries) WriteTimeSeriesPlanData(ctrl spine.Context, rf spine.Featur
 	}
 	timeSeriesData.TimeSeriesSlot = timeSeriesSlots
 
+	function := model.FunctionType(model.FunctionEnumTypeTimeSeriesListData)
 	res := []model.CmdType{{
+		Function: &function,
+		Filter:   []model.FilterType{*model.NewFilterTypePartial()},
 		TimeSeriesListData: &model.TimeSeriesListDataType{
 			TimeSeriesData: []model.TimeSeriesDataType{timeSeriesData},
 		},
@@ -437,6 +511,12 @@ func (f *TimeSeries) WriteTimeSeriesPlanData(ctrl spine.Context, rf spine.Featur
 
 func (f *TimeSeries) HandleRequest(ctrl spine.Context, fct model.FunctionEnumType, op model.CmdClassifierType, rf spine.Feature) (*model.MsgCounterType, error) {
 	switch fct {
+	case model.FunctionEnumTypeTimeSeriesConstraintsListData:
+		if op == model.CmdClassifierTypeRead {
+			return f.requestConstraintsListData(ctrl, rf)
+		}
+		return nil, fmt.Errorf("timeseries.handleRequest: FunctionEnumTypeTimeSeriesConstraintsListData op not implemented: %s", op)
+
 	case model.FunctionEnumTypeTimeSeriesDescriptionListData:
 		if op == model.CmdClassifierTypeRead {
 			return f.requestDescriptionListData(ctrl, rf)
@@ -454,24 +534,38 @@ func (f *TimeSeries) HandleRequest(ctrl spine.Context, fct model.FunctionEnumTyp
 }
 
 func (f *TimeSeries) Handle(ctrl spine.Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error {
+	filterPartial, filterDelete := cmd.ExtractFilter()
+
 	switch {
 	case cmd.TimeSeriesConstraintsData != nil:
 		data := cmd.TimeSeriesConstraintsData
 		switch op {
+		case model.CmdClassifierTypeReply, model.CmdClassifierTypeNotify:
+			// cache as partial update of the constraints list
+			list := model.TimeSeriesConstraintsListDataType{
+				TimeSeriesConstraintsData: []model.TimeSeriesConstraintsDataType{*data},
+			}
+			return f.replyConstraintsListData(ctrl, list, model.NewFilterTypePartial(), nil)
+		default:
+			return fmt.Errorf("timeseries.handle: TimeSeriesConstraintsData CmdClassifierType not implemented: %s", op)
+		}
+	case cmd.TimeSeriesConstraintsListData != nil:
+		data := cmd.TimeSeriesConstraintsListData
+		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyConstraintsData(ctrl, *data, isPartialForCmd)
+			return f.replyConstraintsListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyConstraintsData(ctrl, *data, isPartialForCmd)
+			return f.replyConstraintsListData(ctrl, *data, filterPartial, filterDelete)
 		default:
-			return fmt.Errorf("timeseries.handle: TimeSeriesConstraintsData CmdClassifierType not implemented: %s", op)
+			return fmt.Errorf("timeseries.handle: TimeSeriesConstraintsListData CmdClassifierType not implemented: %s", op)
 		}
 	case cmd.TimeSeriesDescriptionListData != nil:
 		data := cmd.TimeSeriesDescriptionListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyDescriptionListData(ctrl, *data, isPartialForCmd)
+			return f.replyDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyDescriptionListData(ctrl, *data, isPartialForCmd)
+			return f.replyDescriptionListData(ctrl, *data, filterPartial, filterDelete)
 		default:
 			return fmt.Errorf("timeseries.handle: TimeSeriesDescriptionListData CmdClassifierType not implemented: %s", op)
 		}
@@ -480,9 +574,9 @@ func (f *TimeSeries) Handle(ctrl spine.Context, rf model.FeatureAddressType, op
 		data := cmd.TimeSeriesListData
 		switch op {
 		case model.CmdClassifierTypeReply:
-			return f.replyListData(ctrl, *data, isPartialForCmd)
+			return f.replyListData(ctrl, *data, filterPartial, filterDelete)
 		case model.CmdClassifierTypeNotify:
-			return f.replyListData(ctrl, *data, isPartialForCmd)
+			return f.replyListData(ctrl, *data, filterPartial, filterDelete)
 		default:
 			return fmt.Errorf("timeseries.handle: TimeSeriesListData CmdClassifierType not implemented: %s", op)
 		}
@@ -0,0 +1,314 @@
+package spine
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// AddressMap contains the allocated entity addresses and feature ids by their key.
+// Entity keys are the path of entity types from the root entity, e.g. "EVSE/EV",
+// feature keys are role and type, e.g. "client.LoadControl". Keys of additional
+// entities or features of the same type are suffixed with "#<n>".
+type AddressMap struct {
+	Entities map[string][]model.AddressEntityType `json:"entities,omitempty"`
+	Features map[string]map[string]uint           `json:"features,omitempty"`
+}
+
+// AddressStore persists the address map so that addresses remain stable across restarts
+type AddressStore interface {
+	Load() (AddressMap, error)
+	Save(AddressMap) error
+}
+
+// NewFileAddressStore returns an address store persisting the address map as JSON file
+func NewFileAddressStore(path string) AddressStore {
+	return &fileStore[AddressMap]{path: path}
+}
+
+// addressAllocator allocates collision-free entity addresses and feature ids of the local device
+type addressAllocator struct {
+	store       AddressStore
+	addresses   AddressMap
+	entityKeys  map[Entity]string
+	featureKeys map[Feature]string
+	mux         sync.Mutex
+}
+
+// SetAddressStore loads the address map used for allocating addresses from the store.
+// It must be called before entities are added to the device.
+func (d *DeviceImpl) SetAddressStore(store AddressStore) error {
+	addresses, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("device.SetAddressStore: %w", err)
+	}
+
+	d.addresses.mux.Lock()
+	defer d.addresses.mux.Unlock()
+
+	d.addresses.store = store
+	d.addresses.addresses = addresses
+
+	return nil
+}
+
+// allocateEntityAddress assigns the stored or next free address to the entity and
+// allocates the ids of its features. The caller must hold the device lock.
+// The returned error reports that the address map could not be saved.
+func (d *DeviceImpl) allocateEntityAddress(parent Entity, e Entity) error {
+	a := &d.addresses
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	var parentAddr []model.AddressEntityType
+	key := string(e.GetType())
+	if parent != nil {
+		parentAddr = parent.GetAddress()
+		key = a.entityKey(parent) + "/" + key
+	}
+	key = a.uniqueEntityKey(key)
+
+	used := func(addr []model.AddressEntityType) bool {
+		return d.entity(addr) != nil
+	}
+
+	addr, ok := a.addresses.Entities[key]
+	if !ok || len(addr) != len(parentAddr)+1 || !isEntityAddressPrefix(parentAddr, addr) || used(addr) {
+		// nested entity addresses start at 1, see SPINE Resource Specification 7.2.1
+		var id model.AddressEntityType
+		if parent != nil {
+			id = 1
+		}
+
+		for ; ; id++ {
+			addr = append(append([]model.AddressEntityType{}, parentAddr...), id)
+			if !used(addr) && !a.reservedEntityAddress(addr) {
+				break
+			}
+		}
+
+		if a.addresses.Entities == nil {
+			a.addresses.Entities = make(map[string][]model.AddressEntityType)
+		}
+		a.addresses.Entities[key] = addr
+	}
+
+	e.SetAddress(addr)
+
+	if a.entityKeys == nil {
+		a.entityKeys = make(map[Entity]string)
+	}
+	a.entityKeys[e] = key
+
+	// features of a new entity are renumbered since they have not been announced yet
+	var assigned []uint
+	for _, f := range e.GetFeatures() {
+		id := a.allocateFeatureID(e, f, func(id uint) bool {
+			for _, item := range assigned {
+				if item == id {
+					return true
+				}
+			}
+			return false
+		})
+
+		f.SetID(id)
+		assigned = append(assigned, id)
+	}
+
+	return a.save()
+}
+
+// allocateFeatureID assigns the stored or next free id to the feature added to the entity.
+// The returned error reports that the address map could not be saved.
+func (d *DeviceImpl) allocateFeatureID(e Entity, f Feature) error {
+	a := &d.addresses
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	features := e.GetFeatures()
+	id := a.allocateFeatureID(e, f, func(id uint) bool {
+		for _, item := range features {
+			if item.GetID() == id {
+				return true
+			}
+		}
+		return false
+	})
+
+	f.SetID(id)
+
+	return a.save()
+}
+
+// allocateFeatureID returns the stored or next free feature id
+func (a *addressAllocator) allocateFeatureID(e Entity, f Feature, used func(uint) bool) uint {
+	entityKey := a.entityKey(e)
+	key := a.uniqueFeatureKey(entityKey, fmt.Sprintf("%s.%s", f.GetRole(), f.GetType()))
+
+	if a.featureKeys == nil {
+		a.featureKeys = make(map[Feature]string)
+	}
+	a.featureKeys[f] = key
+
+	features := a.addresses.Features[entityKey]
+	if id, ok := features[key]; ok && !used(id) {
+		return id
+	}
+
+	// feature 0 is reserved for the node management of the device information entity
+	var id uint = 1
+	if e.GetType() == model.EntityTypeType(model.EntityTypeEnumTypeDeviceInformation) {
+		id = 0
+	}
+
+	for ; ; id++ {
+		if used(id) {
+			continue
+		}
+
+		reserved := false
+		for itemKey, itemID := range features {
+			if itemKey != key && itemID == id {
+				reserved = true
+				break
+			}
+		}
+
+		if !reserved {
+			break
+		}
+	}
+
+	if features == nil {
+		features = make(map[string]uint)
+		if a.addresses.Features == nil {
+			a.addresses.Features = make(map[string]map[string]uint)
+		}
+		a.addresses.Features[entityKey] = features
+	}
+	features[key] = id
+
+	return id
+}
+
+// entityKey returns the key of an entity not allocated by the allocator from its type path
+func (a *addressAllocator) entityKey(e Entity) string {
+	if key, ok := a.entityKeys[e]; ok {
+		return key
+	}
+
+	key := string(e.GetType())
+	if parent := e.GetParent(); parent != nil {
+		key = a.entityKey(parent) + "/" + key
+	}
+
+	return key
+}
+
+// uniqueEntityKey returns the first key not used by a current entity
+func (a *addressAllocator) uniqueEntityKey(base string) string {
+	for n := 0; ; n++ {
+		key := base
+		if n > 0 {
+			key = fmt.Sprintf("%s#%d", base, n)
+		}
+
+		used := false
+		for _, item := range a.entityKeys {
+			if item == key {
+				used = true
+				break
+			}
+		}
+
+		if !used {
+			return key
+		}
+	}
+}
+
+// uniqueFeatureKey returns the first key not used by a current feature of the entity
+func (a *addressAllocator) uniqueFeatureKey(entityKey, base string) string {
+	for n := 0; ; n++ {
+		key := base
+		if n > 0 {
+			key = fmt.Sprintf("%s#%d", base, n)
+		}
+
+		used := false
+		for f, item := range a.featureKeys {
+			if item == key && f.GetEntity() != nil && a.entityKey(f.GetEntity()) == entityKey {
+				used = true
+				break
+			}
+		}
+
+		if !used {
+			return key
+		}
+	}
+}
+
+// isEntityAddressPrefix checks if addr is below or equal to prefix
+func isEntityAddressPrefix(prefix, addr []model.AddressEntityType) bool {
+	if len(addr) < len(prefix) {
+		return false
+	}
+
+	for i, id := range prefix {
+		if addr[i] != id {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reservedEntityAddress checks if the address is stored for another entity
+func (a *addressAllocator) reservedEntityAddress(addr []model.AddressEntityType) bool {
+	for _, item := range a.addresses.Entities {
+		if reflect.DeepEqual(item, addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remove releases the keys of the entity and its features
+func (a *addressAllocator) remove(e Entity) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	delete(a.entityKeys, e)
+
+	for f := range a.featureKeys {
+		if f.GetEntity() == e {
+			delete(a.featureKeys, f)
+		}
+	}
+}
+
+// removeFeature releases the key of the feature
+func (a *addressAllocator) removeFeature(f Feature) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	delete(a.featureKeys, f)
+}
+
+// save persists the address map
+func (a *addressAllocator) save() error {
+	if a.store == nil {
+		return nil
+	}
+
+	if err := a.store.Save(a.addresses); err != nil {
+		return fmt.Errorf("device.saveAddresses: %w", err)
+	}
+
+	return nil
+}
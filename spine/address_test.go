@@ -0,0 +1,159 @@
+package spine
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func newAddressTestDevice(t *testing.T, store AddressStore) (*DeviceImpl, *EntityImpl, *EntityImpl) {
+	dev := &DeviceImpl{Address: "d:_i:HEMS"}
+	if store != nil {
+		if err := dev.SetAddressStore(store); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	di := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeDeviceInformation)}
+	di.Add(&FeatureImpl{Type: model.FeatureTypeEnumTypeNodeManagement, Role: model.RoleTypeSpecial})
+	dev.Add(di)
+
+	cem := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeCEM)}
+	cem.Add(&FeatureImpl{Type: model.FeatureTypeEnumTypeLoadControl, Role: model.RoleTypeClient})
+	cem.Add(&FeatureImpl{Type: model.FeatureTypeEnumTypeMeasurement, Role: model.RoleTypeClient})
+	dev.Add(cem)
+
+	return dev, di, cem
+}
+
+func TestAddressAllocation(t *testing.T) {
+	dev, di, cem := newAddressTestDevice(t, nil)
+
+	if !reflect.DeepEqual(di.GetAddress(), []model.AddressEntityType{0}) || di.GetFeatures()[0].GetID() != 0 {
+		t.Errorf("unexpected device information address: %v", di.GetAddress())
+	}
+
+	if !reflect.DeepEqual(cem.GetAddress(), []model.AddressEntityType{1}) {
+		t.Errorf("unexpected cem address: %v", cem.GetAddress())
+	}
+
+	for i, f := range cem.GetFeatures() {
+		if f.GetID() != uint(i+1) {
+			t.Errorf("unexpected feature id: %d", f.GetID())
+		}
+	}
+
+	ev := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeEV)}
+	dev.AddSubEntity(cem, ev)
+	if !reflect.DeepEqual(ev.GetAddress(), []model.AddressEntityType{1, 1}) || ev.GetParent() != cem {
+		t.Errorf("unexpected sub-entity address: %v", ev.GetAddress())
+	}
+
+	// explicitly addressed entities are not reused
+	dev.Add(&EntityImpl{Address: []model.AddressEntityType{2}})
+	heatpump := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeHeatPumpAppliance)}
+	dev.Add(heatpump)
+	if !reflect.DeepEqual(heatpump.GetAddress(), []model.AddressEntityType{3}) {
+		t.Errorf("unexpected address: %v", heatpump.GetAddress())
+	}
+
+	// removed feature ids are not reused by other features
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	dev.RemoveFeature(lc)
+
+	f := &FeatureImpl{Type: model.FeatureTypeEnumTypeIdentification, Role: model.RoleTypeClient}
+	dev.AddFeature(cem, f)
+	if f.GetID() != 3 {
+		t.Errorf("unexpected feature id: %d", f.GetID())
+	}
+
+	dev.AddFeature(cem, lc)
+	if lc.GetID() != 1 {
+		t.Errorf("unexpected feature id after re-adding: %d", lc.GetID())
+	}
+}
+
+func TestAddressStore(t *testing.T) {
+	store := NewFileAddressStore(filepath.Join(t.TempDir(), "addresses.json"))
+
+	dev, _, cem := newAddressTestDevice(t, store)
+
+	ev := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeEV)}
+	dev.AddSubEntity(cem, ev)
+
+	f := &FeatureImpl{Type: model.FeatureTypeEnumTypeIdentification, Role: model.RoleTypeClient}
+	dev.AddFeature(ev, f)
+
+	// restart with the entities added in a different order
+	dev = &DeviceImpl{Address: "d:_i:HEMS"}
+	if err := dev.SetAddressStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	cem2 := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeCEM)}
+	cem2.Add(&FeatureImpl{Type: model.FeatureTypeEnumTypeMeasurement, Role: model.RoleTypeClient})
+	cem2.Add(&FeatureImpl{Type: model.FeatureTypeEnumTypeLoadControl, Role: model.RoleTypeClient})
+	dev.Add(cem2)
+
+	di := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeDeviceInformation)}
+	dev.Add(di)
+
+	if !reflect.DeepEqual(cem2.GetAddress(), cem.GetAddress()) || !reflect.DeepEqual(di.GetAddress(), []model.AddressEntityType{0}) {
+		t.Errorf("entity addresses not stable: %v %v", cem2.GetAddress(), di.GetAddress())
+	}
+
+	for _, f := range cem2.GetFeatures() {
+		if prev := cem.FeatureByProps(f.GetType(), f.GetRole()); prev.GetID() != f.GetID() {
+			t.Errorf("feature id of %s not stable: %d != %d", f.GetType(), f.GetID(), prev.GetID())
+		}
+	}
+
+	ev2 := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeEV)}
+	dev.AddSubEntity(cem2, ev2)
+
+	f2 := &FeatureImpl{Type: model.FeatureTypeEnumTypeIdentification, Role: model.RoleTypeClient}
+	dev.AddFeature(ev2, f2)
+
+	if !reflect.DeepEqual(ev2.GetAddress(), ev.GetAddress()) || f2.GetID() != f.GetID() {
+		t.Errorf("sub-entity address not stable: %v %d", ev2.GetAddress(), f2.GetID())
+	}
+}
+
+// failingAddressStore fails saving the address map
+type failingAddressStore struct{}
+
+func (failingAddressStore) Load() (AddressMap, error) {
+	return AddressMap{}, nil
+}
+
+func (failingAddressStore) Save(AddressMap) error {
+	return errors.New("disk full")
+}
+
+func TestAddressStoreError(t *testing.T) {
+	dev := &DeviceImpl{Address: "d:_i:HEMS"}
+	if err := dev.SetAddressStore(failingAddressStore{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	dev.SetStoreErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	cem := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeCEM)}
+	dev.Add(cem)
+	dev.AddFeature(cem, &FeatureImpl{Type: model.FeatureTypeEnumTypeLoadControl, Role: model.RoleTypeClient})
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+
+	// addresses remain allocated
+	if !reflect.DeepEqual(cem.GetAddress(), []model.AddressEntityType{0}) || cem.GetFeatures()[0].GetID() != 1 {
+		t.Errorf("unexpected address: %v", cem.GetAddress())
+	}
+}
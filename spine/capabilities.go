@@ -0,0 +1,89 @@
+package spine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// FunctionCapability contains the operations a feature supports for a function
+type FunctionCapability struct {
+	Function model.FunctionEnumType
+	RW
+}
+
+// FeatureCapability contains the functions supported by a feature
+type FeatureCapability struct {
+	Entity      []model.AddressEntityType
+	EntityType  model.EntityTypeType
+	Feature     uint
+	FeatureType model.FeatureTypeEnumType
+	Role        model.RoleType
+	Functions   []FunctionCapability // sorted by function
+}
+
+// Capabilities returns the capability matrix of all features of the device
+func Capabilities(d Device) []FeatureCapability {
+	var res []FeatureCapability
+
+	for _, e := range d.GetEntities() {
+		for _, f := range e.GetFeatures() {
+			res = append(res, featureCapability(e, f))
+		}
+	}
+
+	return res
+}
+
+func featureCapability(e Entity, f Feature) FeatureCapability {
+	res := FeatureCapability{
+		Entity:      e.GetAddress(),
+		EntityType:  e.GetType(),
+		Feature:     f.GetID(),
+		FeatureType: f.GetType(),
+		Role:        f.GetRole(),
+	}
+
+	for _, sf := range f.Information().Description.SupportedFunction {
+		if sf.Function == nil {
+			continue
+		}
+
+		var rw RW
+		if ops := sf.PossibleOperations; ops != nil {
+			rw = RW{Read: ops.Read != nil, Write: ops.Write != nil}
+		}
+
+		res.Functions = append(res.Functions, FunctionCapability{
+			Function: model.FunctionEnumType(*sf.Function),
+			RW:       rw,
+		})
+	}
+
+	sort.Slice(res.Functions, func(i, j int) bool {
+		return res.Functions[i].Function < res.Functions[j].Function
+	})
+
+	return res
+}
+
+// CheckFunctionOperation returns ErrFeatureNotSupported if the feature does not support
+// reading or writing the function
+func CheckFunctionOperation(f Feature, fun model.FunctionEnumType, op model.CmdClassifierType) error {
+	if f == nil {
+		return fmt.Errorf("%w: %s %s", ErrFeatureNotSupported, op, fun)
+	}
+
+	rw, ok := f.FunctionOperations(fun)
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s does not support %s", ErrFeatureNotSupported, f.GetType(), fun)
+	case op == model.CmdClassifierTypeRead && !rw.Read:
+		return fmt.Errorf("%w: %s %s is not readable", ErrFeatureNotSupported, f.GetType(), fun)
+	case op == model.CmdClassifierTypeWrite && !rw.Write:
+		return fmt.Errorf("%w: %s %s is not writable", ErrFeatureNotSupported, f.GetType(), fun)
+	}
+
+	return nil
+}
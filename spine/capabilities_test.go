@@ -0,0 +1,40 @@
+package spine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestCapabilities(t *testing.T) {
+	dev := &DeviceImpl{Address: "d:_i:EVSE"}
+
+	e := &EntityImpl{Type: model.EntityTypeType(model.EntityTypeEnumTypeEV)}
+	f := &FeatureImpl{Type: model.FeatureTypeEnumTypeLoadControl, Role: model.RoleTypeServer}
+	f.Add(model.FunctionEnumTypeLoadControlLimitListData, true, false)
+	f.Add(model.FunctionEnumTypeLoadControlLimitDescriptionListData, true, false)
+	e.Add(f)
+	dev.Add(e)
+
+	caps := Capabilities(dev)
+	if len(caps) != 1 || len(caps[0].Functions) != 2 {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+
+	if fc := caps[0].Functions[0]; fc.Function != model.FunctionEnumTypeLoadControlLimitDescriptionListData || !fc.Read || fc.Write {
+		t.Errorf("unexpected function capability: %+v", fc)
+	}
+
+	if err := CheckFunctionOperation(f, model.FunctionEnumTypeLoadControlLimitListData, model.CmdClassifierTypeRead); err != nil {
+		t.Error(err)
+	}
+
+	if err := CheckFunctionOperation(f, model.FunctionEnumTypeLoadControlLimitListData, model.CmdClassifierTypeWrite); !errors.Is(err, ErrFeatureNotSupported) {
+		t.Errorf("expected write to be rejected, got %v", err)
+	}
+
+	if err := CheckFunctionOperation(nil, model.FunctionEnumTypeLoadControlLimitListData, model.CmdClassifierTypeRead); !errors.Is(err, ErrFeatureNotSupported) {
+		t.Errorf("expected missing feature to be rejected, got %v", err)
+	}
+}
@@ -7,7 +7,7 @@ type Context interface {
 	SetDevice(Device)
 	GetDevice() Device
 	UpdateDevice(model.NetworkManagementStateChangeType)
-	HeartbeatCounter() *uint64
+	HeartbeatData() model.DeviceDiagnosisHeartbeatDataType
 	Subscribe(lf Feature, rf Feature, typ model.FeatureTypeType) error
 	ProcessSequenceFlowRequest(featureType model.FeatureTypeEnumType, functionType model.FunctionEnumType, cmdClassifier model.CmdClassifierType) (*model.MsgCounterType, error)
 	Request(model.CmdClassifierType, model.FeatureAddressType, model.FeatureAddressType, bool, []model.CmdType) (*model.MsgCounterType, error)
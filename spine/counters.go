@@ -0,0 +1,126 @@
+package spine
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// counterReservation is the number of counter values reserved by each save so that
+// the counters are not persisted for every message
+const counterReservation = 1000
+
+// Counters contains the message and heartbeat counters of the local device
+type Counters struct {
+	MsgCounter       uint64 `json:"msgCounter"`
+	HeartbeatCounter uint64 `json:"heartbeatCounter"`
+}
+
+// CounterStore persists the counters so that they continue increasing across restarts
+type CounterStore interface {
+	Load() (Counters, error)
+	Save(Counters) error
+}
+
+// NewFileCounterStore returns a counter store persisting the counters as JSON file
+func NewFileCounterStore(path string) CounterStore {
+	return &fileStore[Counters]{path: path}
+}
+
+type deviceCounters struct {
+	store    CounterStore
+	current  Counters
+	reserved Counters // counters up to these values may be used without saving
+	mux      sync.Mutex
+}
+
+// SetCounterStore continues the counters from the values reserved in the store
+func (d *DeviceImpl) SetCounterStore(store CounterStore) error {
+	reserved, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("device.SetCounterStore: %w", err)
+	}
+
+	c := &d.counters
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.store = store
+	if reserved.MsgCounter > c.current.MsgCounter {
+		c.current.MsgCounter = reserved.MsgCounter
+	}
+	if reserved.HeartbeatCounter > c.current.HeartbeatCounter {
+		c.current.HeartbeatCounter = reserved.HeartbeatCounter
+	}
+
+	return c.reserve()
+}
+
+// NextMsgCounter returns the next message counter of the device
+func (d *DeviceImpl) NextMsgCounter() model.MsgCounterType {
+	c := &d.counters
+	c.mux.Lock()
+
+	c.current.MsgCounter++
+
+	var err error
+	if c.current.MsgCounter > c.reserved.MsgCounter {
+		err = c.reserve()
+	}
+	res := model.MsgCounterType(c.current.MsgCounter)
+
+	c.mux.Unlock()
+
+	d.storeError(err)
+
+	return res
+}
+
+// NextHeartbeatCounter returns the next heartbeat counter of the device
+func (d *DeviceImpl) NextHeartbeatCounter() uint64 {
+	c := &d.counters
+	c.mux.Lock()
+
+	c.current.HeartbeatCounter++
+
+	var err error
+	if c.current.HeartbeatCounter > c.reserved.HeartbeatCounter {
+		err = c.reserve()
+	}
+	res := c.current.HeartbeatCounter
+
+	c.mux.Unlock()
+
+	d.storeError(err)
+
+	return res
+}
+
+// HeartbeatCounter returns the heartbeat counter last sent by the device
+func (d *DeviceImpl) HeartbeatCounter() uint64 {
+	c := &d.counters
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.current.HeartbeatCounter
+}
+
+// reserve saves the next block of counter values. Without store or if saving
+// fails the counters continue unpersisted.
+func (c *deviceCounters) reserve() error {
+	c.reserved = Counters{
+		MsgCounter:       c.current.MsgCounter + counterReservation,
+		HeartbeatCounter: c.current.HeartbeatCounter + counterReservation,
+	}
+
+	if c.store == nil {
+		return nil
+	}
+
+	if err := c.store.Save(c.reserved); err != nil {
+		return fmt.Errorf("device.saveCounters: %w", err)
+	}
+
+	return nil
+}
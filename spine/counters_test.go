@@ -0,0 +1,86 @@
+package spine
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestCounters(t *testing.T) {
+	store := NewFileCounterStore(filepath.Join(t.TempDir(), "counters.json"))
+
+	dev := &DeviceImpl{}
+	if err := dev.SetCounterStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := dev.NextMsgCounter(); c != 1 {
+		t.Errorf("unexpected msgCounter: %d", c)
+	}
+
+	var last uint64
+	for i := 0; i < counterReservation+1; i++ {
+		last = dev.NextHeartbeatCounter()
+	}
+	if dev.HeartbeatCounter() != last {
+		t.Errorf("unexpected heartbeat counter: %d", dev.HeartbeatCounter())
+	}
+
+	// counters continue increasing after restart
+	dev = &DeviceImpl{}
+	if err := dev.SetCounterStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := dev.NextMsgCounter(); c <= 1 {
+		t.Errorf("msgCounter not increasing after restart: %d", c)
+	}
+	if c := dev.NextHeartbeatCounter(); c <= last {
+		t.Errorf("heartbeat counter not increasing after restart: %d <= %d", c, last)
+	}
+}
+
+// failingCounterStore fails saving after the initial reservation
+type failingCounterStore struct {
+	saved bool
+}
+
+func (s *failingCounterStore) Load() (Counters, error) {
+	return Counters{}, nil
+}
+
+func (s *failingCounterStore) Save(Counters) error {
+	if s.saved {
+		return errors.New("disk full")
+	}
+	s.saved = true
+	return nil
+}
+
+func TestCounterStoreError(t *testing.T) {
+	dev := &DeviceImpl{}
+	if err := dev.SetCounterStore(&failingCounterStore{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	dev.SetStoreErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	var last model.MsgCounterType
+	for i := 0; i < counterReservation+1; i++ {
+		last = dev.NextMsgCounter()
+	}
+
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+
+	// counters continue unpersisted
+	if last != counterReservation+1 {
+		t.Errorf("unexpected msgCounter: %d", last)
+	}
+}
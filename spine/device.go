@@ -1,10 +1,10 @@
 package spine
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sync"
 
 	"github.com/evcc-io/eebus/spine/model"
 )
@@ -15,14 +15,30 @@ type Device interface {
 	GetType() model.DeviceTypeType
 
 	Add(e Entity)
+	AddSubEntity(parent Entity, e Entity)
 	RemoveByAddress(addr []model.AddressEntityType)
+	AddFeature(e Entity, f Feature)
+	RemoveFeature(f Feature)
+	AddDiscoveryChangeHandler(handler DiscoveryChangeHandler) func()
 	Entity(addr []model.AddressEntityType) Entity
 	EntityByType(typ model.EntityTypeType) Entity
 
-	SetUseCaseActor(actorName string, useCases []model.UseCaseSupportType)
-	ResetUseCaseActors()
-	GetUseCaseActors() []string
-	GetUseCaseForActor(actorName string) ([]model.UseCaseSupportType, error)
+	UpdateRemoteUseCases(data []model.UseCaseInformationDataType, partial bool) []UseCaseEvent
+	RemoveRemoteUseCases(actor model.UseCaseActorEnumType) []UseCaseEvent
+	RemoteUseCases() []RemoteUseCase
+	SupportsUseCase(entity []model.AddressEntityType, name model.UseCaseNameEnumType, scenario model.UseCaseScenarioSupportType, minVersion model.SpecificationVersionType) bool
+
+	AddUseCase(uc UseCase) error
+	RemoveUseCase(actor model.UseCaseActorEnumType, name model.UseCaseNameEnumType)
+	SetUseCaseAvailable(actor model.UseCaseActorEnumType, name model.UseCaseNameEnumType, available bool) error
+	UseCases() []UseCase
+	UseCaseInformation() []model.UseCaseInformationDataType
+	AddUseCaseChangeHandler(handler UseCaseChangeHandler) func()
+
+	NextMsgCounter() model.MsgCounterType
+	NextHeartbeatCounter() uint64
+	HeartbeatCounter() uint64
+	SetStoreErrorHandler(handler func(error))
 
 	Information() *model.NodeManagementDetailedDiscoveryDeviceInformationType
 	Dump(w io.Writer)
@@ -31,70 +47,129 @@ type Device interface {
 var _ Device = (*DeviceImpl)(nil)
 
 type DeviceImpl struct {
-	Address       model.AddressDeviceType
-	Type          model.DeviceTypeType
-	Entities      []Entity
-	ActorUseCases map[string][]model.UseCaseSupportType
-}
-
-func (d *DeviceImpl) SetUseCaseActor(actorName string, useCases []model.UseCaseSupportType) {
-	if d.ActorUseCases == nil {
-		d.ActorUseCases = make(map[string][]model.UseCaseSupportType)
-	}
-	d.ActorUseCases[actorName] = useCases
-}
-
-func (d *DeviceImpl) ResetUseCaseActors() {
-	d.ActorUseCases = nil
-}
+	Address     model.AddressDeviceType
+	Type        model.DeviceTypeType
+	FeatureSet  model.NetworkManagementFeatureSetType
+	Label       model.LabelType
+	Description model.DescriptionType
+	Entities    []Entity // all entities including sub-entities
+	mux         sync.RWMutex
 
-func (d *DeviceImpl) GetUseCaseActors() []string {
-	var actors []string
-	for actorName := range d.ActorUseCases {
-		actors = append(actors, actorName)
-	}
-	return actors
-}
-
-func (d *DeviceImpl) GetUseCaseForActor(actorName string) ([]model.UseCaseSupportType, error) {
-	usecases, found := d.ActorUseCases[actorName]
-	if found {
-		return usecases, nil
-	}
-	return nil, errors.New("actor not found")
+	useCases          useCaseRegistry  // use cases implemented by the local or announced by the remote device
+	addresses         addressAllocator // entity addresses and feature ids allocated for the local device
+	counters          deviceCounters   // message and heartbeat counters of the local device
+	storeErrHandler   func(error)
+	storeMux          sync.Mutex
+	discoveryHandlers handlerList[DiscoveryChangeHandler]
 }
 
 func (d *DeviceImpl) GetAddress() model.AddressDeviceType {
 	return d.Address
 }
 
+// GetEntities returns a snapshot of all entities
 func (d *DeviceImpl) GetEntities() []Entity {
-	return d.Entities
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	return append([]Entity(nil), d.Entities...)
 }
 
 func (d *DeviceImpl) GetType() model.DeviceTypeType {
 	return d.Type
 }
 
+// Add adds the entity and links it to its parent and sub-entities based on the entity address.
+// An entity without address is allocated the next free address and its features are allocated
+// free ids in the order they have been added to the entity.
 func (d *DeviceImpl) Add(e Entity) {
+	d.add(nil, e)
+}
+
+// AddSubEntity allocates the next free address below the parent entity and adds the entity
+func (d *DeviceImpl) AddSubEntity(parent Entity, e Entity) {
+	d.add(parent, e)
+}
+
+func (d *DeviceImpl) add(parent Entity, e Entity) {
 	e.SetDevice(d)
+
+	var err error
+
+	d.mux.Lock()
+	if parent != nil || len(e.GetAddress()) == 0 {
+		err = d.allocateEntityAddress(parent, e)
+	}
+
 	d.Entities = append(d.Entities, e)
+
+	addr := e.GetAddress()
+	if len(addr) > 1 {
+		if parent := d.entity(addr[:len(addr)-1]); parent != nil {
+			parent.AddEntity(e)
+		}
+	}
+
+	for _, item := range d.Entities {
+		if itemAddr := item.GetAddress(); len(itemAddr) == len(addr)+1 && reflect.DeepEqual(itemAddr[:len(addr)], addr) {
+			e.AddEntity(item)
+		}
+	}
+	d.mux.Unlock()
+
+	d.storeError(err)
+
+	d.notifyDiscoveryChange(DiscoveryChange{
+		Change: model.NetworkManagementStateChangeTypeAdded,
+		Entity: e,
+	})
 }
 
+// RemoveByAddress removes the entity including its sub-entities
 func (d *DeviceImpl) RemoveByAddress(addr []model.AddressEntityType) {
-	entityForRemoval := d.Entity(addr)
+	d.mux.Lock()
+
+	entityForRemoval := d.entity(addr)
+	if entityForRemoval == nil {
+		d.mux.Unlock()
+		return
+	}
 
-	var newEntities []Entity
+	if parent := entityForRemoval.GetParent(); parent != nil {
+		parent.RemoveEntity(entityForRemoval)
+	}
+
+	var newEntities, removed []Entity
 	for _, item := range d.Entities {
-		if !reflect.DeepEqual(item, entityForRemoval) {
+		if itemAddr := item.GetAddress(); len(itemAddr) < len(addr) || !reflect.DeepEqual(itemAddr[:len(addr)], addr) {
 			newEntities = append(newEntities, item)
+		} else {
+			removed = append(removed, item)
 		}
 	}
 
 	d.Entities = newEntities
+	d.mux.Unlock()
+
+	for _, e := range removed {
+		d.removeUseCasesForEntity(e)
+		d.addresses.remove(e)
+
+		d.notifyDiscoveryChange(DiscoveryChange{
+			Change: model.NetworkManagementStateChangeTypeRemoved,
+			Entity: e,
+		})
+	}
 }
 
 func (d *DeviceImpl) Entity(id []model.AddressEntityType) Entity {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	return d.entity(id)
+}
+
+func (d *DeviceImpl) entity(id []model.AddressEntityType) Entity {
 	for _, e := range d.Entities {
 		if reflect.DeepEqual(id, e.GetAddress()) {
 			return e
@@ -105,6 +180,9 @@ func (d *DeviceImpl) Entity(id []model.AddressEntityType) Entity {
 
 func (d *DeviceImpl) EntityByType(typ model.EntityTypeType) Entity {
 	if d != nil {
+		d.mux.RLock()
+		defer d.mux.RUnlock()
+
 		for _, e := range d.Entities {
 			if e.GetType() == typ {
 				return e
@@ -121,25 +199,39 @@ func (d *DeviceImpl) Information() *model.NodeManagementDetailedDiscoveryDeviceI
 				Device: &d.Address,
 			},
 			DeviceType: &d.Type,
-			// TODO NetworkFeatureSet
-			// NetworkFeatureSet: &smart,
 		},
 	}
+
+	if d.FeatureSet != "" {
+		res.Description.NetworkFeatureSet = &d.FeatureSet
+	}
+	if d.Label != "" {
+		res.Description.Label = &d.Label
+	}
+	if d.Description != "" {
+		res.Description.Description = &d.Description
+	}
+
 	return &res
 }
 
 func (d *DeviceImpl) Dump(w io.Writer) {
 	fmt.Fprintf(w, "Details: device=%s, type=%s\n", d.Address, d.Type)
 
+	entities := d.GetEntities()
+
 	fmt.Fprintln(w, "  Entities:")
-	for _, e := range d.Entities {
+	for _, e := range entities {
 		addr := EntityAddressString(e)
 		fmt.Fprintf(w, "    e[%s] type=%s\n", addr, e.GetType())
 	}
 
+	// sub-entities are dumped by their parent
 	fmt.Fprintln(w, "  Features:")
-	for _, e := range d.Entities {
-		e.Dump(w)
+	for _, e := range entities {
+		if e.GetParent() == nil {
+			e.Dump(w)
+		}
 	}
 }
 
@@ -157,6 +249,18 @@ func UnmarshalDevice(data model.NodeManagementDetailedDiscoveryDataType) *Device
 		if did.DeviceAddress != nil && did.DeviceAddress.Device != nil {
 			dev.Address = *did.DeviceAddress.Device
 		}
+
+		if did.NetworkFeatureSet != nil {
+			dev.FeatureSet = *did.NetworkFeatureSet
+		}
+
+		if did.Label != nil {
+			dev.Label = *did.Label
+		}
+
+		if did.Description != nil {
+			dev.Description = *did.Description
+		}
 	}
 
 	return dev
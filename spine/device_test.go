@@ -0,0 +1,100 @@
+package spine
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestUnmarshalDeviceTree(t *testing.T) {
+	var data model.NodeManagementDetailedDiscoveryDataType
+	if err := json.Unmarshal([]byte(`[
+		{"deviceInformation":[{"description":[{"deviceAddress":[{"device":"d:_i:EVSE"}]},{"deviceType":"ChargingStation"},{"networkFeatureSet":"smart"},{"description":"Wallbox"}]}]},
+		{"entityInformation":[
+			[{"description":[{"entityAddress":[{"entity":[1,1]}]},{"entityType":"EV"},{"description":"Electric Vehicle"}]}],
+			[{"description":[{"entityAddress":[{"entity":[1]}]},{"entityType":"EVSE"},{"label":"EVSE"}]}]
+		]},
+		{"featureInformation":[
+			[{"description":[{"featureAddress":[{"entity":[1,1]},{"feature":1}]},{"featureType":"LoadControl"},{"role":"server"},{"supportedFunction":[[{"function":"loadControlLimitListData"},{"possibleOperations":[{"read":[]},{"write":[]}]}]]},{"description":"Load Control"},{"maxResponseDelay":"PT5S"}]}]
+		]}
+	]`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	dev := UnmarshalDevice(data)
+	if dev.FeatureSet != model.NetworkManagementFeatureSetTypeSmart || dev.Description != "Wallbox" {
+		t.Errorf("device description incomplete: %+v", dev)
+	}
+
+	for _, ei := range data.EntityInformation {
+		dev.Add(UnmarshalEntity(dev.GetAddress(), ei))
+	}
+
+	ev := dev.Entity([]model.AddressEntityType{1, 1})
+	evse := dev.Entity([]model.AddressEntityType{1})
+	if ev == nil || evse == nil {
+		t.Fatal("entities not found")
+	}
+	if ev.GetParent() != evse || len(evse.GetEntities()) != 1 || ev.GetDescription() != "Electric Vehicle" {
+		t.Errorf("entity hierarchy incomplete")
+	}
+
+	f := UnmarshalFeature(data.FeatureInformation[0])
+	ev.Add(f)
+	if f.GetMaxResponseDelay() != 5*time.Second || f.GetDescription() != "Load Control" {
+		t.Errorf("feature description incomplete: %+v", f)
+	}
+	if rw, ok := f.FunctionOperations(model.FunctionEnumTypeLoadControlLimitListData); !ok || !rw.Read || !rw.Write {
+		t.Errorf("unexpected operations: %v", rw)
+	}
+
+	info, err := json.Marshal(dev.Information())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"description":[{"deviceAddress":[{"device":"d:_i:EVSE"}]},{"deviceType":"ChargingStation"},{"networkFeatureSet":"smart"},{"description":"Wallbox"}]}]`; string(info) != expected {
+		t.Errorf("unexpected information:\n%s\n%s", info, expected)
+	}
+
+	dev.RemoveByAddress([]model.AddressEntityType{1})
+	if len(dev.GetEntities()) != 0 {
+		t.Errorf("sub-entities not removed: %v", dev.GetEntities())
+	}
+}
+
+func TestDeviceConcurrentAccess(t *testing.T) {
+	dev := &DeviceImpl{Address: "d:_i:EVSE"}
+	evse := &EntityImpl{Address: []model.AddressEntityType{1}}
+	dev.Add(evse)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 100; i++ {
+			ev := &EntityImpl{Address: []model.AddressEntityType{1, 1}}
+			dev.Add(ev)
+			dev.AddFeature(ev, &FeatureImpl{ID: 1, Role: model.RoleTypeServer})
+			dev.RemoveByAddress(ev.GetAddress())
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		for _, e := range dev.GetEntities() {
+			for _, f := range e.GetFeatures() {
+				_ = f.Information()
+			}
+			_ = e.GetEntities()
+			_ = e.GetParent()
+		}
+		_ = dev.Entity([]model.AddressEntityType{1, 1})
+	}
+
+	<-done
+
+	if len(dev.GetEntities()) != 1 || len(evse.GetEntities()) != 0 {
+		t.Errorf("unexpected entities after removal: %d", len(dev.GetEntities()))
+	}
+}
@@ -0,0 +1,58 @@
+package spine
+
+import (
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// DiscoveryChange describes an entity or feature added to or removed from the device
+type DiscoveryChange struct {
+	Change  model.NetworkManagementStateChangeType
+	Entity  Entity
+	Feature Feature // nil if the entity itself has been added or removed
+}
+
+// DiscoveryChangeHandler is called when entities or features of the device have been added or removed
+type DiscoveryChangeHandler func(change DiscoveryChange)
+
+// AddDiscoveryChangeHandler registers a handler for added or removed entities and features.
+// The returned function removes the handler.
+func (d *DeviceImpl) AddDiscoveryChangeHandler(handler DiscoveryChangeHandler) func() {
+	return d.discoveryHandlers.add(handler)
+}
+
+// AddFeature allocates the next free feature id and adds the feature to the device's entity
+func (d *DeviceImpl) AddFeature(e Entity, f Feature) {
+	err := d.allocateFeatureID(e, f)
+	e.Add(f)
+
+	d.storeError(err)
+
+	d.notifyDiscoveryChange(DiscoveryChange{
+		Change:  model.NetworkManagementStateChangeTypeAdded,
+		Entity:  e,
+		Feature: f,
+	})
+}
+
+// RemoveFeature removes the feature from its entity
+func (d *DeviceImpl) RemoveFeature(f Feature) {
+	e := f.GetEntity()
+	if e == nil {
+		return
+	}
+
+	e.RemoveFeature(f)
+	d.addresses.removeFeature(f)
+
+	d.notifyDiscoveryChange(DiscoveryChange{
+		Change:  model.NetworkManagementStateChangeTypeRemoved,
+		Entity:  e,
+		Feature: f,
+	})
+}
+
+func (d *DeviceImpl) notifyDiscoveryChange(change DiscoveryChange) {
+	for _, handler := range d.discoveryHandlers.list() {
+		handler(change)
+	}
+}
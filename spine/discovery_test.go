@@ -0,0 +1,50 @@
+package spine
+
+import (
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestDiscoveryChange(t *testing.T) {
+	dev := &DeviceImpl{Address: "d:_i:HEMS"}
+
+	var changes []DiscoveryChange
+	remove := dev.AddDiscoveryChangeHandler(func(change DiscoveryChange) {
+		changes = append(changes, change)
+	})
+	defer remove()
+
+	parent := &EntityImpl{Address: []model.AddressEntityType{1}}
+	child := &EntityImpl{Address: []model.AddressEntityType{1, 1}}
+	dev.Add(parent)
+	dev.Add(child)
+
+	f := &FeatureImpl{ID: 1, Type: model.FeatureTypeEnumTypeMeasurement, Role: model.RoleTypeServer}
+	dev.AddFeature(child, f)
+	dev.RemoveFeature(f)
+
+	if child.Feature(1) != nil {
+		t.Error("feature not removed")
+	}
+
+	dev.RemoveByAddress(parent.GetAddress())
+
+	expected := []DiscoveryChange{
+		{Change: model.NetworkManagementStateChangeTypeAdded, Entity: parent},
+		{Change: model.NetworkManagementStateChangeTypeAdded, Entity: child},
+		{Change: model.NetworkManagementStateChangeTypeAdded, Entity: child, Feature: f},
+		{Change: model.NetworkManagementStateChangeTypeRemoved, Entity: child, Feature: f},
+		{Change: model.NetworkManagementStateChangeTypeRemoved, Entity: parent},
+		{Change: model.NetworkManagementStateChangeTypeRemoved, Entity: child},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d", len(expected), len(changes))
+	}
+	for i, change := range changes {
+		if change != expected[i] {
+			t.Errorf("change %d: expected %v, got %v", i, expected[i], change)
+		}
+	}
+}
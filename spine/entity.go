@@ -5,6 +5,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/evcc-io/eebus/spine/model"
 	"github.com/samber/lo"
@@ -19,6 +20,13 @@ type Entity interface {
 
 	GetFeatures() []Feature
 	GetType() model.EntityTypeType
+	GetDescription() model.DescriptionType
+
+	GetParent() Entity
+	SetParent(e Entity)
+	GetEntities() []Entity
+	AddEntity(e Entity)
+	RemoveEntity(e Entity)
 
 	GetManufacturerData() model.DeviceClassificationManufacturerDataType
 	SetManufacturerData(model.DeviceClassificationManufacturerDataType)
@@ -27,6 +35,7 @@ type Entity interface {
 	SetOperationState(model.DeviceDiagnosisOperatingStateType)
 
 	Add(f Feature)
+	RemoveFeature(f Feature)
 	Feature(id uint) Feature
 	FeatureByProps(typ model.FeatureTypeEnumType, role model.RoleType) Feature
 
@@ -41,11 +50,13 @@ type EntityImpl struct {
 	Address          []model.AddressEntityType
 	Type             model.EntityTypeType
 	Description      model.DescriptionType
+	Label            model.LabelType
 	Parent           Entity
 	Entities         []Entity
 	Features         []Feature
 	ManufacturerData model.DeviceClassificationManufacturerDataType
 	OperationState   model.DeviceDiagnosisOperatingStateType
+	mux              sync.RWMutex
 }
 
 func (e *EntityImpl) GetAddress() []model.AddressEntityType {
@@ -64,38 +75,129 @@ func (e *EntityImpl) SetDevice(d Device) {
 	e.Device = d
 }
 
+// GetFeatures returns a snapshot of the entity's features
 func (e *EntityImpl) GetFeatures() []Feature {
-	return e.Features
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	return append([]Feature(nil), e.Features...)
 }
 
 func (e *EntityImpl) GetType() model.EntityTypeType {
 	return e.Type
 }
 
+func (e *EntityImpl) GetDescription() model.DescriptionType {
+	return e.Description
+}
+
+func (e *EntityImpl) GetParent() Entity {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	return e.Parent
+}
+
+func (e *EntityImpl) SetParent(parent Entity) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	e.Parent = parent
+}
+
+// GetEntities returns a snapshot of the sub-entities
+func (e *EntityImpl) GetEntities() []Entity {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	return append([]Entity(nil), e.Entities...)
+}
+
+// AddEntity adds a sub-entity
+func (e *EntityImpl) AddEntity(child Entity) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for _, item := range e.Entities {
+		if item == child {
+			return
+		}
+	}
+
+	child.SetParent(e)
+	e.Entities = append(e.Entities, child)
+}
+
+// RemoveEntity removes a sub-entity
+func (e *EntityImpl) RemoveEntity(child Entity) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for i, item := range e.Entities {
+		if item == child {
+			e.Entities = append(e.Entities[:i:i], e.Entities[i+1:]...)
+			child.SetParent(nil)
+			return
+		}
+	}
+}
+
 func (e *EntityImpl) GetManufacturerData() model.DeviceClassificationManufacturerDataType {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
 	return e.ManufacturerData
 }
 
 func (e *EntityImpl) SetManufacturerData(data model.DeviceClassificationManufacturerDataType) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
 	e.ManufacturerData = data
 }
 
 func (e *EntityImpl) GetOperationState() model.DeviceDiagnosisOperatingStateType {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
 	return e.OperationState
 }
 
 func (e *EntityImpl) SetOperationState(data model.DeviceDiagnosisOperatingStateType) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
 	e.OperationState = data
 }
 
-// TODO maintain feature id when adding
+// Add adds the feature with its given id, use Device.AddFeature for allocating a free id
 func (e *EntityImpl) Add(f Feature) {
 	f.SetEntity(e)
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
 	e.Features = append(e.Features, f)
 }
 
+func (e *EntityImpl) RemoveFeature(f Feature) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	for i, item := range e.Features {
+		if item == f {
+			// copy to keep previously returned snapshots intact
+			e.Features = append(e.Features[:i:i], e.Features[i+1:]...)
+			return
+		}
+	}
+}
+
 func (e *EntityImpl) Feature(id uint) Feature {
 	if e != nil {
+		e.mux.RLock()
+		defer e.mux.RUnlock()
+
 		for _, f := range e.Features {
 			if f.GetID() == id {
 				return f
@@ -107,6 +209,9 @@ func (e *EntityImpl) Feature(id uint) Feature {
 
 func (e *EntityImpl) FeatureByProps(typ model.FeatureTypeEnumType, role model.RoleType) Feature {
 	if e != nil {
+		e.mux.RLock()
+		defer e.mux.RUnlock()
+
 		for _, f := range e.Features {
 			if f.GetType() == typ && f.GetRole() == role {
 				return f
@@ -124,16 +229,23 @@ func (e *EntityImpl) Information() *model.NodeManagementDetailedDiscoveryEntityI
 		},
 	}
 
+	if e.Label != "" {
+		res.Description.Label = &e.Label
+	}
+	if e.Description != "" {
+		res.Description.Description = &e.Description
+	}
+
 	return &res
 }
 
 func (e *EntityImpl) Dump(w io.Writer) {
-	for _, f := range e.Features {
+	for _, f := range e.GetFeatures() {
 		addr := EntityAddressString(e)
 		fmt.Fprintf(w, "    e[%s] f-%d type=%s.%s\n", addr, f.GetID(), f.GetRole(), f.GetType())
 		f.Dump(w)
 	}
-	for _, child := range e.Entities {
+	for _, child := range e.GetEntities() {
 		child.Dump(w)
 	}
 }
@@ -155,6 +267,10 @@ func UnmarshalEntity(
 			e.Description = *eid.Description
 		}
 
+		if eid.Label != nil {
+			e.Label = *eid.Label
+		}
+
 		if ea := eid.EntityAddress; ea != nil {
 			e.Address = ea.Entity
 
@@ -0,0 +1,40 @@
+package spine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+var (
+	// ErrNotConnected is returned if the remote device is not connected or not yet discovered
+	ErrNotConnected = errors.New("charger is not connected")
+	// ErrNoEV is returned if no EV is connected to the remote device
+	ErrNoEV = errors.New("no ev connected")
+	// ErrFeatureNotSupported is returned if a required local or remote feature or function is not available
+	ErrFeatureNotSupported = errors.New("feature not supported")
+	// ErrWriteRejected matches all WriteRejectedError
+	ErrWriteRejected = errors.New("write rejected")
+)
+
+// WriteRejectedError is reported if the remote device answers a write with an error result
+type WriteRejectedError struct {
+	MsgCounter  model.MsgCounterType // msgCounter of the rejected write
+	Function    string               // data name of the written cmds, e.g. loadControlLimitListData
+	ErrorNumber model.ErrorNumberType
+	Description model.DescriptionType
+}
+
+func (e *WriteRejectedError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s: error number %d: %s", ErrWriteRejected, e.Function, e.ErrorNumber, e.Description)
+	}
+
+	return fmt.Sprintf("%s: %s: error number %d", ErrWriteRejected, e.Function, e.ErrorNumber)
+}
+
+// Is makes WriteRejectedError match ErrWriteRejected
+func (e *WriteRejectedError) Is(target error) bool {
+	return target == ErrWriteRejected
+}
@@ -0,0 +1,30 @@
+package spine
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestWriteRejectedError(t *testing.T) {
+	err := fmt.Errorf("write limits: %w", &WriteRejectedError{
+		MsgCounter:  42,
+		Function:    "loadControlLimitListData",
+		ErrorNumber: model.ErrorNumberTypeCommandRejected,
+	})
+
+	if !errors.Is(err, ErrWriteRejected) {
+		t.Error("expected ErrWriteRejected")
+	}
+
+	var writeErr *WriteRejectedError
+	if !errors.As(err, &writeErr) || writeErr.ErrorNumber != model.ErrorNumberTypeCommandRejected {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if errors.Is(err, ErrNotConnected) {
+		t.Error("unexpected ErrNotConnected")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/evcc-io/eebus/spine/model"
 )
@@ -23,16 +25,24 @@ type Feature interface {
 
 	GetType() model.FeatureTypeEnumType
 	GetRole() model.RoleType
+	GetDescription() model.DescriptionType
+	GetMaxResponseDelay() time.Duration
 
 	Add(fun model.FunctionEnumType, r, w bool)
 
 	SupportForFunctionAvailable(fun model.FunctionEnumType) bool
+	FunctionOperations(fun model.FunctionEnumType) (RW, bool)
 
 	Information() *model.NodeManagementDetailedDiscoveryFeatureInformationType
 	Dump(w io.Writer)
 
 	EVDisconnect()
 
+	Data(function model.FunctionEnumType) any
+	SetData(function model.FunctionEnumType, data any)
+	ClearData()
+	AddDataChangeHandler(handler DataChangeHandler)
+
 	HandleRequest(ctrl Context, fct model.FunctionEnumType, op model.CmdClassifierType, rf Feature) (*model.MsgCounterType, error)
 	Handle(ctrl Context, rf model.FeatureAddressType, op model.CmdClassifierType, cmd model.CmdType, isPartialForCmd bool) error
 	HandleResultData(ctrl Context, op model.CmdClassifierType) error
@@ -41,13 +51,22 @@ type Feature interface {
 var _ Feature = (*FeatureImpl)(nil)
 
 type FeatureImpl struct {
-	Entity        Entity
-	ID            uint
-	Type          model.FeatureTypeEnumType
-	Description   model.DescriptionType
-	Role          model.RoleType
-	Functions     map[model.FunctionEnumType]RW
-	Subscriptions []model.SubscriptionManagementEntryDataType
+	Entity           Entity
+	ID               uint
+	Type             model.FeatureTypeEnumType
+	Description      model.DescriptionType
+	Label            model.LabelType
+	Role             model.RoleType
+	SpecificUsage    []model.FeatureSpecificUsageType
+	FeatureGroup     model.FeatureGroupType
+	MaxResponseDelay model.MaxResponseDelayType
+	Functions        map[model.FunctionEnumType]RW
+	Subscriptions    []model.SubscriptionManagementEntryDataType
+	functionsMux     sync.RWMutex
+
+	data         map[model.FunctionEnumType]any
+	dataHandlers []DataChangeHandler
+	dataMux      sync.Mutex
 }
 
 func (f *FeatureImpl) GetAddress() *model.FeatureAddressType {
@@ -78,7 +97,28 @@ func (f *FeatureImpl) GetRole() model.RoleType {
 	return f.Role
 }
 
+func (f *FeatureImpl) GetDescription() model.DescriptionType {
+	return f.Description
+}
+
+// GetMaxResponseDelay returns the announced maximum response delay, 0 if unknown
+func (f *FeatureImpl) GetMaxResponseDelay() time.Duration {
+	if f.MaxResponseDelay == "" {
+		return 0
+	}
+
+	d, err := model.GetISO8601Duration(string(f.MaxResponseDelay))
+	if err != nil {
+		return 0
+	}
+
+	return d
+}
+
 func (f *FeatureImpl) Add(fun model.FunctionEnumType, r, w bool) {
+	f.functionsMux.Lock()
+	defer f.functionsMux.Unlock()
+
 	if f.Functions == nil {
 		f.Functions = make(map[model.FunctionEnumType]RW)
 	}
@@ -90,13 +130,27 @@ func (f *FeatureImpl) Add(fun model.FunctionEnumType, r, w bool) {
 	f.Functions[fun] = RW{r, w}
 }
 
+// SupportForFunctionAvailable checks if the function is supported with read or write operation
 func (f *FeatureImpl) SupportForFunctionAvailable(fun model.FunctionEnumType) bool {
-	_, found := f.Functions[fun]
-	return found
+	f.functionsMux.RLock()
+	defer f.functionsMux.RUnlock()
+
+	rw, found := f.Functions[fun]
+	return found && (rw.Read || rw.Write)
+}
+
+// FunctionOperations returns the possible operations of the function and if it is supported
+func (f *FeatureImpl) FunctionOperations(fun model.FunctionEnumType) (RW, bool) {
+	f.functionsMux.RLock()
+	defer f.functionsMux.RUnlock()
+
+	rw, found := f.Functions[fun]
+	return rw, found
 }
 
 func (f *FeatureImpl) Information() *model.NodeManagementDetailedDiscoveryFeatureInformationType {
 	var funs []model.FunctionPropertyType
+	f.functionsMux.RLock()
 	for fun, rw := range f.Functions {
 		var functionType model.FunctionType = model.FunctionType(fun)
 		sf := model.FunctionPropertyType{
@@ -106,6 +160,7 @@ func (f *FeatureImpl) Information() *model.NodeManagementDetailedDiscoveryFeatur
 
 		funs = append(funs, sf)
 	}
+	f.functionsMux.RUnlock()
 
 	var featureType model.FeatureTypeType = model.FeatureTypeType(f.Type)
 	var featureRole model.RoleType = model.RoleType(f.Role)
@@ -114,15 +169,32 @@ func (f *FeatureImpl) Information() *model.NodeManagementDetailedDiscoveryFeatur
 		Description: &model.NetworkManagementFeatureDescriptionDataType{
 			FeatureAddress:    FeatureAddressType(f),
 			FeatureType:       &featureType,
+			SpecificUsage:     f.SpecificUsage,
 			Role:              &featureRole,
 			SupportedFunction: funs,
 		},
 	}
 
+	if f.FeatureGroup != "" {
+		res.Description.FeatureGroup = &f.FeatureGroup
+	}
+	if f.Label != "" {
+		res.Description.Label = &f.Label
+	}
+	if f.Description != "" {
+		res.Description.Description = &f.Description
+	}
+	if f.MaxResponseDelay != "" {
+		res.Description.MaxResponseDelay = &f.MaxResponseDelay
+	}
+
 	return &res
 }
 
 func (f *FeatureImpl) Dump(w io.Writer) {
+	f.functionsMux.RLock()
+	defer f.functionsMux.RUnlock()
+
 	for fun, ops := range f.Functions {
 		fmt.Fprintf(w, "      {%s} %s\n", ops, fun)
 	}
@@ -158,23 +230,53 @@ func UnmarshalFeature(
 
 	if fid := featureData.Description; fid != nil {
 		f = &FeatureImpl{
-			Type: model.FeatureTypeEnumType(*fid.FeatureType),
+			SpecificUsage: fid.SpecificUsage,
+		}
+
+		if fid.FeatureType != nil {
+			f.Type = model.FeatureTypeEnumType(*fid.FeatureType)
 		}
 
 		if fid.Description != nil {
 			f.Description = *fid.Description
 		}
 
+		if fid.Label != nil {
+			f.Label = *fid.Label
+		}
+
 		if fid.Role != nil {
 			f.Role = *fid.Role
 		}
 
-		if addr := fid.FeatureAddress; addr != nil {
+		if fid.FeatureGroup != nil {
+			f.FeatureGroup = *fid.FeatureGroup
+		}
+
+		if fid.MaxResponseDelay != nil {
+			f.MaxResponseDelay = *fid.MaxResponseDelay
+		}
+
+		if addr := fid.FeatureAddress; addr != nil && addr.Feature != nil {
 			f.ID = uint(*addr.Feature)
 		}
 
+		// remote functions are stored regardless of role
 		for _, sf := range fid.SupportedFunction {
-			f.Add(model.FunctionEnumType(*sf.Function), sf.PossibleOperations.Read != nil, sf.PossibleOperations.Write != nil)
+			if sf.Function == nil {
+				continue
+			}
+
+			if f.Functions == nil {
+				f.Functions = make(map[model.FunctionEnumType]RW)
+			}
+
+			var rw RW
+			if ops := sf.PossibleOperations; ops != nil {
+				rw = RW{Read: ops.Read != nil, Write: ops.Write != nil}
+			}
+
+			f.Functions[model.FunctionEnumType(*sf.Function)] = rw
 		}
 	}
 
@@ -0,0 +1,66 @@
+package spine
+
+import (
+	"reflect"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// DataChangeHandler is called when the cached data of a feature function has changed
+type DataChangeHandler func(function model.FunctionEnumType)
+
+// Data returns the cached data of the function, nil if not available
+func (f *FeatureImpl) Data(function model.FunctionEnumType) any {
+	f.dataMux.Lock()
+	defer f.dataMux.Unlock()
+
+	return f.data[function]
+}
+
+// SetData caches the data of the function and notifies the change handlers if the data has changed.
+// Data is stored as pointer to the model data type, e.g. *model.MeasurementListDataType.
+// The data is shared with concurrent readers and must not be modified after it has been set.
+func (f *FeatureImpl) SetData(function model.FunctionEnumType, data any) {
+	f.dataMux.Lock()
+
+	if f.data == nil {
+		f.data = make(map[model.FunctionEnumType]any)
+	}
+
+	changed := !reflect.DeepEqual(f.data[function], data)
+	f.data[function] = data
+	handlers := f.dataHandlers
+
+	f.dataMux.Unlock()
+
+	if changed {
+		for _, handler := range handlers {
+			handler(function)
+		}
+	}
+}
+
+// ClearData removes all cached data
+func (f *FeatureImpl) ClearData() {
+	f.dataMux.Lock()
+	defer f.dataMux.Unlock()
+
+	f.data = nil
+}
+
+// AddDataChangeHandler registers a handler for changes of the cached data
+func (f *FeatureImpl) AddDataChangeHandler(handler DataChangeHandler) {
+	f.dataMux.Lock()
+	defer f.dataMux.Unlock()
+
+	f.dataHandlers = append(f.dataHandlers, handler)
+}
+
+// FeatureData returns the typed cached data of the feature's function, nil if not available
+func FeatureData[T any](f Feature, function model.FunctionEnumType) *T {
+	if data, ok := f.Data(function).(*T); ok {
+		return data
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package spine
+
+import (
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestFeatureData(t *testing.T) {
+	f := &FeatureImpl{}
+
+	var changes []model.FunctionEnumType
+	f.AddDataChangeHandler(func(function model.FunctionEnumType) {
+		changes = append(changes, function)
+	})
+
+	if data := FeatureData[model.LoadControlLimitListDataType](f, model.FunctionEnumTypeLoadControlLimitListData); data != nil {
+		t.Errorf("unexpected data: %v", data)
+	}
+
+	f.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{
+		LoadControlLimitData: []model.LoadControlLimitDataType{limitData(1, true, 16)},
+	})
+
+	data := FeatureData[model.LoadControlLimitListDataType](f, model.FunctionEnumTypeLoadControlLimitListData)
+	if data == nil || len(data.LoadControlLimitData) != 1 {
+		t.Fatalf("unexpected data: %v", data)
+	}
+
+	// unchanged data must not be notified
+	f.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{
+		LoadControlLimitData: []model.LoadControlLimitDataType{limitData(1, true, 16)},
+	})
+
+	if len(changes) != 1 || changes[0] != model.FunctionEnumTypeLoadControlLimitListData {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	// wrong type
+	if data := FeatureData[model.MeasurementListDataType](f, model.FunctionEnumTypeLoadControlLimitListData); data != nil {
+		t.Errorf("unexpected data: %v", data)
+	}
+
+	f.ClearData()
+	if data := f.Data(model.FunctionEnumTypeLoadControlLimitListData); data != nil {
+		t.Errorf("unexpected data: %v", data)
+	}
+}
@@ -0,0 +1,72 @@
+package spine
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// Store persists data so that it is available across restarts
+type Store[T any] interface {
+	Load() (T, error)
+	Save(T) error
+}
+
+// NewFileStore returns a store persisting the data as JSON file
+func NewFileStore[T any](path string) Store[T] {
+	return &fileStore[T]{path: path}
+}
+
+// fileStore persists data as JSON file
+type fileStore[T any] struct {
+	path string
+}
+
+// Load returns the zero value if the file does not exist
+func (s *fileStore[T]) Load() (T, error) {
+	var res T
+
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return res, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(b, &res)
+	}
+
+	return res, err
+}
+
+func (s *fileStore[T]) Save(data T) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.path, b, 0o600)
+	}
+
+	return err
+}
+
+// SetStoreErrorHandler sets the handler called if the address map or the counters cannot be saved.
+// Allocated addresses and counters remain valid for the running process.
+func (d *DeviceImpl) SetStoreErrorHandler(handler func(error)) {
+	d.storeMux.Lock()
+	defer d.storeMux.Unlock()
+
+	d.storeErrHandler = handler
+}
+
+// storeError passes a failure to save to the error handler.
+// It must not be called while holding the device lock.
+func (d *DeviceImpl) storeError(err error) {
+	if err == nil {
+		return
+	}
+
+	d.storeMux.Lock()
+	handler := d.storeErrHandler
+	d.storeMux.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,44 @@
+package spine
+
+import "sync"
+
+// handlerList is a concurrency-safe list of handlers which can be removed individually
+type handlerList[T any] struct {
+	mux      sync.Mutex
+	handlers map[int]T
+	nextId   int
+}
+
+// add registers the handler and returns a function removing it
+func (h *handlerList[T]) add(handler T) func() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if h.handlers == nil {
+		h.handlers = make(map[int]T)
+	}
+
+	id := h.nextId
+	h.nextId++
+	h.handlers[id] = handler
+
+	return func() {
+		h.mux.Lock()
+		defer h.mux.Unlock()
+
+		delete(h.handlers, id)
+	}
+}
+
+// list returns a snapshot of the registered handlers
+func (h *handlerList[T]) list() []T {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	res := make([]T, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		res = append(res, handler)
+	}
+
+	return res
+}
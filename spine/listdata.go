@@ -0,0 +1,225 @@
+package spine
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// UpdateList applies received list data to the existing list according to the cmd's filters.
+// Without partial and delete filters the received data replaces the existing list.
+// A delete filter is applied first:
+//   - with selectors all matching items are removed
+//   - with elements the given elements of all (matching) items are cleared
+//   - without selectors and elements the list is cleared
+//
+// The received data is then merged into the remaining items: items with the same
+// identifiers are updated with the received non-empty elements, others are appended.
+func UpdateList[T any](existing, newData []T, filterPartial, filterDelete *model.FilterType) []T {
+	if filterPartial == nil && filterDelete == nil {
+		return append([]T{}, newData...)
+	}
+
+	result := append([]T{}, existing...)
+
+	if filterDelete != nil {
+		result = deleteListItems(result, filterDelete)
+	}
+
+	for _, item := range newData {
+		if index := indexOfListItem(result, item); index >= 0 {
+			mergeListItem(&result[index], item)
+		} else {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// FilterList returns the items of the list matching the filter's selectors
+// restricted to the filter's elements
+func FilterList[T any](data []T, filter *model.FilterType) []T {
+	if filter == nil {
+		return data
+	}
+
+	selectors := filterField[T](filter, "ListDataSelectors")
+	elements := filterField[T](filter, "DataElements")
+
+	var result []T
+	for _, item := range data {
+		if selectors.IsValid() && !matchesSelectors(item, selectors) {
+			continue
+		}
+
+		if elements.IsValid() {
+			item = restrictElements(item, elements)
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+func deleteListItems[T any](data []T, filter *model.FilterType) []T {
+	selectors := filterField[T](filter, "ListDataSelectors")
+	elements := filterField[T](filter, "DataElements")
+
+	if !selectors.IsValid() && !elements.IsValid() {
+		return nil
+	}
+
+	var result []T
+	for _, item := range data {
+		if selectors.IsValid() && !matchesSelectors(item, selectors) {
+			result = append(result, item)
+			continue
+		}
+
+		if elements.IsValid() {
+			result = append(result, clearElements(item, elements))
+		}
+	}
+
+	return result
+}
+
+// filterField returns the filter's selectors or elements for list items of type T
+func filterField[T any](filter *model.FilterType, suffix string) reflect.Value {
+	name := strings.TrimSuffix(reflect.TypeOf(*new(T)).Name(), "DataType") + suffix
+
+	field := reflect.ValueOf(filter).Elem().FieldByName(name)
+	if !field.IsValid() || field.IsNil() {
+		return reflect.Value{}
+	}
+
+	return field.Elem()
+}
+
+// matchesSelectors checks if all set selector fields are equal to the item's fields
+func matchesSelectors(item any, selectors reflect.Value) bool {
+	itemValue := reflect.ValueOf(item)
+
+	for i := 0; i < selectors.NumField(); i++ {
+		selector := selectors.Field(i)
+		if selector.IsNil() {
+			continue
+		}
+
+		// selectors without a corresponding item field (e.g. intervals) are not supported
+		field := itemValue.FieldByName(selectors.Type().Field(i).Name)
+		if !field.IsValid() || field.Type() != selector.Type() || field.IsNil() {
+			return false
+		}
+
+		if !reflect.DeepEqual(field.Elem().Interface(), selector.Elem().Interface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// clearElements returns a copy of the item with the tagged elements removed
+func clearElements[T any](item T, elements reflect.Value) T {
+	itemValue := reflect.ValueOf(&item).Elem()
+
+	for i := 0; i < elements.NumField(); i++ {
+		if elements.Field(i).IsNil() {
+			continue
+		}
+
+		name := elements.Type().Field(i).Name
+		if isListKey(itemValue.Type(), name) {
+			continue
+		}
+
+		if field := itemValue.FieldByName(name); field.IsValid() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+
+	return item
+}
+
+// restrictElements returns a copy of the item containing only the identifiers and the tagged elements
+func restrictElements[T any](item T, elements reflect.Value) T {
+	var result T
+	resultValue := reflect.ValueOf(&result).Elem()
+	itemValue := reflect.ValueOf(item)
+
+	for i := 0; i < itemValue.NumField(); i++ {
+		name := itemValue.Type().Field(i).Name
+
+		if element := elements.FieldByName(name); isListKey(itemValue.Type(), name) || element.IsValid() && !element.IsNil() {
+			resultValue.Field(i).Set(itemValue.Field(i))
+		}
+	}
+
+	return result
+}
+
+func isListKey(typ reflect.Type, name string) bool {
+	for _, key := range listDataKeys[typ] {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// indexOfListItem returns the index of the item with the same identifiers, -1 if not found
+func indexOfListItem[T any](data []T, item T) int {
+	keys := listDataKeys[reflect.TypeOf(item)]
+	if len(keys) == 0 {
+		return -1
+	}
+
+	itemValue := reflect.ValueOf(item)
+	for _, key := range keys {
+		if itemValue.FieldByName(key).IsNil() {
+			return -1
+		}
+	}
+
+	for i := range data {
+		value := reflect.ValueOf(data[i])
+
+		match := true
+		for _, key := range keys {
+			field := value.FieldByName(key)
+			if field.IsNil() || !reflect.DeepEqual(field.Elem().Interface(), itemValue.FieldByName(key).Elem().Interface()) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// mergeListItem updates the existing item with all non-empty fields of the item
+func mergeListItem[T any](existing *T, item T) {
+	existingValue := reflect.ValueOf(existing).Elem()
+	itemValue := reflect.ValueOf(item)
+
+	for i := 0; i < itemValue.NumField(); i++ {
+		field := itemValue.Field(i)
+
+		switch field.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			if field.IsNil() {
+				continue
+			}
+		}
+
+		existingValue.Field(i).Set(field)
+	}
+}
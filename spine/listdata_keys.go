@@ -0,0 +1,157 @@
+package spine
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// listDataSelectors contains the list data types with their selectors type
+var listDataSelectors = [][2]any{
+	{model.BindingManagementEntryListDataType{}, model.BindingManagementEntryListDataSelectorsType{}},
+	{model.DeviceConfigurationKeyValueListDataType{}, model.DeviceConfigurationKeyValueListDataSelectorsType{}},
+	{model.DeviceConfigurationKeyValueDescriptionListDataType{}, model.DeviceConfigurationKeyValueDescriptionListDataSelectorsType{}},
+	{model.DeviceConfigurationKeyValueConstraintsListDataType{}, model.DeviceConfigurationKeyValueConstraintsListDataSelectorsType{}},
+	{model.ElectricalConnectionParameterDescriptionListDataType{}, model.ElectricalConnectionParameterDescriptionListDataSelectorsType{}},
+	{model.ElectricalConnectionPermittedValueSetListDataType{}, model.ElectricalConnectionPermittedValueSetListDataSelectorsType{}},
+	{model.ElectricalConnectionStateListDataType{}, model.ElectricalConnectionStateListDataSelectorsType{}},
+	{model.ElectricalConnectionDescriptionListDataType{}, model.ElectricalConnectionDescriptionListDataSelectorsType{}},
+	{model.IdentificationListDataType{}, model.IdentificationListDataSelectorsType{}},
+	{model.LoadControlEventListDataType{}, model.LoadControlEventListDataSelectorsType{}},
+	{model.LoadControlStateListDataType{}, model.LoadControlStateListDataSelectorsType{}},
+	{model.LoadControlLimitListDataType{}, model.LoadControlLimitListDataSelectorsType{}},
+	{model.LoadControlLimitConstraintsListDataType{}, model.LoadControlLimitConstraintsListDataSelectorsType{}},
+	{model.LoadControlLimitDescriptionListDataType{}, model.LoadControlLimitDescriptionListDataSelectorsType{}},
+	{model.MeasurementListDataType{}, model.MeasurementListDataSelectorsType{}},
+	{model.MeasurementConstraintsListDataType{}, model.MeasurementConstraintsListDataSelectorsType{}},
+	{model.MeasurementDescriptionListDataType{}, model.MeasurementDescriptionListDataSelectorsType{}},
+	{model.MeasurementThresholdRelationListDataType{}, model.MeasurementThresholdRelationListDataSelectorsType{}},
+	{model.AlarmListDataType{}, model.AlarmListDataSelectorsType{}},
+	{model.BillListDataType{}, model.BillListDataSelectorsType{}},
+	{model.BillConstraintsListDataType{}, model.BillConstraintsListDataSelectorsType{}},
+	{model.BillDescriptionListDataType{}, model.BillDescriptionListDataSelectorsType{}},
+	{model.DirectControlActivityListDataType{}, model.DirectControlActivityListDataSelectorsType{}},
+	{model.HvacSystemFunctionListDataType{}, model.HvacSystemFunctionListDataSelectorsType{}},
+	{model.HvacSystemFunctionOperationModeRelationListDataType{}, model.HvacSystemFunctionOperationModeRelationListDataSelectorsType{}},
+	{model.HvacSystemFunctionSetpointRelationListDataType{}, model.HvacSystemFunctionSetpointRelationListDataSelectorsType{}},
+	{model.HvacSystemFunctionPowerSequenceRelationListDataType{}, model.HvacSystemFunctionPowerSequenceRelationListDataSelectorsType{}},
+	{model.HvacSystemFunctionDescriptionListDataType{}, model.HvacSystemFunctionDescriptionListDataSelectorsType{}},
+	{model.HvacOperationModeDescriptionListDataType{}, model.HvacOperationModeDescriptionListDataSelectorsType{}},
+	{model.HvacOverrunListDataType{}, model.HvacOverrunListDataSelectorsType{}},
+	{model.HvacOverrunDescriptionListDataType{}, model.HvacOverrunDescriptionListDataSelectorsType{}},
+	{model.MessagingListDataType{}, model.MessagingListDataSelectorsType{}},
+	{model.OperatingConstraintsInterruptListDataType{}, model.OperatingConstraintsInterruptListDataSelectorsType{}},
+	{model.OperatingConstraintsDurationListDataType{}, model.OperatingConstraintsDurationListDataSelectorsType{}},
+	{model.OperatingConstraintsPowerDescriptionListDataType{}, model.OperatingConstraintsPowerDescriptionListDataSelectorsType{}},
+	{model.OperatingConstraintsPowerRangeListDataType{}, model.OperatingConstraintsPowerRangeListDataSelectorsType{}},
+	{model.OperatingConstraintsPowerLevelListDataType{}, model.OperatingConstraintsPowerLevelListDataSelectorsType{}},
+	{model.OperatingConstraintsResumeImplicationListDataType{}, model.OperatingConstraintsResumeImplicationListDataSelectorsType{}},
+	{model.PowerTimeSlotScheduleListDataType{}, model.PowerTimeSlotScheduleListDataSelectorsType{}},
+	{model.PowerTimeSlotValueListDataType{}, model.PowerTimeSlotValueListDataSelectorsType{}},
+	{model.PowerTimeSlotScheduleConstraintsListDataType{}, model.PowerTimeSlotScheduleConstraintsListDataSelectorsType{}},
+	{model.PowerSequenceAlternativesRelationListDataType{}, model.PowerSequenceAlternativesRelationListDataSelectorsType{}},
+	{model.PowerSequenceDescriptionListDataType{}, model.PowerSequenceDescriptionListDataSelectorsType{}},
+	{model.PowerSequenceStateListDataType{}, model.PowerSequenceStateListDataSelectorsType{}},
+	{model.PowerSequenceScheduleListDataType{}, model.PowerSequenceScheduleListDataSelectorsType{}},
+	{model.PowerSequenceScheduleConstraintsListDataType{}, model.PowerSequenceScheduleConstraintsListDataSelectorsType{}},
+	{model.PowerSequencePriceListDataType{}, model.PowerSequencePriceListDataSelectorsType{}},
+	{model.PowerSequenceSchedulePreferenceListDataType{}, model.PowerSequenceSchedulePreferenceListDataSelectorsType{}},
+	{model.SensingListDataType{}, model.SensingListDataSelectorsType{}},
+	{model.SetpointListDataType{}, model.SetpointListDataSelectorsType{}},
+	{model.SetpointConstraintsListDataType{}, model.SetpointConstraintsListDataSelectorsType{}},
+	{model.SetpointDescriptionListDataType{}, model.SetpointDescriptionListDataSelectorsType{}},
+	{model.SupplyConditionListDataType{}, model.SupplyConditionListDataSelectorsType{}},
+	{model.SupplyConditionDescriptionListDataType{}, model.SupplyConditionDescriptionListDataSelectorsType{}},
+	{model.SupplyConditionThresholdRelationListDataType{}, model.SupplyConditionThresholdRelationListDataSelectorsType{}},
+	{model.TaskManagementJobListDataType{}, model.TaskManagementJobListDataSelectorsType{}},
+	{model.TaskManagementJobRelationListDataType{}, model.TaskManagementJobRelationListDataSelectorsType{}},
+	{model.TaskManagementJobDescriptionListDataType{}, model.TaskManagementJobDescriptionListDataSelectorsType{}},
+	{model.ThresholdListDataType{}, model.ThresholdListDataSelectorsType{}},
+	{model.ThresholdConstraintsListDataType{}, model.ThresholdConstraintsListDataSelectorsType{}},
+	{model.ThresholdDescriptionListDataType{}, model.ThresholdDescriptionListDataSelectorsType{}},
+	{model.NetworkManagementDeviceDescriptionListDataType{}, model.NetworkManagementDeviceDescriptionListDataSelectorsType{}},
+	{model.NetworkManagementEntityDescriptionListDataType{}, model.NetworkManagementEntityDescriptionListDataSelectorsType{}},
+	{model.NetworkManagementFeatureDescriptionListDataType{}, model.NetworkManagementFeatureDescriptionListDataSelectorsType{}},
+	{model.NodeManagementDestinationListDataType{}, model.NodeManagementDestinationListDataSelectorsType{}},
+	{model.SubscriptionManagementEntryListDataType{}, model.SubscriptionManagementEntryListDataSelectorsType{}},
+	{model.TariffListDataType{}, model.TariffListDataSelectorsType{}},
+	{model.TariffTierRelationListDataType{}, model.TariffTierRelationListDataSelectorsType{}},
+	{model.TariffBoundaryRelationListDataType{}, model.TariffBoundaryRelationListDataSelectorsType{}},
+	{model.TariffDescriptionListDataType{}, model.TariffDescriptionListDataSelectorsType{}},
+	{model.TierBoundaryListDataType{}, model.TierBoundaryListDataSelectorsType{}},
+	{model.TierBoundaryDescriptionListDataType{}, model.TierBoundaryDescriptionListDataSelectorsType{}},
+	{model.CommodityListDataType{}, model.CommodityListDataSelectorsType{}},
+	{model.TierListDataType{}, model.TierListDataSelectorsType{}},
+	{model.TierIncentiveRelationListDataType{}, model.TierIncentiveRelationListDataSelectorsType{}},
+	{model.TierDescriptionListDataType{}, model.TierDescriptionListDataSelectorsType{}},
+	{model.IncentiveListDataType{}, model.IncentiveListDataSelectorsType{}},
+	{model.IncentiveDescriptionListDataType{}, model.IncentiveDescriptionListDataSelectorsType{}},
+	{model.TimeSeriesListDataType{}, model.TimeSeriesListDataSelectorsType{}},
+	{model.TimeSeriesDescriptionListDataType{}, model.TimeSeriesDescriptionListDataSelectorsType{}},
+	{model.TimeSeriesConstraintsListDataType{}, model.TimeSeriesConstraintsListDataSelectorsType{}},
+	{model.TimeTableListDataType{}, model.TimeTableListDataSelectorsType{}},
+	{model.TimeTableConstraintsListDataType{}, model.TimeTableConstraintsListDataSelectorsType{}},
+	{model.TimeTableDescriptionListDataType{}, model.TimeTableDescriptionListDataSelectorsType{}},
+	{model.UseCaseInformationListDataType{}, model.UseCaseInformationListDataSelectorsType{}},
+	{model.SpecificationVersionListDataType{}, model.SpecificationVersionListDataSelectorsType{}},
+}
+
+// listDataKeys contains the identifier fields of list data items derived from the selectors of their list
+var listDataKeys = make(map[reflect.Type][]string)
+
+func init() {
+	for _, pair := range listDataSelectors {
+		item := reflect.TypeOf(pair[0]).Field(0).Type.Elem()
+		listDataKeys[item] = selectorKeys(item, reflect.TypeOf(pair[1]))
+	}
+}
+
+// selectorKeys returns the selector fields identifying an item of the list. These are the id fields
+// of the item's function, ids referencing other functions (e.g. the measurementId of a limit) are not
+// part of the key. If the selectors only contain such references the first id is used, without ids
+// all selectors contained in the item identify it.
+func selectorKeys(item, selectors reflect.Type) []string {
+	var fields, ids, keys []string
+
+	for i := 0; i < selectors.NumField(); i++ {
+		selector := selectors.Field(i)
+		if selector.Type.Kind() != reflect.Pointer {
+			continue
+		}
+
+		// some generated items use the underlying type of the selector
+		if field, ok := item.FieldByName(selector.Name); !ok || field.Type.Kind() != reflect.Pointer || field.Type.Elem().Kind() != selector.Type.Elem().Kind() {
+			continue
+		}
+		fields = append(fields, selector.Name)
+
+		if !strings.HasSuffix(selector.Name, "Id") && !strings.HasSuffix(selector.Name, "Number") {
+			continue
+		}
+		ids = append(ids, selector.Name)
+
+		if firstWord(selector.Type.Elem().Name()) == firstWord(item.Name()) {
+			keys = append(keys, selector.Name)
+		}
+	}
+
+	switch {
+	case len(keys) > 0:
+		return keys
+	case len(ids) > 0:
+		return ids[:1]
+	default:
+		return fields
+	}
+}
+
+// firstWord returns the first word of a camel case type name
+func firstWord(name string) string {
+	for i := 1; i < len(name); i++ {
+		if name[i] >= 'A' && name[i] <= 'Z' {
+			return name[:i]
+		}
+	}
+	return name
+}
@@ -0,0 +1,163 @@
+package spine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func limitData(id uint, active bool, value float64) model.LoadControlLimitDataType {
+	limitId := model.LoadControlLimitIdType(id)
+	return model.LoadControlLimitDataType{
+		LimitId:       &limitId,
+		IsLimitActive: &active,
+		Value:         model.NewScaledNumberType(value),
+	}
+}
+
+func TestUpdateListReplace(t *testing.T) {
+	existing := []model.LoadControlLimitDataType{limitData(1, true, 16), limitData(2, true, 16)}
+	newData := []model.LoadControlLimitDataType{limitData(3, false, 6)}
+
+	result := UpdateList(existing, newData, nil, nil)
+	if len(result) != 1 || *result[0].LimitId != 3 {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestUpdateListPartial(t *testing.T) {
+	existing := []model.LoadControlLimitDataType{limitData(1, true, 16), limitData(2, true, 16)}
+
+	limitId := model.LoadControlLimitIdType(2)
+	newData := []model.LoadControlLimitDataType{
+		{LimitId: &limitId, Value: model.NewScaledNumberType(10)},
+		limitData(3, false, 6),
+	}
+
+	result := UpdateList(existing, newData, model.NewFilterTypePartial(), nil)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(result))
+	}
+	if result[1].Value.GetValue() != 10 || result[1].IsLimitActive == nil || !*result[1].IsLimitActive {
+		t.Errorf("partial item not merged: %v", result[1])
+	}
+	if *result[2].LimitId != 3 {
+		t.Errorf("partial item not added: %v", result[2])
+	}
+	if existing[1].Value.GetValue() != 16 {
+		t.Errorf("existing list modified")
+	}
+}
+
+func TestUpdateListPartialKeys(t *testing.T) {
+	incentive := func(id uint, timeTableId uint, value float64) model.IncentiveDataType {
+		incentiveId := model.IncentiveIdType(id)
+		timeTable := model.TimeTableIdType(timeTableId)
+		return model.IncentiveDataType{IncentiveId: &incentiveId, TimeTableId: &timeTable, Value: model.NewScaledNumberType(value)}
+	}
+
+	// the referenced time table is not part of the incentive's identifiers
+	existing := []model.IncentiveDataType{incentive(1, 1, 10), incentive(2, 1, 20)}
+	newData := []model.IncentiveDataType{incentive(2, 2, 25)}
+
+	result := UpdateList(existing, newData, model.NewFilterTypePartial(), nil)
+	if len(result) != 2 || result[1].Value.GetValue() != 25 || *result[1].TimeTableId != 2 {
+		t.Errorf("partial item not merged: %v", result)
+	}
+}
+
+func TestListDataKeys(t *testing.T) {
+	for _, tc := range []struct {
+		typ  any
+		keys []string
+	}{
+		{model.LoadControlLimitDescriptionDataType{}, []string{"LimitId"}},
+		{model.ElectricalConnectionPermittedValueSetDataType{}, []string{"ElectricalConnectionId", "ParameterId"}},
+		{model.TimeTableDescriptionDataType{}, []string{"TimeTableId"}},
+		{model.SupplyConditionThresholdRelationDataType{}, []string{"ConditionId"}},
+		{model.PowerTimeSlotValueDataType{}, []string{"SequenceId", "SlotNumber"}},
+		{model.TierDataType{}, []string{"TierId"}},
+	} {
+		if res := listDataKeys[reflect.TypeOf(tc.typ)]; !reflect.DeepEqual(res, tc.keys) {
+			t.Errorf("%T: expected keys %v, got %v", tc.typ, tc.keys, res)
+		}
+	}
+}
+
+func TestUpdateListDeleteAndPartial(t *testing.T) {
+	var cmd model.CmdType
+	if err := json.Unmarshal([]byte(`[
+		{"function":"electricalConnectionPermittedValueSetListData"},
+		{"filter":[[{"cmdControl":[{"delete":[]}]},{"electricalConnectionPermittedValueSetListDataSelectors":[{"electricalConnectionId":0},{"parameterId":1}]}],[{"cmdControl":[{"partial":[]}]}]]},
+		{"electricalConnectionPermittedValueSetListData":[{"electricalConnectionPermittedValueSetData":[[{"electricalConnectionId":0},{"parameterId":2},{"permittedValueSet":[[{"range":[[{"min":[{"number":2},{"scale":0}]},{"max":[{"number":16},{"scale":0}]}]]}]]}]]}]}
+	]`), &cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	filterPartial, filterDelete := cmd.ExtractFilter()
+	if filterPartial == nil || filterDelete == nil {
+		t.Fatal("filters not extracted")
+	}
+
+	ecId := model.ElectricalConnectionIdType(0)
+	param1 := model.ElectricalConnectionParameterIdType(1)
+	param2 := model.ElectricalConnectionParameterIdType(2)
+	param3 := model.ElectricalConnectionParameterIdType(3)
+	existing := []model.ElectricalConnectionPermittedValueSetDataType{
+		{ElectricalConnectionId: &ecId, ParameterId: &param1},
+		{ElectricalConnectionId: &ecId, ParameterId: &param2},
+		{ElectricalConnectionId: &ecId, ParameterId: &param3},
+	}
+
+	result := UpdateList(existing, cmd.ElectricalConnectionPermittedValueSetListData.ElectricalConnectionPermittedValueSetData, filterPartial, filterDelete)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(result))
+	}
+	if *result[0].ParameterId != 2 || len(result[0].PermittedValueSet) != 1 {
+		t.Errorf("item not updated: %v", result[0])
+	}
+	if *result[1].ParameterId != 3 {
+		t.Errorf("unexpected item: %v", result[1])
+	}
+}
+
+func TestUpdateListDeleteElements(t *testing.T) {
+	limitId := model.LoadControlLimitIdType(1)
+	filterDelete := model.NewFilterTypeDelete()
+	filterDelete.LoadControlLimitListDataSelectors = &model.LoadControlLimitListDataSelectorsType{LimitId: &limitId}
+	filterDelete.LoadControlLimitDataElements = &model.LoadControlLimitDataElementsType{Value: &model.ElementTagType{}}
+
+	existing := []model.LoadControlLimitDataType{limitData(1, true, 16), limitData(2, true, 16)}
+
+	result := UpdateList(existing, nil, nil, filterDelete)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(result))
+	}
+	if result[0].Value != nil || result[0].LimitId == nil || result[0].IsLimitActive == nil {
+		t.Errorf("element not deleted: %v", result[0])
+	}
+	if result[1].Value == nil {
+		t.Errorf("unselected element deleted: %v", result[1])
+	}
+
+	if result := UpdateList(existing, nil, nil, model.NewFilterTypeDelete()); len(result) != 0 {
+		t.Errorf("expected empty list, got %v", result)
+	}
+}
+
+func TestFilterList(t *testing.T) {
+	limitId := model.LoadControlLimitIdType(2)
+	filter := &model.FilterType{
+		LoadControlLimitListDataSelectors: &model.LoadControlLimitListDataSelectorsType{LimitId: &limitId},
+		LoadControlLimitDataElements:      &model.LoadControlLimitDataElementsType{Value: &model.ElementTagType{}},
+	}
+
+	data := []model.LoadControlLimitDataType{limitData(1, true, 16), limitData(2, true, 10)}
+
+	result := FilterList(data, filter)
+	if len(result) != 1 || *result[0].LimitId != 2 || result[0].Value.GetValue() != 10 || result[0].IsLimitActive != nil {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
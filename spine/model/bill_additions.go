@@ -0,0 +1,180 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// The bill types replace the generated types, which use string ids and empty position types.
+
+// BillValueType complex type
+type BillValueType struct {
+	ValueId         *BillValueIdType  `json:"valueId,omitempty"`
+	Unit            string            `json:"unit,omitempty"`
+	Value           *ScaledNumberType `json:"value,omitempty"`
+	ValuePercentage *ScaledNumberType `json:"valuePercentage,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillValueType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillValueType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillCostType complex type
+type BillCostType struct {
+	CostId         *BillCostIdType   `json:"costId,omitempty"`
+	CostType       string            `json:"costType,omitempty"`
+	ValueId        *BillValueIdType  `json:"valueId,omitempty"`
+	Unit           string            `json:"unit,omitempty"`
+	Currency       string            `json:"currency,omitempty"`
+	Cost           *ScaledNumberType `json:"cost,omitempty"`
+	CostPercentage *ScaledNumberType `json:"costPercentage,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillCostType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillCostType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillPositionType complex type
+type BillPositionType struct {
+	PositionId   *BillPositionIdType `json:"positionId,omitempty"`
+	PositionType string              `json:"positionType,omitempty"`
+	TimePeriod   *TimePeriodType     `json:"timePeriod,omitempty"`
+	Value        []BillValueType     `json:"value,omitempty"`
+	Cost         []BillCostType      `json:"cost,omitempty"`
+	Label        *LabelType          `json:"label,omitempty"`
+	Description  *DescriptionType    `json:"description,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillPositionType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillPositionType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillDataType complex type
+type BillDataType struct {
+	BillId    *BillIdType        `json:"billId,omitempty"`
+	BillType  string             `json:"billType,omitempty"`
+	ScopeType string             `json:"scopeType,omitempty"`
+	Total     *BillPositionType  `json:"total,omitempty"`
+	Position  []BillPositionType `json:"position,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillDataType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillDataType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillListDataSelectorsType complex type
+type BillListDataSelectorsType struct {
+	BillId    *BillIdType `json:"billId,omitempty"`
+	ScopeType string      `json:"scopeType,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillListDataSelectorsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillListDataSelectorsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillConstraintsDataType complex type
+type BillConstraintsDataType struct {
+	BillId           *BillIdType            `json:"billId,omitempty"`
+	PositionCountMin *BillPositionCountType `json:"positionCountMin,omitempty"`
+	PositionCountMax *BillPositionCountType `json:"positionCountMax,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillConstraintsDataType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillConstraintsDataType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillConstraintsListDataSelectorsType complex type
+type BillConstraintsListDataSelectorsType struct {
+	BillId *BillIdType `json:"billId,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillConstraintsListDataSelectorsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillConstraintsListDataSelectorsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillDescriptionDataType complex type
+type BillDescriptionDataType struct {
+	BillId            *BillIdType `json:"billId,omitempty"`
+	BillWriteable     *bool       `json:"billWriteable,omitempty"`
+	UpdateRequired    *bool       `json:"updateRequired,omitempty"`
+	SupportedBillType []string    `json:"supportedBillType,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillDescriptionDataType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillDescriptionDataType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillDescriptionListDataSelectorsType complex type
+type BillDescriptionListDataSelectorsType struct {
+	BillId *BillIdType `json:"billId,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m BillDescriptionListDataSelectorsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *BillDescriptionListDataSelectorsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// BillIdType type
+type BillIdType uint
+
+// BillPositionIdType type
+type BillPositionIdType uint
+
+// BillPositionCountType type
+type BillPositionCountType BillPositionIdType
+
+// BillValueIdType type
+type BillValueIdType uint
+
+// BillCostIdType type
+type BillCostIdType uint
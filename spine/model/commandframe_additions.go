@@ -0,0 +1,154 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/evcc-io/eebus/util"
+)
+
+// FilterType complex type. It replaces the generated type, which only contains the selectors of a few
+// list functions, with the selectors and elements of the list functions read and written partially.
+type FilterType struct {
+	FilterId   *FilterIdType   `json:"filterId,omitempty"`
+	CmdControl *CmdControlType `json:"cmdControl,omitempty"`
+
+	// DataSelectorsChoiceGroup
+	DeviceConfigurationKeyValueListDataSelectors              *DeviceConfigurationKeyValueListDataSelectorsType              `json:"deviceConfigurationKeyValueListDataSelectors,omitempty"`
+	DeviceConfigurationKeyValueDescriptionListDataSelectors   *DeviceConfigurationKeyValueDescriptionListDataSelectorsType   `json:"deviceConfigurationKeyValueDescriptionListDataSelectors,omitempty"`
+	ElectricalConnectionDescriptionListDataSelectors          *ElectricalConnectionDescriptionListDataSelectorsType          `json:"electricalConnectionDescriptionListDataSelectors,omitempty"`
+	ElectricalConnectionParameterDescriptionListDataSelectors *ElectricalConnectionParameterDescriptionListDataSelectorsType `json:"electricalConnectionParameterDescriptionListDataSelectors,omitempty"`
+	ElectricalConnectionPermittedValueSetListDataSelectors    *ElectricalConnectionPermittedValueSetListDataSelectorsType    `json:"electricalConnectionPermittedValueSetListDataSelectors,omitempty"`
+	IdentificationListDataSelectors                           *IdentificationListDataSelectorsType                           `json:"identificationListDataSelectors,omitempty"`
+	LoadControlLimitListDataSelectors                         *LoadControlLimitListDataSelectorsType                         `json:"loadControlLimitListDataSelectors,omitempty"`
+	LoadControlLimitDescriptionListDataSelectors              *LoadControlLimitDescriptionListDataSelectorsType              `json:"loadControlLimitDescriptionListDataSelectors,omitempty"`
+	MeasurementListDataSelectors                              *MeasurementListDataSelectorsType                              `json:"measurementListDataSelectors,omitempty"`
+	MeasurementConstraintsListDataSelectors                   *MeasurementConstraintsListDataSelectorsType                   `json:"measurementConstraintsListDataSelectors,omitempty"`
+	MeasurementDescriptionListDataSelectors                   *MeasurementDescriptionListDataSelectorsType                   `json:"measurementDescriptionListDataSelectors,omitempty"`
+	TimeSeriesListDataSelectors                               *TimeSeriesListDataSelectorsType                               `json:"timeSeriesListDataSelectors,omitempty"`
+	TimeSeriesConstraintsListDataSelectors                    *TimeSeriesConstraintsListDataSelectorsType                    `json:"timeSeriesConstraintsListDataSelectors,omitempty"`
+	TimeSeriesDescriptionListDataSelectors                    *TimeSeriesDescriptionListDataSelectorsType                    `json:"timeSeriesDescriptionListDataSelectors,omitempty"`
+
+	// DataElementsChoiceGroup
+	DeviceConfigurationKeyValueDataElements              *DeviceConfigurationKeyValueDataElementsType              `json:"deviceConfigurationKeyValueDataElements,omitempty"`
+	DeviceConfigurationKeyValueDescriptionDataElements   *DeviceConfigurationKeyValueDescriptionDataElementsType   `json:"deviceConfigurationKeyValueDescriptionDataElements,omitempty"`
+	ElectricalConnectionDescriptionDataElements          *ElectricalConnectionDescriptionDataElementsType          `json:"electricalConnectionDescriptionDataElements,omitempty"`
+	ElectricalConnectionParameterDescriptionDataElements *ElectricalConnectionParameterDescriptionDataElementsType `json:"electricalConnectionParameterDescriptionDataElements,omitempty"`
+	ElectricalConnectionPermittedValueSetDataElements    *ElectricalConnectionPermittedValueSetDataElementsType    `json:"electricalConnectionPermittedValueSetDataElements,omitempty"`
+	IdentificationDataElements                           *IdentificationDataElementsType                           `json:"identificationDataElements,omitempty"`
+	LoadControlLimitDataElements                         *LoadControlLimitDataElementsType                         `json:"loadControlLimitDataElements,omitempty"`
+	LoadControlLimitDescriptionDataElements              *LoadControlLimitDescriptionDataElementsType              `json:"loadControlLimitDescriptionDataElements,omitempty"`
+	MeasurementDataElements                              *MeasurementDataElementsType                              `json:"measurementDataElements,omitempty"`
+	MeasurementConstraintsDataElements                   *MeasurementConstraintsDataElementsType                   `json:"measurementConstraintsDataElements,omitempty"`
+	MeasurementDescriptionDataElements                   *MeasurementDescriptionDataElementsType                   `json:"measurementDescriptionDataElements,omitempty"`
+	TimeSeriesDataElements                               *TimeSeriesDataElementsType                               `json:"timeSeriesDataElements,omitempty"`
+	TimeSeriesConstraintsDataElements                    *TimeSeriesConstraintsDataElementsType                    `json:"timeSeriesConstraintsDataElements,omitempty"`
+	TimeSeriesDescriptionDataElements                    *TimeSeriesDescriptionDataElementsType                    `json:"timeSeriesDescriptionDataElements,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m FilterType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *FilterType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// CmdType complex type. It replaces the generated type to add the bill functions missing in the generated model.
+type CmdType struct {
+	// CmdOptionGroup
+	Function *FunctionType `json:"function,omitempty"`
+	Filter   []FilterType  `json:"filter,omitempty"`
+
+	// DataChoiceGroup
+	BillConstraintsListData                          *BillConstraintsListDataType                          `json:"billConstraintsListData,omitempty"`
+	BillDescriptionListData                          *BillDescriptionListDataType                          `json:"billDescriptionListData,omitempty"`
+	BillListData                                     *BillListDataType                                     `json:"billListData,omitempty"`
+	DeviceClassificationManufacturerData             *DeviceClassificationManufacturerDataType             `json:"deviceClassificationManufacturerData,omitempty"`
+	DeviceConfigurationKeyValueDescriptionListData   *DeviceConfigurationKeyValueDescriptionListDataType   `json:"deviceConfigurationKeyValueDescriptionListData,omitempty"`
+	DeviceConfigurationKeyValueListData              *DeviceConfigurationKeyValueListDataType              `json:"deviceConfigurationKeyValueListData,omitempty"`
+	DeviceDiagnosisHeartbeatData                     *DeviceDiagnosisHeartbeatDataType                     `json:"deviceDiagnosisHeartbeatData,omitempty"`
+	DeviceDiagnosisStateData                         *DeviceDiagnosisStateDataType                         `json:"deviceDiagnosisStateData,omitempty"`
+	ElectricalConnectionDescriptionListData          *ElectricalConnectionDescriptionListDataType          `json:"electricalConnectionDescriptionListData,omitempty"`
+	ElectricalConnectionParameterDescriptionListData *ElectricalConnectionParameterDescriptionListDataType `json:"electricalConnectionParameterDescriptionListData,omitempty"`
+	ElectricalConnectionPermittedValueSetListData    *ElectricalConnectionPermittedValueSetListDataType    `json:"electricalConnectionPermittedValueSetListData,omitempty"`
+	IdentificationListData                           *IdentificationListDataType                           `json:"identificationListData,omitempty"`
+	IncentiveTableDescriptionData                    *IncentiveTableDescriptionDataType                    `json:"incentiveTableDescriptionData,omitempty"`
+	IncentiveTableConstraintsData                    *IncentiveTableConstraintsDataType                    `json:"incentiveTableConstraintsData,omitempty"`
+	IncentiveTableData                               *IncentiveTableDataType                               `json:"incentiveTableData,omitempty"`
+	LoadControlLimitDescriptionListData              *LoadControlLimitDescriptionListDataType              `json:"loadControlLimitDescriptionListData,omitempty"`
+	LoadControlLimitListData                         *LoadControlLimitListDataType                         `json:"loadControlLimitListData,omitempty"`
+	NodeManagementBindingRequestCall                 *NodeManagementBindingRequestCallType                 `json:"nodeManagementBindingRequestCall,omitempty"`
+	NodeManagementDestinationListData                *NodeManagementDestinationListDataType                `json:"nodeManagementDestinationListData,omitempty"`
+	NodeManagementDetailedDiscoveryData              *NodeManagementDetailedDiscoveryDataType              `json:"nodeManagementDetailedDiscoveryData,omitempty"`
+	NodeManagementSubscriptionData                   *NodeManagementSubscriptionDataType                   `json:"nodeManagementSubscriptionData,omitempty"`
+	NodeManagementSubscriptionRequestCall            *NodeManagementSubscriptionRequestCallType            `json:"nodeManagementSubscriptionRequestCall,omitempty"`
+	NodeManagementSubscriptionDeleteCall             *NodeManagementSubscriptionDeleteCallType             `json:"nodeManagementSubscriptionDeleteCall,omitempty"`
+	NodeManagementUseCaseData                        *NodeManagementUseCaseDataType                        `json:"nodeManagementUseCaseData,omitempty"`
+	MeasurementConstraintsListData                   *MeasurementConstraintsListDataType                   `json:"measurementConstraintsListData,omitempty"`
+	MeasurementDescriptionListData                   *MeasurementDescriptionListDataType                   `json:"measurementDescriptionListData,omitempty"`
+	MeasurementListData                              *MeasurementListDataType                              `json:"measurementListData,omitempty"`
+	ResultData                                       *ResultDataType                                       `json:"resultData,omitempty"`
+	TimeSeriesConstraintsData                        *TimeSeriesConstraintsDataType                        `json:"timeSeriesConstraintsData,omitempty"`
+	TimeSeriesConstraintsListData                    *TimeSeriesConstraintsListDataType                    `json:"timeSeriesConstraintsListData,omitempty"`
+	TimeSeriesDescriptionListData                    *TimeSeriesDescriptionListDataType                    `json:"timeSeriesDescriptionListData,omitempty"`
+	TimeSeriesListData                               *TimeSeriesListDataType                               `json:"timeSeriesListData,omitempty"`
+
+	// DataExtendGroup
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m CmdType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *CmdType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// NewFilterTypePartial creates a filter with cmdControl partial
+func NewFilterTypePartial() *FilterType {
+	return &FilterType{CmdControl: &CmdControlType{Partial: &ElementTagType{}}}
+}
+
+// NewFilterTypeDelete creates a filter with cmdControl delete
+func NewFilterTypeDelete() *FilterType {
+	return &FilterType{CmdControl: &CmdControlType{Delete: &ElementTagType{}}}
+}
+
+// ExtractFilter returns the partial and delete filters of the cmd, nil if not present
+func (cmd CmdType) ExtractFilter() (filterPartial *FilterType, filterDelete *FilterType) {
+	for i := range cmd.Filter {
+		filter := cmd.Filter[i]
+		if filter.CmdControl == nil {
+			continue
+		}
+
+		if filter.CmdControl.Partial != nil {
+			filterPartial = &filter
+		}
+		if filter.CmdControl.Delete != nil {
+			filterDelete = &filter
+		}
+	}
+
+	return filterPartial, filterDelete
+}
+
+// DataName returns the function name of the cmd's data, empty if no data is set
+func (cmd CmdType) DataName() string {
+	value := reflect.ValueOf(cmd)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Name == "Function" || field.Name == "Filter" || value.Field(i).IsNil() {
+			continue
+		}
+
+		return strings.Split(field.Tag.Get("json"), ",")[0]
+	}
+
+	return ""
+}
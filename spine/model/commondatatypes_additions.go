@@ -50,3 +50,13 @@ func NewISO8601Duration(duration time.Duration) *string {
 	value := d.String()
 	return &value
 }
+
+func GetISO8601Duration(value string) (time.Duration, error) {
+	p, err := period.Parse(value)
+	if err != nil {
+		return 0, err
+	}
+
+	d, _ := p.Duration()
+	return d, nil
+}
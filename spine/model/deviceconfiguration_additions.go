@@ -0,0 +1,47 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// DeviceConfigurationKeyNameEnumType constants of SPINE 1.3 missing in the generated model
+const (
+	DeviceConfigurationKeyNameEnumTypeFailsafeConsumptionActivePowerLimit DeviceConfigurationKeyNameEnumType = "failsafeConsumptionActivePowerLimit"
+	DeviceConfigurationKeyNameEnumTypeFailsafeProductionActivePowerLimit  DeviceConfigurationKeyNameEnumType = "failsafeProductionActivePowerLimit"
+	DeviceConfigurationKeyNameEnumTypeFailsafeDurationMinimum             DeviceConfigurationKeyNameEnumType = "failsafeDurationMinimum"
+)
+
+// DeviceConfigurationKeyValueDataElementsType complex type
+type DeviceConfigurationKeyValueDataElementsType struct {
+	KeyId             *ElementTagType `json:"keyId,omitempty"`
+	Value             *ElementTagType `json:"value,omitempty"`
+	IsValueChangeable *ElementTagType `json:"isValueChangeable,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m DeviceConfigurationKeyValueDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *DeviceConfigurationKeyValueDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// DeviceConfigurationKeyValueDescriptionDataElementsType complex type
+type DeviceConfigurationKeyValueDescriptionDataElementsType struct {
+	KeyId       *ElementTagType `json:"keyId,omitempty"`
+	KeyName     *ElementTagType `json:"keyName,omitempty"`
+	ValueType   *ElementTagType `json:"valueType,omitempty"`
+	Unit        *ElementTagType `json:"unit,omitempty"`
+	Label       *ElementTagType `json:"label,omitempty"`
+	Description *ElementTagType `json:"description,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m DeviceConfigurationKeyValueDescriptionDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *DeviceConfigurationKeyValueDescriptionDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
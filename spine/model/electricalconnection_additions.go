@@ -0,0 +1,68 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// ElectricalConnectionParameterDescriptionDataElementsType complex type
+type ElectricalConnectionParameterDescriptionDataElementsType struct {
+	ElectricalConnectionId  *ElementTagType `json:"electricalConnectionId,omitempty"`
+	ParameterId             *ElementTagType `json:"parameterId,omitempty"`
+	MeasurementId           *ElementTagType `json:"measurementId,omitempty"`
+	VoltageType             *ElementTagType `json:"voltageType,omitempty"`
+	AcMeasuredPhases        *ElementTagType `json:"acMeasuredPhases,omitempty"`
+	AcMeasuredInReferenceTo *ElementTagType `json:"acMeasuredInReferenceTo,omitempty"`
+	AcMeasurementType       *ElementTagType `json:"acMeasurementType,omitempty"`
+	AcMeasurementVariant    *ElementTagType `json:"acMeasurementVariant,omitempty"`
+	AcMeasuredHarmonic      *ElementTagType `json:"acMeasuredHarmonic,omitempty"`
+	ScopeType               *ElementTagType `json:"scopeType,omitempty"`
+	Label                   *ElementTagType `json:"label,omitempty"`
+	Description             *ElementTagType `json:"description,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m ElectricalConnectionParameterDescriptionDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *ElectricalConnectionParameterDescriptionDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// ElectricalConnectionPermittedValueSetDataElementsType complex type
+type ElectricalConnectionPermittedValueSetDataElementsType struct {
+	ElectricalConnectionId *ElementTagType `json:"electricalConnectionId,omitempty"`
+	ParameterId            *ElementTagType `json:"parameterId,omitempty"`
+	PermittedValueSet      *ElementTagType `json:"permittedValueSet,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m ElectricalConnectionPermittedValueSetDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *ElectricalConnectionPermittedValueSetDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// ElectricalConnectionDescriptionDataElementsType complex type
+type ElectricalConnectionDescriptionDataElementsType struct {
+	ElectricalConnectionId  *ElementTagType `json:"electricalConnectionId,omitempty"`
+	PowerSupplyType         *ElementTagType `json:"powerSupplyType,omitempty"`
+	AcConnectedPhases       *ElementTagType `json:"acConnectedPhases,omitempty"`
+	AcRmsPeriodDuration     *ElementTagType `json:"acRmsPeriodDuration,omitempty"`
+	PositiveEnergyDirection *ElementTagType `json:"positiveEnergyDirection,omitempty"`
+	ScopeType               *ElementTagType `json:"scopeType,omitempty"`
+	Label                   *ElementTagType `json:"label,omitempty"`
+	Description             *ElementTagType `json:"description,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m ElectricalConnectionDescriptionDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *ElectricalConnectionDescriptionDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
@@ -0,0 +1,17 @@
+package model
+
+// The files marked "Code generated by github.com/andig/xsd2go" are generated from the SPINE xsd files
+// and must not be edited by hand. Types the generator does not produce correctly are excluded from its
+// output and replaced by hand-written types in the *_additions.go files. After regenerating, remove the
+// excluded types together with their MarshalJSON and UnmarshalJSON methods from the generated files,
+// otherwise the package does not build because of the duplicate declarations.
+//
+// Excluded types:
+//
+//   - commandframe.go: FilterType, CmdType, replaced in commandframe_additions.go as the generated types
+//     only contain the selectors and data of a few functions
+//   - models.go: BillIdType, BillPositionIdType, BillPositionCountType, BillValueIdType, BillCostIdType,
+//     BillValueType, BillCostType, BillPositionType, BillDataType, BillListDataSelectorsType,
+//     BillConstraintsDataType, BillConstraintsListDataSelectorsType, BillDescriptionDataType and
+//     BillDescriptionListDataSelectorsType, replaced in bill_additions.go as the generated types use
+//     string ids and empty position types
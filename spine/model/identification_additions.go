@@ -0,0 +1,21 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// IdentificationDataElementsType complex type
+type IdentificationDataElementsType struct {
+	IdentificationId    *ElementTagType `json:"identificationId,omitempty"`
+	IdentificationType  *ElementTagType `json:"identificationType,omitempty"`
+	IdentificationValue *ElementTagType `json:"identificationValue,omitempty"`
+	Authorized          *ElementTagType `json:"authorized,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m IdentificationDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *IdentificationDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
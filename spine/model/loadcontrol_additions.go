@@ -0,0 +1,45 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// LoadControlLimitDataElementsType complex type
+type LoadControlLimitDataElementsType struct {
+	LimitId           *ElementTagType `json:"limitId,omitempty"`
+	IsLimitChangeable *ElementTagType `json:"isLimitChangeable,omitempty"`
+	IsLimitActive     *ElementTagType `json:"isLimitActive,omitempty"`
+	TimePeriod        *ElementTagType `json:"timePeriod,omitempty"`
+	Value             *ElementTagType `json:"value,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m LoadControlLimitDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *LoadControlLimitDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// LoadControlLimitDescriptionDataElementsType complex type
+type LoadControlLimitDescriptionDataElementsType struct {
+	LimitId        *ElementTagType `json:"limitId,omitempty"`
+	LimitType      *ElementTagType `json:"limitType,omitempty"`
+	LimitCategory  *ElementTagType `json:"limitCategory,omitempty"`
+	LimitDirection *ElementTagType `json:"limitDirection,omitempty"`
+	MeasurementId  *ElementTagType `json:"measurementId,omitempty"`
+	Unit           *ElementTagType `json:"unit,omitempty"`
+	ScopeType      *ElementTagType `json:"scopeType,omitempty"`
+	Label          *ElementTagType `json:"label,omitempty"`
+	Description    *ElementTagType `json:"description,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m LoadControlLimitDescriptionDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *LoadControlLimitDescriptionDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
@@ -87,3 +87,34 @@ func TestLoadControlLimitListDataWrite(t *testing.T) {
 		t.Errorf("TestLoadControlLimitListDataWrite() actual json string doesn't match expected result")
 	}
 }
+
+func TestLoadControlLimitListDataReadPartial(t *testing.T) {
+	var limitId LoadControlLimitIdType = 2
+	function := FunctionType(FunctionEnumTypeLoadControlLimitListData)
+
+	filter := NewFilterTypePartial()
+	filter.LoadControlLimitListDataSelectors = &LoadControlLimitListDataSelectorsType{LimitId: &limitId}
+	filter.LoadControlLimitDataElements = &LoadControlLimitDataElementsType{Value: &ElementTagType{}}
+
+	cmd := CmdType{
+		Function:                 &function,
+		Filter:                   []FilterType{*filter},
+		LoadControlLimitListData: &LoadControlLimitListDataType{},
+	}
+
+	json, err := json.Marshal(cmd)
+	if err != nil {
+		t.Errorf("TestLoadControlLimitListDataReadPartial() error = %v", err)
+	}
+	jsonString := string(json)
+
+	jsonTest := `[{"function":"loadControlLimitListData"},{"filter":[[{"cmdControl":[{"partial":[]}]},{"loadControlLimitListDataSelectors":[{"limitId":2}]},{"loadControlLimitDataElements":[{"value":[]}]}]]},{"loadControlLimitListData":[]}]`
+	if jsonString != jsonTest {
+		fmt.Println("EXPECTED:")
+		fmt.Println(string(jsonTest))
+		fmt.Println("\nACTUAL:")
+		fmt.Println(string(jsonString))
+
+		t.Errorf("TestLoadControlLimitListDataReadPartial() actual json string doesn't match expected result")
+	}
+}
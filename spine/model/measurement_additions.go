@@ -0,0 +1,72 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// ScopeTypeEnumType constants of the EV State of Charge use case missing in the generated model
+const (
+	ScopeTypeEnumTypeNominalEnergyCapacity ScopeTypeEnumType = "nominalEnergyCapacity"
+	ScopeTypeEnumTypeActualRange           ScopeTypeEnumType = "actualRange"
+	ScopeTypeEnumTypeTravelRange           ScopeTypeEnumType = "travelRange"
+)
+
+// MeasurementDataElementsType complex type
+type MeasurementDataElementsType struct {
+	MeasurementId    *ElementTagType `json:"measurementId,omitempty"`
+	ValueType        *ElementTagType `json:"valueType,omitempty"`
+	Timestamp        *ElementTagType `json:"timestamp,omitempty"`
+	Value            *ElementTagType `json:"value,omitempty"`
+	EvaluationPeriod *ElementTagType `json:"evaluationPeriod,omitempty"`
+	ValueSource      *ElementTagType `json:"valueSource,omitempty"`
+	ValueTendency    *ElementTagType `json:"valueTendency,omitempty"`
+	ValueState       *ElementTagType `json:"valueState,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m MeasurementDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *MeasurementDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// MeasurementConstraintsDataElementsType complex type
+type MeasurementConstraintsDataElementsType struct {
+	MeasurementId *ElementTagType `json:"measurementId,omitempty"`
+	ValueRangeMin *ElementTagType `json:"valueRangeMin,omitempty"`
+	ValueRangeMax *ElementTagType `json:"valueRangeMax,omitempty"`
+	ValueStepSize *ElementTagType `json:"valueStepSize,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m MeasurementConstraintsDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *MeasurementConstraintsDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// MeasurementDescriptionDataElementsType complex type
+type MeasurementDescriptionDataElementsType struct {
+	MeasurementId    *ElementTagType `json:"measurementId,omitempty"`
+	MeasurementType  *ElementTagType `json:"measurementType,omitempty"`
+	CommodityType    *ElementTagType `json:"commodityType,omitempty"`
+	Unit             *ElementTagType `json:"unit,omitempty"`
+	CalibrationValue *ElementTagType `json:"calibrationValue,omitempty"`
+	ScopeType        *ElementTagType `json:"scopeType,omitempty"`
+	Label            *ElementTagType `json:"label,omitempty"`
+	Description      *ElementTagType `json:"description,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m MeasurementDescriptionDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *MeasurementDescriptionDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
@@ -8560,85 +8560,6 @@ func (m *AlarmListDataSelectorsType) UnmarshalJSON(data []byte) error {
 	return util.Unmarshal(data, &m)
 }
 
-// BillValueType complex type
-type BillValueType struct {
-	ValueId         *BillValueIdType  `json:"valueId"`
-	Unit            string            `json:"unit"`
-	Value           *ScaledNumberType `json:"value"`
-	ValuePercentage *ScaledNumberType `json:"valuePercentage"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillValueType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillValueType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
-// BillCostType complex type
-type BillCostType struct {
-	CostId         *BillCostIdType   `json:"costId"`
-	CostType       string            `json:"costType"`
-	ValueId        *BillValueIdType  `json:"valueId"`
-	Unit           string            `json:"unit"`
-	Currency       string            `json:"currency"`
-	Cost           *ScaledNumberType `json:"cost"`
-	CostPercentage *ScaledNumberType `json:"costPercentage"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillCostType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillCostType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
-// BillPositionType complex type
-type BillPositionType struct {
-	PositionId   *BillPositionIdType `json:"positionId"`
-	PositionType string              `json:"positionType"`
-	TimePeriod   *TimePeriodType     `json:"timePeriod"`
-	Value        []BillValueType     `json:"value"`
-	Cost         []BillCostType      `json:"cost"`
-	Label        *LabelType          `json:"label"`
-	Description  *DescriptionType    `json:"description"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillPositionType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillPositionType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
-// BillDataType complex type
-type BillDataType struct {
-	BillId    *BillIdType `json:"billId"`
-	BillType  string      `json:"billType"`
-	ScopeType string      `json:"scopeType"`
-	Total     *Total      `json:"total"`
-	Position  []Position  `json:"position"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillDataType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillDataType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
 // BillListDataType complex type
 type BillListDataType struct {
 	BillData []BillDataType `json:"billData"`
@@ -8654,39 +8575,6 @@ func (m *BillListDataType) UnmarshalJSON(data []byte) error {
 	return util.Unmarshal(data, &m)
 }
 
-// BillListDataSelectorsType complex type
-type BillListDataSelectorsType struct {
-	BillId    *BillIdType `json:"billId"`
-	ScopeType string      `json:"scopeType"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillListDataSelectorsType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillListDataSelectorsType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
-// BillConstraintsDataType complex type
-type BillConstraintsDataType struct {
-	BillId           *BillIdType            `json:"billId"`
-	PositionCountMin *BillPositionCountType `json:"positionCountMin"`
-	PositionCountMax *BillPositionCountType `json:"positionCountMax"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillConstraintsDataType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillConstraintsDataType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
 // BillConstraintsListDataType complex type
 type BillConstraintsListDataType struct {
 	BillConstraintsData []BillConstraintsDataType `json:"billConstraintsData"`
@@ -8702,39 +8590,6 @@ func (m *BillConstraintsListDataType) UnmarshalJSON(data []byte) error {
 	return util.Unmarshal(data, &m)
 }
 
-// BillConstraintsListDataSelectorsType complex type
-type BillConstraintsListDataSelectorsType struct {
-	BillId *BillIdType `json:"billId"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillConstraintsListDataSelectorsType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillConstraintsListDataSelectorsType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
-// BillDescriptionDataType complex type
-type BillDescriptionDataType struct {
-	BillId            *BillIdType `json:"billId"`
-	BillWriteable     *bool       `json:"billWriteable"`
-	UpdateRequired    *bool       `json:"updateRequired"`
-	SupportedBillType []string    `json:"supportedBillType"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillDescriptionDataType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillDescriptionDataType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
 // BillDescriptionListDataType complex type
 type BillDescriptionListDataType struct {
 	BillDescriptionData []BillDescriptionDataType `json:"billDescriptionData"`
@@ -8750,21 +8605,6 @@ func (m *BillDescriptionListDataType) UnmarshalJSON(data []byte) error {
 	return util.Unmarshal(data, &m)
 }
 
-// BillDescriptionListDataSelectorsType complex type
-type BillDescriptionListDataSelectorsType struct {
-	BillId *BillIdType `json:"billId"`
-}
-
-// MarshalJSON is the SHIP serialization marshaller
-func (m BillDescriptionListDataSelectorsType) MarshalJSON() ([]byte, error) {
-	return util.Marshal(m)
-}
-
-// UnmarshalJSON is the SHIP serialization unmarshaller
-func (m *BillDescriptionListDataSelectorsType) UnmarshalJSON(data []byte) error {
-	return util.Unmarshal(data, &m)
-}
-
 // DataTunnelingHeaderType complex type
 type DataTunnelingHeaderType struct {
 	PurposeId  *PurposeIdType `json:"purposeId"`
@@ -11192,9 +11032,6 @@ const (
 	AlarmTypeEnumTypeOverthreshold  AlarmTypeEnumType = "overThreshold"
 )
 
-// BillIdType type
-type BillIdType string
-
 // BillTypeType type
 type BillTypeType string
 
@@ -11206,12 +11043,6 @@ const (
 	BillTypeEnumTypeChargingsummary BillTypeEnumType = "chargingSummary"
 )
 
-// BillPositionIdType type
-type BillPositionIdType string
-
-// BillPositionCountType type
-type BillPositionCountType string
-
 // BillPositionTypeType type
 type BillPositionTypeType string
 
@@ -11224,12 +11055,6 @@ const (
 	BillPositionTypeEnumTypeSelfproducedelectricenergy BillPositionTypeEnumType = "selfProducedElectricEnergy"
 )
 
-// BillValueIdType type
-type BillValueIdType string
-
-// BillCostIdType type
-type BillCostIdType string
-
 // BillCostTypeType type
 type BillCostTypeType string
 
@@ -0,0 +1,54 @@
+package model
+
+import "fmt"
+
+// ErrorNumberType constants, see SPINE Protocol Specification
+const (
+	ErrorNumberTypeNoError                                           ErrorNumberType = 0
+	ErrorNumberTypeGeneralError                                      ErrorNumberType = 1
+	ErrorNumberTypeTimeout                                           ErrorNumberType = 2
+	ErrorNumberTypeOverload                                          ErrorNumberType = 3
+	ErrorNumberTypeDestinationUnknown                                ErrorNumberType = 4
+	ErrorNumberTypeDestinationUnreachable                            ErrorNumberType = 5
+	ErrorNumberTypeCommandNotSupported                               ErrorNumberType = 6
+	ErrorNumberTypeCommandRejected                                   ErrorNumberType = 7
+	ErrorNumberTypeRestrictedFunctionExchangeCombinationNotSupported ErrorNumberType = 8
+	ErrorNumberTypeBindingIsNecessaryForFunctionExchange             ErrorNumberType = 9
+)
+
+// ErrorType is an error carrying a SPINE error number
+type ErrorType struct {
+	ErrorNumber ErrorNumberType
+	Description DescriptionType
+}
+
+// NewErrorType creates an error with the given error number and description
+func NewErrorType(errorNumber ErrorNumberType, description string) *ErrorType {
+	return &ErrorType{
+		ErrorNumber: errorNumber,
+		Description: DescriptionType(description),
+	}
+}
+
+// NewErrorTypeFromResult creates an error from result data, nil if the result is no error
+func NewErrorTypeFromResult(result *ResultDataType) *ErrorType {
+	if result == nil || result.ErrorNumber == nil || *result.ErrorNumber == ErrorNumberTypeNoError {
+		return nil
+	}
+
+	res := &ErrorType{
+		ErrorNumber: *result.ErrorNumber,
+	}
+	if result.Description != nil {
+		res.Description = *result.Description
+	}
+
+	return res
+}
+
+func (e *ErrorType) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("error number %d", e.ErrorNumber)
+	}
+	return fmt.Sprintf("error number %d: %s", e.ErrorNumber, e.Description)
+}
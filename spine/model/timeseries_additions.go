@@ -0,0 +1,69 @@
+package model
+
+import "github.com/evcc-io/eebus/util"
+
+// TimeSeriesDataElementsType complex type
+type TimeSeriesDataElementsType struct {
+	TimeSeriesId   *ElementTagType `json:"timeSeriesId,omitempty"`
+	TimePeriod     *ElementTagType `json:"timePeriod,omitempty"`
+	TimeSeriesSlot *ElementTagType `json:"timeSeriesSlot,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m TimeSeriesDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *TimeSeriesDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// TimeSeriesDescriptionDataElementsType complex type
+type TimeSeriesDescriptionDataElementsType struct {
+	TimeSeriesId        *ElementTagType `json:"timeSeriesId,omitempty"`
+	TimeSeriesType      *ElementTagType `json:"timeSeriesType,omitempty"`
+	TimeSeriesWriteable *ElementTagType `json:"timeSeriesWriteable,omitempty"`
+	UpdateRequired      *ElementTagType `json:"updateRequired,omitempty"`
+	MeasurementId       *ElementTagType `json:"measurementId,omitempty"`
+	Currency            *ElementTagType `json:"currency,omitempty"`
+	Unit                *ElementTagType `json:"unit,omitempty"`
+	Label               *ElementTagType `json:"label,omitempty"`
+	Description         *ElementTagType `json:"description,omitempty"`
+	ScopeType           *ElementTagType `json:"scopeType,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m TimeSeriesDescriptionDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *TimeSeriesDescriptionDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
+
+// TimeSeriesConstraintsDataElementsType complex type
+type TimeSeriesConstraintsDataElementsType struct {
+	TimeSeriesId                *ElementTagType `json:"timeSeriesId,omitempty"`
+	SlotCountMin                *ElementTagType `json:"slotCountMin,omitempty"`
+	SlotCountMax                *ElementTagType `json:"slotCountMax,omitempty"`
+	SlotDurationMin             *ElementTagType `json:"slotDurationMin,omitempty"`
+	SlotDurationMax             *ElementTagType `json:"slotDurationMax,omitempty"`
+	SlotDurationStepSize        *ElementTagType `json:"slotDurationStepSize,omitempty"`
+	EarliestTimeSeriesStartTime *ElementTagType `json:"earliestTimeSeriesStartTime,omitempty"`
+	LatestTimeSeriesEndTime     *ElementTagType `json:"latestTimeSeriesEndTime,omitempty"`
+	SlotValueMin                *ElementTagType `json:"slotValueMin,omitempty"`
+	SlotValueMax                *ElementTagType `json:"slotValueMax,omitempty"`
+	SlotValueStepSize           *ElementTagType `json:"slotValueStepSize,omitempty"`
+}
+
+// MarshalJSON is the SHIP serialization marshaller
+func (m TimeSeriesConstraintsDataElementsType) MarshalJSON() ([]byte, error) {
+	return util.Marshal(m)
+}
+
+// UnmarshalJSON is the SHIP serialization unmarshaller
+func (m *TimeSeriesConstraintsDataElementsType) UnmarshalJSON(data []byte) error {
+	return util.Unmarshal(data, &m)
+}
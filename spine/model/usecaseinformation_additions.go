@@ -0,0 +1,7 @@
+package model
+
+// additional use case actors not covered by the generated model
+const (
+	UseCaseActorEnumTypeCEM  UseCaseActorEnumType = "CEM"
+	UseCaseActorEnumTypeEVSE UseCaseActorEnumType = "EVSE"
+)
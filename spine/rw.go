@@ -14,6 +14,8 @@ func (rw RW) String() string {
 		return "RO"
 	case rw.Read && rw.Write:
 		return "RW"
+	case !rw.Read && rw.Write:
+		return "WO"
 	default:
 		return "--"
 	}
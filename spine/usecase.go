@@ -0,0 +1,201 @@
+package spine
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// UseCaseFeature is a feature an entity needs to provide for implementing a use case
+type UseCaseFeature struct {
+	Type model.FeatureTypeEnumType
+	Role model.RoleType
+}
+
+// UseCase describes a use case implemented by an entity of the local device
+type UseCase struct {
+	Entity    Entity
+	Actor     model.UseCaseActorEnumType
+	Name      model.UseCaseNameEnumType
+	Version   model.SpecificationVersionType
+	Scenarios []model.UseCaseScenarioSupportType
+	Features  []UseCaseFeature
+	Available bool
+}
+
+// UseCaseChangeHandler is called when the local use cases have changed
+type UseCaseChangeHandler func()
+
+type useCaseRegistry struct {
+	useCases []UseCase
+	remote   []RemoteUseCase
+	handlers handlerList[UseCaseChangeHandler]
+	mux      sync.Mutex
+}
+
+// AddUseCase registers or replaces a use case identified by actor and name.
+// The use case's entity must provide all required features. Registering an
+// unchanged use case again does not notify the change handlers.
+func (d *DeviceImpl) AddUseCase(uc UseCase) error {
+	if uc.Entity == nil {
+		return fmt.Errorf("usecase %s: missing entity", uc.Name)
+	}
+
+	for _, f := range uc.Features {
+		if uc.Entity.FeatureByProps(f.Type, f.Role) == nil {
+			return fmt.Errorf("usecase %s: missing feature %s %s", uc.Name, f.Role, f.Type)
+		}
+	}
+
+	d.useCases.mux.Lock()
+
+	changed := true
+	if i := d.useCaseIndex(uc.Actor, uc.Name); i >= 0 {
+		changed = !reflect.DeepEqual(d.useCases.useCases[i], uc)
+		d.useCases.useCases[i] = uc
+	} else {
+		d.useCases.useCases = append(d.useCases.useCases, uc)
+	}
+
+	d.useCases.mux.Unlock()
+
+	if changed {
+		d.notifyUseCaseChange()
+	}
+
+	return nil
+}
+
+// RemoveUseCase unregisters the use case
+func (d *DeviceImpl) RemoveUseCase(actor model.UseCaseActorEnumType, name model.UseCaseNameEnumType) {
+	d.useCases.mux.Lock()
+
+	i := d.useCaseIndex(actor, name)
+	if i >= 0 {
+		d.useCases.useCases = append(d.useCases.useCases[:i], d.useCases.useCases[i+1:]...)
+	}
+
+	d.useCases.mux.Unlock()
+
+	if i >= 0 {
+		d.notifyUseCaseChange()
+	}
+}
+
+// SetUseCaseAvailable enables or disables the use case at runtime
+func (d *DeviceImpl) SetUseCaseAvailable(actor model.UseCaseActorEnumType, name model.UseCaseNameEnumType, available bool) error {
+	d.useCases.mux.Lock()
+
+	i := d.useCaseIndex(actor, name)
+	if i < 0 {
+		d.useCases.mux.Unlock()
+		return fmt.Errorf("usecase %s: not found", name)
+	}
+
+	changed := d.useCases.useCases[i].Available != available
+	d.useCases.useCases[i].Available = available
+
+	d.useCases.mux.Unlock()
+
+	if changed {
+		d.notifyUseCaseChange()
+	}
+
+	return nil
+}
+
+// UseCases returns the registered use cases
+func (d *DeviceImpl) UseCases() []UseCase {
+	d.useCases.mux.Lock()
+	defer d.useCases.mux.Unlock()
+
+	return append([]UseCase(nil), d.useCases.useCases...)
+}
+
+// UseCaseInformation returns the registered use cases grouped by entity and actor
+func (d *DeviceImpl) UseCaseInformation() []model.UseCaseInformationDataType {
+	var res []model.UseCaseInformationDataType
+
+	for _, uc := range d.UseCases() {
+		address := model.FeatureAddressType{
+			Device: &d.Address,
+			Entity: uc.Entity.GetAddress(),
+		}
+		actor := model.UseCaseActorType(uc.Actor)
+
+		idx := -1
+		for i, item := range res {
+			if *item.Actor == actor && reflect.DeepEqual(*item.Address, address) {
+				idx = i
+				break
+			}
+		}
+
+		if idx < 0 {
+			res = append(res, model.UseCaseInformationDataType{
+				Address: &address,
+				Actor:   &actor,
+			})
+			idx = len(res) - 1
+		}
+
+		name := model.UseCaseNameType(uc.Name)
+		version := uc.Version
+		available := uc.Available
+
+		res[idx].UseCaseSupport = append(res[idx].UseCaseSupport, model.UseCaseSupportType{
+			UseCaseName:      &name,
+			UseCaseVersion:   &version,
+			UseCaseAvailable: &available,
+			ScenarioSupport:  uc.Scenarios,
+		})
+	}
+
+	return res
+}
+
+// AddUseCaseChangeHandler registers a handler called when use cases are added, removed, enabled or disabled.
+// The returned function removes the handler.
+func (d *DeviceImpl) AddUseCaseChangeHandler(handler UseCaseChangeHandler) func() {
+	return d.useCases.handlers.add(handler)
+}
+
+// removeUseCasesForEntity unregisters all use cases implemented by the entity
+func (d *DeviceImpl) removeUseCasesForEntity(e Entity) {
+	d.useCases.mux.Lock()
+
+	var useCases []UseCase
+	for _, uc := range d.useCases.useCases {
+		if uc.Entity != e {
+			useCases = append(useCases, uc)
+		}
+	}
+
+	changed := len(useCases) != len(d.useCases.useCases)
+	d.useCases.useCases = useCases
+
+	d.useCases.mux.Unlock()
+
+	if changed {
+		d.notifyUseCaseChange()
+	}
+}
+
+// useCaseIndex returns the index of the use case, -1 if not found. Requires lock to be held.
+func (d *DeviceImpl) useCaseIndex(actor model.UseCaseActorEnumType, name model.UseCaseNameEnumType) int {
+	for i, uc := range d.useCases.useCases {
+		if uc.Actor == actor && uc.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (d *DeviceImpl) notifyUseCaseChange() {
+	for _, handler := range d.useCases.handlers.list() {
+		handler()
+	}
+}
@@ -0,0 +1,240 @@
+package spine
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/evcc-io/eebus/spine/model"
+	"github.com/samber/lo"
+)
+
+// RemoteUseCase is a use case announced by a remote device
+type RemoteUseCase struct {
+	Entity    []model.AddressEntityType // nil if announced without entity address
+	Actor     model.UseCaseActorEnumType
+	Name      model.UseCaseNameEnumType
+	Version   model.SpecificationVersionType
+	Available bool
+	Scenarios []model.UseCaseScenarioSupportType
+}
+
+// SupportsScenario checks if the scenario is part of the announced use case
+func (uc RemoteUseCase) SupportsScenario(scenario model.UseCaseScenarioSupportType) bool {
+	return lo.Contains(uc.Scenarios, scenario)
+}
+
+// UseCaseEvent describes the change of a remote use case's availability or of one of its scenarios
+type UseCaseEvent struct {
+	UseCase   RemoteUseCase
+	Scenario  model.UseCaseScenarioSupportType // 0 if the event refers to the use case availability
+	Supported bool                             // use case available or scenario supported after the change
+}
+
+// UpdateRemoteUseCases stores the use cases announced by a remote device and returns the resulting changes.
+// Unless partial, use cases not contained in data are removed.
+func (d *DeviceImpl) UpdateRemoteUseCases(data []model.UseCaseInformationDataType, partial bool) []UseCaseEvent {
+	var updated []RemoteUseCase
+
+	for _, info := range data {
+		var entity []model.AddressEntityType
+		if info.Address != nil {
+			entity = info.Address.Entity
+		}
+
+		var actor model.UseCaseActorEnumType
+		if info.Actor != nil {
+			actor = model.UseCaseActorEnumType(*info.Actor)
+		}
+
+		for _, item := range info.UseCaseSupport {
+			if item.UseCaseName == nil {
+				continue
+			}
+
+			uc := RemoteUseCase{
+				Entity:    entity,
+				Actor:     actor,
+				Name:      model.UseCaseNameEnumType(*item.UseCaseName),
+				Available: item.UseCaseAvailable == nil || *item.UseCaseAvailable,
+				Scenarios: item.ScenarioSupport,
+			}
+			if item.UseCaseVersion != nil {
+				uc.Version = *item.UseCaseVersion
+			}
+
+			updated = append(updated, uc)
+		}
+	}
+
+	d.useCases.mux.Lock()
+	defer d.useCases.mux.Unlock()
+
+	existing := d.useCases.remote
+
+	var res []RemoteUseCase
+	if partial {
+		res = append(res, existing...)
+		for _, uc := range updated {
+			if i := remoteUseCaseIndex(res, uc); i >= 0 {
+				res[i] = uc
+			} else {
+				res = append(res, uc)
+			}
+		}
+	} else {
+		res = updated
+	}
+
+	d.useCases.remote = res
+
+	var events []UseCaseEvent
+	for _, uc := range res {
+		var old *RemoteUseCase
+		if i := remoteUseCaseIndex(existing, uc); i >= 0 {
+			old = &existing[i]
+		}
+		events = append(events, useCaseEvents(old, &uc)...)
+	}
+	for i := range existing {
+		if remoteUseCaseIndex(res, existing[i]) < 0 {
+			events = append(events, useCaseEvents(&existing[i], nil)...)
+		}
+	}
+
+	return events
+}
+
+// RemoveRemoteUseCases removes the use cases announced by the remote device for the actor and returns the resulting changes
+func (d *DeviceImpl) RemoveRemoteUseCases(actor model.UseCaseActorEnumType) []UseCaseEvent {
+	d.useCases.mux.Lock()
+	defer d.useCases.mux.Unlock()
+
+	var events []UseCaseEvent
+	var res []RemoteUseCase
+	for i, uc := range d.useCases.remote {
+		if uc.Actor != actor {
+			res = append(res, uc)
+			continue
+		}
+		events = append(events, useCaseEvents(&d.useCases.remote[i], nil)...)
+	}
+
+	d.useCases.remote = res
+
+	return events
+}
+
+// RemoteUseCases returns the use cases announced by the remote device
+func (d *DeviceImpl) RemoteUseCases() []RemoteUseCase {
+	d.useCases.mux.Lock()
+	defer d.useCases.mux.Unlock()
+
+	return append([]RemoteUseCase(nil), d.useCases.remote...)
+}
+
+// SupportsUseCase checks if the remote entity supports the scenario of the available use case with at least the given version.
+// Entity nil matches any entity, scenario 0 and an empty minVersion are not checked.
+// Use cases announced without version do not satisfy a minVersion.
+func (d *DeviceImpl) SupportsUseCase(entity []model.AddressEntityType, name model.UseCaseNameEnumType, scenario model.UseCaseScenarioSupportType, minVersion model.SpecificationVersionType) bool {
+	for _, uc := range d.RemoteUseCases() {
+		if uc.Name != name || !uc.Available {
+			continue
+		}
+
+		if entity != nil && uc.Entity != nil && !reflect.DeepEqual(entity, uc.Entity) {
+			continue
+		}
+
+		if scenario != 0 && !uc.SupportsScenario(scenario) {
+			continue
+		}
+
+		if minVersion != "" && (uc.Version == "" || CompareVersions(uc.Version, minVersion) < 0) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// CompareVersions compares dotted version numbers returning -1, 0 or 1
+func CompareVersions(a, b model.SpecificationVersionType) int {
+	as := strings.Split(string(a), ".")
+	bs := strings.Split(string(b), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func remoteUseCaseIndex(useCases []RemoteUseCase, uc RemoteUseCase) int {
+	for i, item := range useCases {
+		if item.Actor == uc.Actor && item.Name == uc.Name && reflect.DeepEqual(item.Entity, uc.Entity) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// useCaseEvents returns the changes between old and new, either of which may be nil
+func useCaseEvents(old, new *RemoteUseCase) []UseCaseEvent {
+	var res []UseCaseEvent
+
+	uc := new
+	if uc == nil {
+		uc = old
+	}
+
+	if old == nil || new == nil || old.Available != new.Available {
+		res = append(res, UseCaseEvent{
+			UseCase:   *uc,
+			Supported: new != nil && new.Available,
+		})
+	}
+
+	var scenarios []model.UseCaseScenarioSupportType
+	for _, item := range []*RemoteUseCase{old, new} {
+		if item == nil {
+			continue
+		}
+		for _, scenario := range item.Scenarios {
+			if !lo.Contains(scenarios, scenario) {
+				scenarios = append(scenarios, scenario)
+			}
+		}
+	}
+
+	for _, scenario := range scenarios {
+		before := old != nil && old.SupportsScenario(scenario)
+		after := new != nil && new.SupportsScenario(scenario)
+
+		if before != after {
+			res = append(res, UseCaseEvent{
+				UseCase:   *uc,
+				Scenario:  scenario,
+				Supported: after,
+			})
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,68 @@
+package spine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestRemoteUseCases(t *testing.T) {
+	var data model.NodeManagementUseCaseDataType
+	if err := json.Unmarshal([]byte(`[{"useCaseInformation":[[{"address":[{"device":"d:_i:EVSE"},{"entity":[1,1]}]},{"actor":"EV"},{"useCaseSupport":[[{"useCaseName":"coordinatedEvCharging"},{"useCaseVersion":"1.0.1"},{"useCaseAvailable":true},{"scenarioSupport":[1,2,3]}],[{"useCaseName":"evStateOfCharge"},{"useCaseAvailable":false},{"scenarioSupport":[1]}]]}]]}]`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	dev := &DeviceImpl{}
+
+	events := dev.UpdateRemoteUseCases(data.UseCaseInformation, false)
+	if len(events) != 6 {
+		t.Errorf("expected 6 events, got %d: %v", len(events), events)
+	}
+
+	entity := []model.AddressEntityType{1, 1}
+	if !dev.SupportsUseCase(entity, model.UseCaseNameEnumTypeCoordinatedEVCharging, 3, "1.0.1") {
+		t.Error("expected coordinatedEvCharging scenario 3 to be supported")
+	}
+	if dev.SupportsUseCase(entity, model.UseCaseNameEnumTypeCoordinatedEVCharging, 3, "1.1") {
+		t.Error("expected version 1.1 not to be supported")
+	}
+	if dev.SupportsUseCase(entity, model.UseCaseNameEnumTypeCoordinatedEVCharging, 4, "") {
+		t.Error("expected scenario 4 not to be supported")
+	}
+	if dev.SupportsUseCase(nil, model.UseCaseNameEnumTypeEVStateOfCharge, 0, "") {
+		t.Error("expected unavailable use case not to be supported")
+	}
+
+	// notify removing scenario 3 and enabling soc
+	data.UseCaseInformation[0].UseCaseSupport[0].ScenarioSupport = []model.UseCaseScenarioSupportType{1, 2}
+	available := true
+	data.UseCaseInformation[0].UseCaseSupport[1].UseCaseAvailable = &available
+
+	events = dev.UpdateRemoteUseCases(data.UseCaseInformation, false)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %v", len(events), events)
+	}
+	if e := events[0]; e.UseCase.Name != model.UseCaseNameEnumTypeCoordinatedEVCharging || e.Scenario != 3 || e.Supported {
+		t.Errorf("unexpected event: %v", e)
+	}
+	if e := events[1]; e.UseCase.Name != model.UseCaseNameEnumTypeEVStateOfCharge || e.Scenario != 0 || !e.Supported {
+		t.Errorf("unexpected event: %v", e)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b model.SpecificationVersionType
+		res  int
+	}{
+		{"1.0.1", "1.0.1", 0},
+		{"1.0.1", "1.0", 1},
+		{"1.0.1", "1.1", -1},
+		{"1.10", "1.9", 1},
+	} {
+		if res := CompareVersions(tc.a, tc.b); res != tc.res {
+			t.Errorf("CompareVersions(%s, %s) = %d, expected %d", tc.a, tc.b, res, tc.res)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package spine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestUseCaseRegistry(t *testing.T) {
+	entity := &EntityImpl{Address: []model.AddressEntityType{1}}
+	entity.Add(&FeatureImpl{Type: model.FeatureTypeEnumTypeMeasurement, Role: model.RoleTypeClient})
+
+	dev := &DeviceImpl{Address: "d:_i:HEMS"}
+	dev.Add(entity)
+
+	var changes int
+	remove := dev.AddUseCaseChangeHandler(func() { changes++ })
+
+	err := dev.AddUseCase(UseCase{
+		Entity:    entity,
+		Actor:     model.UseCaseActorEnumTypeCEM,
+		Name:      model.UseCaseNameEnumTypeEVStateOfCharge,
+		Version:   "1.0.1",
+		Scenarios: []model.UseCaseScenarioSupportType{1},
+		Features:  []UseCaseFeature{{Type: model.FeatureTypeEnumTypeMeasurement, Role: model.RoleTypeClient}},
+		Available: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dev.AddUseCase(UseCase{
+		Entity:   entity,
+		Actor:    model.UseCaseActorEnumTypeCEM,
+		Name:     model.UseCaseNameEnumTypeCoordinatedEVCharging,
+		Features: []UseCaseFeature{{Type: model.FeatureTypeEnumTypeTimeSeries, Role: model.RoleTypeClient}},
+	})
+	if err == nil {
+		t.Error("expected error for missing feature")
+	}
+
+	if err := dev.SetUseCaseAvailable(model.UseCaseActorEnumTypeCEM, model.UseCaseNameEnumTypeEVStateOfCharge, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes != 2 {
+		t.Errorf("expected 2 changes, got %d", changes)
+	}
+
+	remove()
+	dev.RemoveUseCase(model.UseCaseActorEnumTypeCEM, model.UseCaseNameEnumTypeEVStateOfCharge)
+
+	if changes != 2 || len(dev.UseCases()) != 0 {
+		t.Errorf("unexpected changes %d or use cases %v", changes, dev.UseCases())
+	}
+}
+
+func TestUseCaseInformation(t *testing.T) {
+	entity := &EntityImpl{Address: []model.AddressEntityType{1}}
+	dev := &DeviceImpl{Address: "d:_i:HEMS"}
+	dev.Add(entity)
+
+	for _, name := range []model.UseCaseNameEnumType{model.UseCaseNameEnumTypeEVStateOfCharge, model.UseCaseNameEnumTypeEVChargingSummary} {
+		if err := dev.AddUseCase(UseCase{
+			Entity:    entity,
+			Actor:     model.UseCaseActorEnumTypeCEM,
+			Name:      name,
+			Version:   "1.0.1",
+			Scenarios: []model.UseCaseScenarioSupportType{1},
+			Available: true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := json.Marshal(model.NodeManagementUseCaseDataType{UseCaseInformation: dev.UseCaseInformation()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"useCaseInformation":[[{"address":[{"device":"d:_i:HEMS"},{"entity":[1]}]},{"actor":"CEM"},{"useCaseSupport":[[{"useCaseName":"evStateOfCharge"},{"useCaseVersion":"1.0.1"},{"useCaseAvailable":true},{"scenarioSupport":[1]}],[{"useCaseName":"evChargingSummary"},{"useCaseVersion":"1.0.1"},{"useCaseAvailable":true},{"scenarioSupport":[1]}]]}]]}]`
+	if string(data) != expected {
+		t.Errorf("unexpected json:\n%s\n%s", data, expected)
+	}
+}
@@ -0,0 +1,170 @@
+package ev
+
+import (
+	"sync"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// AuthorizationState is the state of the authorization of the connected EV
+type AuthorizationState string
+
+const (
+	AuthorizationStateNone       AuthorizationState = "none" // no authorizer, all EVs may charge
+	AuthorizationStatePending    AuthorizationState = "pending"
+	AuthorizationStateAuthorized AuthorizationState = "authorized"
+	AuthorizationStateDenied     AuthorizationState = "denied"
+)
+
+// Authorizer decides if the EV with the identifications may charge, e.g. by its MAC address or the user's RFID tag.
+// It is called whenever the identifications of the connected EV change. An error keeps the authorization pending.
+type Authorizer func(ids []Identification) (bool, error)
+
+// Authorization holds the charging of the connected EV by writing zero overload limits until it has been authorized
+type Authorization struct {
+	evcc       *EVCC
+	opev       *OPEV
+	authorizer Authorizer
+	state      AuthorizationState
+	mux        sync.Mutex
+}
+
+// SetAuthorizer enables the authorization of connected EVs. A nil authorizer allows all EVs to charge.
+func (a *Authorization) SetAuthorizer(authorizer Authorizer) {
+	a.mux.Lock()
+	a.authorizer = authorizer
+	a.mux.Unlock()
+
+	if authorizer == nil {
+		a.setState(AuthorizationStateNone)
+		a.updateLimits()
+		return
+	}
+
+	if a.evcc.EVConnected() && !a.decided() {
+		a.setState(AuthorizationStatePending)
+		a.updateLimits()
+		a.authorize()
+	}
+}
+
+// State returns the authorization state of the connected EV
+func (a *Authorization) State() AuthorizationState {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.state
+}
+
+// Authorize allows or denies charging of the connected EV independent of its identifications,
+// e.g. after the user has been authorized by the application
+func (a *Authorization) Authorize(allowed bool) {
+	state := AuthorizationStateDenied
+	if allowed {
+		state = AuthorizationStateAuthorized
+	}
+
+	if a.setState(state) {
+		a.updateLimits()
+	}
+}
+
+// decided returns if charging of the connected EV has been authorized or denied
+func (a *Authorization) decided() bool {
+	state := a.State()
+	return state == AuthorizationStateAuthorized || state == AuthorizationStateDenied
+}
+
+// held returns if the overload limits of the connected EV are held at zero
+func (a *Authorization) held() bool {
+	state := a.State()
+	return state == AuthorizationStatePending || state == AuthorizationStateDenied
+}
+
+// setState updates the state and returns if it has changed
+func (a *Authorization) setState(state AuthorizationState) bool {
+	a.mux.Lock()
+	changed := a.state != state
+	a.state = state
+	a.mux.Unlock()
+
+	if !changed {
+		return false
+	}
+
+	switch state {
+	case AuthorizationStatePending:
+		a.evcc.event(EventEVAuthorizationPending)
+	case AuthorizationStateAuthorized:
+		a.evcc.event(EventEVAuthorized)
+	case AuthorizationStateDenied:
+		a.evcc.event(EventEVAuthorizationDenied)
+	}
+
+	return true
+}
+
+// updateLimits writes zero overload limits while charging is held and restores the requested limits afterwards.
+// The limits may not be available yet, they are written again once the EV has provided them.
+func (a *Authorization) updateLimits() {
+	if !a.evcc.EVConnected() {
+		return
+	}
+
+	if a.held() {
+		_ = a.opev.hold()
+	} else {
+		_ = a.opev.release()
+	}
+}
+
+// authorize asks the authorizer for a decision on the identifications of the connected EV
+func (a *Authorization) authorize() {
+	a.mux.Lock()
+	authorizer := a.authorizer
+	a.mux.Unlock()
+
+	if authorizer == nil || a.decided() {
+		return
+	}
+
+	ids, err := a.evcc.Identifications()
+	if err != nil {
+		return
+	}
+
+	if allowed, err := authorizer(ids); err == nil {
+		a.Authorize(allowed)
+	}
+}
+
+func (a *Authorization) evConnectionChanged(connected bool) {
+	a.mux.Lock()
+	enabled := a.authorizer != nil
+	a.mux.Unlock()
+
+	switch {
+	case !enabled:
+		a.setState(AuthorizationStateNone)
+	case connected:
+		// the limits are usually not available yet and are written once the EV has provided them
+		a.setState(AuthorizationStatePending)
+		a.updateLimits()
+		a.authorize()
+	default:
+		// the next EV has to be authorized again
+		a.setState(AuthorizationStatePending)
+	}
+}
+
+func (a *Authorization) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	switch function {
+	case model.FunctionEnumTypeIdentificationListData:
+		a.authorize()
+
+	case model.FunctionEnumTypeLoadControlLimitDescriptionListData, model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData:
+		if a.held() {
+			a.updateLimits()
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ev
+
+import (
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestAuthorization(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uc.Authorization.SetAuthorizer(func(ids []Identification) (bool, error) {
+		for _, id := range ids {
+			if id.Type == model.IdentificationTypeEnumTypeUserrfidtag {
+				return id.Value == "1234", nil
+			}
+		}
+		return false, nil
+	})
+
+	lastWrite := func() []float64 {
+		t.Helper()
+
+		if len(conn.ctx.writes) == 0 {
+			t.Fatal("no limits written")
+		}
+
+		var res []float64
+		for _, item := range conn.ctx.writes[len(conn.ctx.writes)-1][0].LoadControlLimitListData.LoadControlLimitData {
+			res = append(res, item.Value.GetValue())
+		}
+		return res
+	}
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+
+	if state := uc.Authorization.State(); state != AuthorizationStatePending {
+		t.Errorf("expected pending, got %s", state)
+	}
+
+	// the limits are held as soon as the EV provides them
+	setTestLimitData(cem)
+
+	if values := lastWrite(); len(values) != 3 || values[0] != 0.1 {
+		t.Errorf("expected held limits, got %v", values)
+	}
+
+	// requested limits are held until the EV has been authorized
+	if err := uc.OPEV.WriteOverloadLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+	if values := lastWrite(); values[0] != 0.1 {
+		t.Errorf("expected held limits, got %v", values)
+	}
+
+	id := func(typ model.IdentificationTypeEnumType, value string) model.IdentificationDataType {
+		return model.IdentificationDataType{
+			IdentificationId:    ptr(model.IdentificationIdType(0)),
+			IdentificationType:  ptr(model.IdentificationTypeType(typ)),
+			IdentificationValue: ptr(model.IdentificationValueType(value)),
+		}
+	}
+
+	identification := cem.FeatureByProps(model.FeatureTypeEnumTypeIdentification, model.RoleTypeClient)
+	identification.SetData(model.FunctionEnumTypeIdentificationListData, &model.IdentificationListDataType{
+		IdentificationData: []model.IdentificationDataType{id(model.IdentificationTypeEnumTypeUserrfidtag, "1234")},
+	})
+
+	if state := uc.Authorization.State(); state != AuthorizationStateAuthorized {
+		t.Errorf("expected authorized, got %s", state)
+	}
+	if values := lastWrite(); len(values) != 3 || values[0] != 10 {
+		t.Errorf("expected requested limits, got %v", values)
+	}
+
+	// the next EV is held again
+	conn.remote.RemoveByAddress([]model.AddressEntityType{1, 1})
+	identification.EVDisconnect()
+	conn.evConnected(false)
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+
+	identification.SetData(model.FunctionEnumTypeIdentificationListData, &model.IdentificationListDataType{
+		IdentificationData: []model.IdentificationDataType{id(model.IdentificationTypeEnumTypeUserrfidtag, "5678")},
+	})
+
+	if state := uc.Authorization.State(); state != AuthorizationStateDenied {
+		t.Errorf("expected denied, got %s", state)
+	}
+	if values := lastWrite(); values[0] != 0.1 {
+		t.Errorf("expected held limits, got %v", values)
+	}
+
+	history := uc.EVCC.IdentificationHistory()
+	if len(history) != 2 || history[0].Value != "1234" || history[1].Value != "5678" {
+		t.Errorf("unexpected history: %v", history)
+	}
+}
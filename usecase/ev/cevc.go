@@ -0,0 +1,435 @@
+package ev
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// ChargeStrategy is the charging strategy of the EV derived from its energy demand
+type ChargeStrategy string
+
+const (
+	ChargeStrategyUnknown        ChargeStrategy = "unknown"
+	ChargeStrategyNoDemand       ChargeStrategy = "noDemand"
+	ChargeStrategyDirectCharging ChargeStrategy = "directCharging"
+	ChargeStrategyTimedCharging  ChargeStrategy = "timedCharging"
+)
+
+// Demand is the energy demand of the EV
+type Demand struct {
+	Energy   float64       // Wh
+	Duration time.Duration // until the demand should be met, 0 if not timed
+}
+
+// ChargingSlot is a slot of the charging plan
+type ChargingSlot struct {
+	Duration time.Duration
+	MaxValue float64 // W
+	Pricing  float64
+}
+
+// ChargingPlan contains the power limits and incentives sent to the EV
+type ChargingPlan struct {
+	Duration time.Duration
+	Slots    []ChargingSlot
+}
+
+// ChargingPlanDeadline is the time the CEM has for sending a charging plan after the EV requested one
+const ChargingPlanDeadline = 20 * time.Second
+
+// ErrChargingPlanDeadline is reported if no charging plan has been sent before the deadline
+var ErrChargingPlanDeadline = errors.New("charging plan deadline exceeded")
+
+// ChargingPlanProvider creates the charging plan for the EV's demand within the EV's constraints
+type ChargingPlanProvider func(demand Demand, constraints ChargingPlanConstraints) (ChargingPlan, error)
+
+// NegotiationState is the state of the charging plan negotiation
+type NegotiationState string
+
+const (
+	NegotiationStateIdle     NegotiationState = "idle"     // no charging plan requested
+	NegotiationStateRequired NegotiationState = "required" // the EV requested a charging plan
+	NegotiationStateSent     NegotiationState = "sent"     // the charging plan has been sent, waiting for the EV's plan
+	NegotiationStateAccepted NegotiationState = "accepted" // the EV returned its plan for the charging plan
+	NegotiationStateFailed   NegotiationState = "failed"   // no charging plan has been sent before the deadline
+)
+
+// CEVC implements the Coordinated EV Charging use case
+type CEVC struct {
+	useCase
+
+	provider ChargingPlanProvider
+	state    NegotiationState
+	err      error
+	required bool
+	deadline time.Time
+	timer    *time.Timer
+	evPlan   *model.TimeSeriesDataType
+	mux      sync.Mutex
+}
+
+func (u *CEVC) register() error {
+	return u.useCase.register("1.0.0b", []model.UseCaseScenarioSupportType{1, 3, 4, 5, 6, 7, 8}, clientFeatures(model.FeatureTypeEnumTypeTimeSeries, model.FeatureTypeEnumTypeIncentiveTable))
+}
+
+// SetChargingPlanProvider sets the provider used for automatically sending a charging plan when the EV requests one
+func (u *CEVC) SetChargingPlanProvider(provider ChargingPlanProvider) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.provider = provider
+}
+
+// NegotiationState returns the state of the charging plan negotiation and the last error
+func (u *CEVC) NegotiationState() (NegotiationState, error) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	if u.state == "" {
+		return NegotiationStateIdle, u.err
+	}
+
+	return u.state, u.err
+}
+
+// timeSeriesId returns the id of the time series of the given type
+func (u *CEVC) timeSeriesId(f *feature.TimeSeries, typ model.TimeSeriesTypeEnumType) (model.TimeSeriesIdType, bool) {
+	for _, desc := range f.DescriptionListData() {
+		if desc.TimeSeriesId != nil && desc.TimeSeriesType != nil && model.TimeSeriesTypeEnumType(*desc.TimeSeriesType) == typ {
+			return *desc.TimeSeriesId, true
+		}
+	}
+
+	return 0, false
+}
+
+// timeSeries returns the time series of the given type
+func (u *CEVC) timeSeries(typ model.TimeSeriesTypeEnumType) (model.TimeSeriesDataType, error) {
+	f, err := localFeature[*feature.TimeSeries](&u.useCase, model.FeatureTypeEnumTypeTimeSeries)
+	if err != nil {
+		return model.TimeSeriesDataType{}, err
+	}
+
+	if id, ok := u.timeSeriesId(f, typ); ok {
+		for _, item := range f.ListData() {
+			if item.TimeSeriesId != nil && *item.TimeSeriesId == id {
+				return item, nil
+			}
+		}
+	}
+
+	return model.TimeSeriesDataType{}, ErrDataNotAvailable
+}
+
+// EVChargingPlan returns the charging plan returned by the EV
+func (u *CEVC) EVChargingPlan() (ChargingPlan, error) {
+	data, err := u.timeSeries(model.TimeSeriesTypeEnumTypePlan)
+	if err != nil {
+		return ChargingPlan{}, err
+	}
+
+	if len(data.TimeSeriesSlot) == 0 {
+		return ChargingPlan{}, ErrDataNotAvailable
+	}
+
+	var res ChargingPlan
+	for _, item := range data.TimeSeriesSlot {
+		var slot ChargingSlot
+
+		if item.Duration != nil {
+			if slot.Duration, err = model.GetISO8601Duration(*item.Duration); err != nil {
+				return ChargingPlan{}, err
+			}
+		}
+
+		switch {
+		case item.Value != nil:
+			slot.MaxValue = item.Value.GetValue()
+		case item.MaxValue != nil:
+			slot.MaxValue = item.MaxValue.GetValue()
+		}
+
+		res.Duration += slot.Duration
+		res.Slots = append(res.Slots, slot)
+	}
+
+	return res, nil
+}
+
+// EnergyDemand returns the energy demand of the EV
+func (u *CEVC) EnergyDemand() (Demand, error) {
+	data, err := u.timeSeries(model.TimeSeriesTypeEnumTypeSingleDemand)
+	if err != nil {
+		return Demand{}, err
+	}
+
+	if len(data.TimeSeriesSlot) == 0 || data.TimeSeriesSlot[0].Value == nil {
+		return Demand{}, ErrDataNotAvailable
+	}
+
+	slot := data.TimeSeriesSlot[0]
+	res := Demand{Energy: slot.Value.GetValue()}
+
+	if slot.Duration != nil {
+		if res.Duration, err = model.GetISO8601Duration(*slot.Duration); err != nil {
+			return Demand{}, err
+		}
+	}
+
+	return res, nil
+}
+
+// ChargeStrategy returns the charging strategy of the EV
+func (u *CEVC) ChargeStrategy() ChargeStrategy {
+	demand, err := u.EnergyDemand()
+
+	switch {
+	case err != nil:
+		return ChargeStrategyUnknown
+	case demand.Energy <= 0:
+		return ChargeStrategyNoDemand
+	case demand.Duration == 0:
+		// if demand is > 0 and duration is not existing, the EV is not charging via a timer
+		// but either via direct charging enabled or charging to minimum SoC using a profile
+		return ChargeStrategyDirectCharging
+	default:
+		return ChargeStrategyTimedCharging
+	}
+}
+
+// ChargingPlanRequired returns if the EV requests a charging plan
+func (u *CEVC) ChargingPlanRequired() bool {
+	f, err := localFeature[*feature.TimeSeries](&u.useCase, model.FeatureTypeEnumTypeTimeSeries)
+	if err != nil {
+		return false
+	}
+
+	for _, desc := range f.DescriptionListData() {
+		if desc.TimeSeriesType != nil && model.TimeSeriesTypeEnumType(*desc.TimeSeriesType) == model.TimeSeriesTypeEnumTypeConstraints &&
+			desc.UpdateRequired != nil && *desc.UpdateRequired {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteChargingPlan sends the power limits and incentives of the charging plan to the EV.
+// The plan is adjusted to the EV's constraints if these are known.
+// A plan sent after the deadline has been exceeded still recovers the negotiation.
+func (u *CEVC) WriteChargingPlan(plan ChargingPlan) error {
+	ts, err := localFeature[*feature.TimeSeries](&u.useCase, model.FeatureTypeEnumTypeTimeSeries)
+	if err != nil {
+		return err
+	}
+
+	it, err := localFeature[*feature.IncentiveTable](&u.useCase, model.FeatureTypeEnumTypeIncentiveTable)
+	if err != nil {
+		return err
+	}
+
+	rts, err := u.remoteFeature(model.FeatureTypeEnumTypeTimeSeries)
+	if err != nil {
+		return err
+	}
+
+	rit, err := u.remoteFeature(model.FeatureTypeEnumTypeIncentiveTable)
+	if err != nil {
+		return err
+	}
+
+	constraints, err := u.ChargingPlanConstraints()
+	if err != nil && !errors.Is(err, ErrDataNotAvailable) {
+		return err
+	}
+
+	if plan, err = constraints.Apply(plan); err != nil {
+		return err
+	}
+
+	powerPlan := feature.TimeSeriesChargingPlan{Duration: plan.Duration}
+	incentivePlan := feature.IncentiveChargingPlan{Duration: plan.Duration}
+
+	for _, slot := range plan.Slots {
+		powerPlan.Slots = append(powerPlan.Slots, feature.TimeSeriesChargingSlot{
+			Duration: slot.Duration,
+			MaxValue: slot.MaxValue,
+		})
+		incentivePlan.Slots = append(incentivePlan.Slots, feature.IncentiveChargingSlot{
+			Duration: slot.Duration,
+			Pricing:  slot.Pricing,
+		})
+	}
+
+	// the power limits need to be sent before the incentives
+	err = u.write(func(ctx spine.Context) error {
+		if err := ts.WriteTimeSeriesPlanData(ctx, rts, powerPlan); err != nil {
+			return err
+		}
+
+		return it.WriteIncentiveTablePlanData(ctx, rit, incentivePlan)
+	})
+
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	// a failed write keeps the state, including a deadline exceeded meanwhile
+	if err != nil {
+		u.err = err
+		return err
+	}
+
+	u.err = nil
+	u.stopDeadline()
+	u.state = NegotiationStateSent
+
+	return nil
+}
+
+// negotiate sends the provider's charging plan before the deadline
+func (u *CEVC) negotiate(provider ChargingPlanProvider, deadline time.Time) {
+	demand, _ := u.EnergyDemand()
+
+	constraints, err := u.ChargingPlanConstraints()
+	if err != nil && !errors.Is(err, ErrDataNotAvailable) {
+		u.fail(err)
+		return
+	}
+
+	type result struct {
+		plan ChargingPlan
+		err  error
+	}
+
+	resC := make(chan result, 1)
+	go func() {
+		plan, err := provider(demand, constraints)
+		resC <- result{plan, err}
+	}()
+
+	select {
+	case res := <-resC:
+		if res.err != nil {
+			u.fail(res.err)
+			return
+		}
+
+		if err := u.WriteChargingPlan(res.plan); err != nil {
+			u.fail(err)
+		}
+
+	case <-time.After(time.Until(deadline)):
+		// reported by the deadline timer
+	}
+}
+
+// fail records the error of the negotiation
+func (u *CEVC) fail(err error) {
+	u.mux.Lock()
+	u.err = err
+	u.mux.Unlock()
+
+	u.event(EventEVChargingPlanFailed)
+}
+
+// deadlineExceeded fails the negotiation if no charging plan has been sent
+func (u *CEVC) deadlineExceeded() {
+	u.mux.Lock()
+	if u.state != NegotiationStateRequired {
+		u.mux.Unlock()
+		return
+	}
+	u.state = NegotiationStateFailed
+	u.mux.Unlock()
+
+	u.fail(ErrChargingPlanDeadline)
+}
+
+// stopDeadline stops the deadline timer, the caller must hold the lock
+func (u *CEVC) stopDeadline() {
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+}
+
+// updateRequired starts a negotiation when the EV requests a new charging plan
+func (u *CEVC) updateRequired() {
+	required := u.ChargingPlanRequired()
+
+	u.mux.Lock()
+	rising := required && !u.required
+	u.required = required
+
+	provider := u.provider
+	if rising {
+		u.state = NegotiationStateRequired
+		u.err = nil
+		u.deadline = time.Now().Add(ChargingPlanDeadline)
+		u.stopDeadline()
+		u.timer = time.AfterFunc(ChargingPlanDeadline, u.deadlineExceeded)
+	}
+	deadline := u.deadline
+	u.mux.Unlock()
+
+	if !rising {
+		return
+	}
+
+	u.event(EventEVChargingPlanRequired)
+
+	if provider != nil {
+		go u.negotiate(provider, deadline)
+	}
+}
+
+// evPlanChanged detects the plan returned by the EV
+func (u *CEVC) evPlanChanged() {
+	data, err := u.timeSeries(model.TimeSeriesTypeEnumTypePlan)
+	if err != nil || len(data.TimeSeriesSlot) == 0 {
+		return
+	}
+
+	u.mux.Lock()
+	changed := u.evPlan == nil || !reflect.DeepEqual(*u.evPlan, data)
+	u.evPlan = &data
+	if changed && u.state == NegotiationStateSent {
+		u.state = NegotiationStateAccepted
+	}
+	u.mux.Unlock()
+
+	if changed {
+		u.event(EventEVChargingPlanUpdated)
+	}
+}
+
+func (u *CEVC) evConnectionChanged(connected bool) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.stopDeadline()
+	u.state = NegotiationStateIdle
+	u.err = nil
+	u.required = false
+	u.evPlan = nil
+}
+
+func (u *CEVC) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	switch function {
+	case model.FunctionEnumTypeTimeSeriesDescriptionListData:
+		u.updateRequired()
+	case model.FunctionEnumTypeTimeSeriesConstraintsListData, model.FunctionEnumTypeIncentiveTableConstraintsData:
+		u.event(EventEVChargingPlanConstraintsUpdated)
+	case model.FunctionEnumTypeTimeSeriesListData:
+		if _, err := u.EnergyDemand(); err == nil {
+			u.event(EventEVEnergyDemandUpdated)
+		}
+		u.evPlanChanged()
+	}
+}
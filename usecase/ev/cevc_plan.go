@@ -0,0 +1,222 @@
+package ev
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// ErrInvalidChargingPlan is returned if a charging plan can not be made compliant with the EV's constraints
+var ErrInvalidChargingPlan = errors.New("invalid charging plan")
+
+// ChargingPlanConstraints are the constraints of the EV for the charging plan.
+// Zero values are not constrained.
+type ChargingPlanConstraints struct {
+	SlotCountMax     uint
+	SlotDurationMin  time.Duration
+	SlotDurationMax  time.Duration
+	SlotDurationStep time.Duration
+	PowerMin         float64 // W
+	PowerMax         float64 // W
+}
+
+// ChargingPlanConstraints returns the constraints of the power limits and incentives time series
+func (u *CEVC) ChargingPlanConstraints() (ChargingPlanConstraints, error) {
+	ts, err := localFeature[*feature.TimeSeries](&u.useCase, model.FeatureTypeEnumTypeTimeSeries)
+	if err != nil {
+		return ChargingPlanConstraints{}, err
+	}
+
+	var res ChargingPlanConstraints
+	var found bool
+
+	if id, ok := u.timeSeriesId(ts, model.TimeSeriesTypeEnumTypeConstraints); ok {
+		for _, item := range ts.ConstraintsListData() {
+			if item.TimeSeriesId == nil || *item.TimeSeriesId != id {
+				continue
+			}
+
+			found = true
+
+			if item.SlotCountMax != nil {
+				res.SlotCountMax = uint(*item.SlotCountMax)
+			}
+			if res.SlotDurationMin, err = optionalDuration(item.SlotDurationMin); err != nil {
+				return res, err
+			}
+			if res.SlotDurationMax, err = optionalDuration(item.SlotDurationMax); err != nil {
+				return res, err
+			}
+			if res.SlotDurationStep, err = optionalDuration(item.SlotDurationStepSize); err != nil {
+				return res, err
+			}
+			if item.SlotValueMin != nil {
+				res.PowerMin = item.SlotValueMin.GetValue()
+			}
+			if item.SlotValueMax != nil {
+				res.PowerMax = item.SlotValueMax.GetValue()
+			}
+		}
+	}
+
+	// incentives are sent with the same slots as the power limits
+	if it, err := localFeature[*feature.IncentiveTable](&u.useCase, model.FeatureTypeEnumTypeIncentiveTable); err == nil {
+		if data := it.ConstraintsData(); data != nil {
+			for _, item := range data.IncentiveTableConstraints {
+				if item.IncentiveSlotConstraints == nil || item.IncentiveSlotConstraints.SlotCountMax == nil {
+					continue
+				}
+
+				found = true
+
+				if max := uint(*item.IncentiveSlotConstraints.SlotCountMax); res.SlotCountMax == 0 || max < res.SlotCountMax {
+					res.SlotCountMax = max
+				}
+			}
+		}
+	}
+
+	if !found {
+		return res, ErrDataNotAvailable
+	}
+
+	return res, nil
+}
+
+func optionalDuration(value *string) (time.Duration, error) {
+	if value == nil {
+		return 0, nil
+	}
+
+	return model.GetISO8601Duration(*value)
+}
+
+// Apply returns the charging plan adjusted to the constraints:
+// slot durations are rounded to the step size, short slots are merged with their successor,
+// long slots are split, adjacent slots are merged until the slot count fits and power limits are
+// reduced to the permitted range.
+func (c ChargingPlanConstraints) Apply(plan ChargingPlan) (ChargingPlan, error) {
+	var slots []ChargingSlot
+
+	for _, slot := range plan.Slots {
+		if c.SlotDurationStep > 0 {
+			slot.Duration = time.Duration(math.Round(float64(slot.Duration)/float64(c.SlotDurationStep))) * c.SlotDurationStep
+		}
+		if slot.Duration > 0 {
+			slots = append(slots, slot)
+		}
+	}
+
+	if len(slots) == 0 {
+		return ChargingPlan{}, fmt.Errorf("%w: no slots", ErrInvalidChargingPlan)
+	}
+
+	// merge short slots with their successor, the last slot with its predecessor
+	if c.SlotDurationMin > 0 {
+		for i := 0; i < len(slots) && len(slots) > 1; {
+			if slots[i].Duration >= c.SlotDurationMin {
+				i++
+				continue
+			}
+
+			j := i
+			if j == len(slots)-1 {
+				j--
+			}
+			slots = mergeSlots(slots, j)
+		}
+
+		if slots[0].Duration < c.SlotDurationMin {
+			slots[0].Duration = c.SlotDurationMin
+		}
+	}
+
+	// split long slots
+	if max := c.maxSlotDuration(); max > 0 {
+		var split []ChargingSlot
+		for _, slot := range slots {
+			for slot.Duration > max {
+				part := slot
+				part.Duration = max
+				split = append(split, part)
+				slot.Duration -= max
+			}
+			split = append(split, slot)
+		}
+		slots = split
+	}
+
+	// merge the most similar adjacent slots until the slot count fits
+	if c.SlotCountMax > 0 {
+		for uint(len(slots)) > c.SlotCountMax {
+			i := c.mergeCandidate(slots)
+			if i < 0 {
+				// plan covers more time than the EV accepts
+				return ChargingPlan{}, fmt.Errorf("%w: %d slots exceed the maximum of %d", ErrInvalidChargingPlan, len(slots), c.SlotCountMax)
+			}
+			slots = mergeSlots(slots, i)
+		}
+	}
+
+	res := ChargingPlan{Slots: slots}
+	for i := range res.Slots {
+		slot := &res.Slots[i]
+		if c.PowerMax > 0 && slot.MaxValue > c.PowerMax {
+			slot.MaxValue = c.PowerMax
+		}
+		if slot.MaxValue < c.PowerMin {
+			slot.MaxValue = c.PowerMin
+		}
+		res.Duration += slot.Duration
+	}
+
+	return res, nil
+}
+
+// maxSlotDuration returns the maximum slot duration as multiple of the step size
+func (c ChargingPlanConstraints) maxSlotDuration() time.Duration {
+	if c.SlotDurationMax > 0 && c.SlotDurationStep > 0 {
+		return c.SlotDurationMax / c.SlotDurationStep * c.SlotDurationStep
+	}
+
+	return c.SlotDurationMax
+}
+
+// mergeCandidate returns the index of the slot to be merged with its successor, -1 if none can be merged
+func (c ChargingPlanConstraints) mergeCandidate(slots []ChargingSlot) int {
+	res := -1
+	var best float64
+
+	for i := 0; i < len(slots)-1; i++ {
+		if max := c.maxSlotDuration(); max > 0 && slots[i].Duration+slots[i+1].Duration > max {
+			continue
+		}
+
+		diff := math.Abs(slots[i].MaxValue-slots[i+1].MaxValue) + math.Abs(slots[i].Pricing-slots[i+1].Pricing)
+		if res < 0 || diff < best {
+			res, best = i, diff
+		}
+	}
+
+	return res
+}
+
+// mergeSlots merges slot i with its successor. The merged slot uses the lower power limit and
+// the duration weighted pricing.
+func mergeSlots(slots []ChargingSlot, i int) []ChargingSlot {
+	a, b := slots[i], slots[i+1]
+
+	merged := ChargingSlot{
+		Duration: a.Duration + b.Duration,
+		MaxValue: math.Min(a.MaxValue, b.MaxValue),
+		Pricing:  (a.Pricing*float64(a.Duration) + b.Pricing*float64(b.Duration)) / float64(a.Duration+b.Duration),
+	}
+
+	res := append([]ChargingSlot{}, slots[:i]...)
+	res = append(res, merged)
+	return append(res, slots[i+2:]...)
+}
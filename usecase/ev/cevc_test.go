@@ -0,0 +1,186 @@
+package ev
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestChargingPlanConstraintsApply(t *testing.T) {
+	c := ChargingPlanConstraints{
+		SlotCountMax:     3,
+		SlotDurationMin:  15 * time.Minute,
+		SlotDurationMax:  2 * time.Hour,
+		SlotDurationStep: 15 * time.Minute,
+		PowerMax:         11000,
+	}
+
+	plan, err := c.Apply(ChargingPlan{Slots: []ChargingSlot{
+		{Duration: 5 * time.Minute, MaxValue: 4000, Pricing: 0.3},   // rounded away
+		{Duration: 50 * time.Minute, MaxValue: 22000, Pricing: 0.2}, // rounded to 45m, clamped to 11kW
+		{Duration: 3 * time.Hour, MaxValue: 3000, Pricing: 0.1},     // split into 2h + 1h
+		{Duration: time.Hour, MaxValue: 3500, Pricing: 0.1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ChargingSlot{
+		{Duration: 45 * time.Minute, MaxValue: 11000, Pricing: 0.2},
+		{Duration: 2 * time.Hour, MaxValue: 3000, Pricing: 0.1},
+		{Duration: 2 * time.Hour, MaxValue: 3000, Pricing: 0.1},
+	}
+
+	if len(plan.Slots) != len(expected) {
+		t.Fatalf("unexpected slots: %v", plan.Slots)
+	}
+	for i, slot := range plan.Slots {
+		if slot.Duration != expected[i].Duration || slot.MaxValue != expected[i].MaxValue || math.Abs(slot.Pricing-expected[i].Pricing) > 1e-9 {
+			t.Errorf("slot %d: expected %v, got %v", i, expected[i], slot)
+		}
+	}
+	if plan.Duration != 4*time.Hour+45*time.Minute {
+		t.Errorf("unexpected duration: %v", plan.Duration)
+	}
+
+	if _, err := c.Apply(ChargingPlan{}); !errors.Is(err, ErrInvalidChargingPlan) {
+		t.Errorf("expected invalid plan, got %v", err)
+	}
+
+	// slots exceeding the maximum duration can not be merged
+	if _, err := c.Apply(ChargingPlan{Slots: []ChargingSlot{
+		{Duration: 2 * time.Hour, MaxValue: 1000},
+		{Duration: 2 * time.Hour, MaxValue: 2000},
+		{Duration: 2 * time.Hour, MaxValue: 3000},
+		{Duration: 2 * time.Hour, MaxValue: 4000},
+	}}); !errors.Is(err, ErrInvalidChargingPlan) {
+		t.Errorf("expected invalid plan, got %v", err)
+	}
+}
+
+func TestCEVCNegotiation(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{remote: testRemoteDevice(t)}
+
+	ev := conn.remote.Entity([]model.AddressEntityType{1, 1})
+	for _, typ := range []model.FeatureTypeEnumType{model.FeatureTypeEnumTypeTimeSeries, model.FeatureTypeEnumTypeIncentiveTable} {
+		f := &spine.FeatureImpl{Type: typ, Role: model.RoleTypeServer}
+		f.Add(model.FunctionEnumTypeTimeSeriesListData, true, true)
+		f.Add(model.FunctionEnumTypeIncentiveTableData, true, true)
+		ev.Add(f)
+	}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planC := make(chan ChargingPlanConstraints, 1)
+	uc.CEVC.SetChargingPlanProvider(func(demand Demand, constraints ChargingPlanConstraints) (ChargingPlan, error) {
+		planC <- constraints
+		return ChargingPlan{Slots: []ChargingSlot{{Duration: 6 * time.Hour, MaxValue: 11000}}}, nil
+	})
+
+	ts := cem.FeatureByProps(model.FeatureTypeEnumTypeTimeSeries, model.RoleTypeClient)
+
+	id := model.TimeSeriesIdType(1)
+	planId := model.TimeSeriesIdType(2)
+	description := func(updateRequired bool) *model.TimeSeriesDescriptionListDataType {
+		return &model.TimeSeriesDescriptionListDataType{
+			TimeSeriesDescriptionData: []model.TimeSeriesDescriptionDataType{
+				{TimeSeriesId: &id, TimeSeriesType: ptr(model.TimeSeriesTypeType(model.TimeSeriesTypeEnumTypeConstraints)), UpdateRequired: &updateRequired},
+				{TimeSeriesId: &planId, TimeSeriesType: ptr(model.TimeSeriesTypeType(model.TimeSeriesTypeEnumTypePlan))},
+			},
+		}
+	}
+
+	ts.SetData(model.FunctionEnumTypeTimeSeriesConstraintsListData, &model.TimeSeriesConstraintsListDataType{
+		TimeSeriesConstraintsData: []model.TimeSeriesConstraintsDataType{{
+			TimeSeriesId:    &id,
+			SlotCountMax:    ptr(model.TimeSeriesSlotCountType(30)),
+			SlotDurationMax: model.NewISO8601Duration(4 * time.Hour),
+		}},
+	})
+	ts.SetData(model.FunctionEnumTypeTimeSeriesDescriptionListData, description(true))
+
+	select {
+	case constraints := <-planC:
+		if constraints.SlotCountMax != 30 || constraints.SlotDurationMax != 4*time.Hour {
+			t.Errorf("unexpected constraints: %+v", constraints)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("charging plan not requested")
+	}
+
+	waitFor := func(state NegotiationState) {
+		for i := 0; i < 100; i++ {
+			if s, _ := uc.CEVC.NegotiationState(); s == state {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		s, err := uc.CEVC.NegotiationState()
+		t.Fatalf("expected %s, got %s (%v)", state, s, err)
+	}
+
+	waitFor(NegotiationStateSent)
+
+	// the plan has been split according to the slot duration constraint
+	if len(conn.ctx.writes) != 2 {
+		t.Fatalf("expected time series and incentive writes, got %d", len(conn.ctx.writes))
+	}
+	if slots := conn.ctx.writes[0][0].TimeSeriesListData.TimeSeriesData[0].TimeSeriesSlot; len(slots) != 2 {
+		t.Errorf("unexpected slots: %d", len(slots))
+	}
+
+	ts.SetData(model.FunctionEnumTypeTimeSeriesListData, &model.TimeSeriesListDataType{
+		TimeSeriesData: []model.TimeSeriesDataType{{
+			TimeSeriesId:   &planId,
+			TimeSeriesSlot: []model.TimeSeriesSlotType{{Duration: model.NewISO8601Duration(time.Hour), Value: model.NewScaledNumberType(7000)}},
+		}},
+	})
+
+	waitFor(NegotiationStateAccepted)
+
+	plan, err := uc.CEVC.EVChargingPlan()
+	if err != nil || len(plan.Slots) != 1 || plan.Slots[0].MaxValue != 7000 {
+		t.Errorf("unexpected ev plan: %v %v", plan, err)
+	}
+
+	// re-negotiation after updateRequired flips
+	ts.SetData(model.FunctionEnumTypeTimeSeriesDescriptionListData, description(false))
+	ts.SetData(model.FunctionEnumTypeTimeSeriesDescriptionListData, description(true))
+
+	select {
+	case <-planC:
+	case <-time.After(time.Second):
+		t.Fatal("charging plan not renegotiated")
+	}
+
+	waitFor(NegotiationStateSent)
+
+	// plans written after the deadline are sent and recover the negotiation
+	uc.CEVC.mux.Lock()
+	uc.CEVC.state = NegotiationStateFailed
+	uc.CEVC.err = ErrChargingPlanDeadline
+	uc.CEVC.mux.Unlock()
+
+	writes := len(conn.ctx.writes)
+	if err := uc.CEVC.WriteChargingPlan(ChargingPlan{Slots: []ChargingSlot{{Duration: time.Hour, MaxValue: 11000}}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(conn.ctx.writes) == writes {
+		t.Error("plan not written after deadline")
+	}
+	if s, err := uc.CEVC.NegotiationState(); s != NegotiationStateSent || err != nil {
+		t.Errorf("expected %s, got %s (%v)", NegotiationStateSent, s, err)
+	}
+}
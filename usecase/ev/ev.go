@@ -0,0 +1,186 @@
+// Package ev implements the EV charging use cases of the CEM actor on top of the device/feature clients.
+package ev
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// ErrDataNotAvailable is returned if the remote device has not provided the requested data (yet)
+var ErrDataNotAvailable = errors.New("data not available")
+
+// ErrKeyNotChangeable is returned if the remote device does not allow changing the device configuration key
+var ErrKeyNotChangeable = errors.New("key not changeable")
+
+// Connection is the connection to the EVSE, implemented by communication.ConnectionController
+type Connection interface {
+	// GetDevice returns the remote device, nil if not yet discovered
+	GetDevice() spine.Device
+	// WriteBatch calls fn with a context for writing to the remote device
+	WriteBatch(fn func(ctx spine.Context) error) error
+	// AddEVConnectionHandler registers a handler called when an EV has been connected or disconnected
+	AddEVConnectionHandler(handler func(connected bool))
+	// NotifySubscribers sends the cmd to all remote subscribers of the local server feature
+	NotifySubscribers(lf spine.Feature, cmd []model.CmdType) error
+}
+
+// registrar is implemented by the use cases announced by the local device
+type registrar interface {
+	register() error
+}
+
+// dataChangeHandler is implemented by the use cases for updating their state from the cached feature data
+type dataChangeHandler interface {
+	dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType)
+	evConnectionChanged(connected bool)
+}
+
+// UseCases contains the EV use cases of the local CEM entity for a single EVSE connection
+type UseCases struct {
+	EVSECC *EVSECC
+	EVCC   *EVCC
+	EVCEM  *EVCEM
+	EVSoC  *EVSoC
+	OPEV   *OPEV
+	OSCEV  *OSCEV
+	CEVC   *CEVC
+	EVCS   *EVCS
+
+	// Authorization holds charging of connected EVs until they have been authorized by the Authorizer
+	Authorization *Authorization
+	// PhaseSwitching switches between single and three phase charging if supported by the EVSE
+	PhaseSwitching *PhaseSwitching
+	// Failsafe provides the values the EVSE falls back to if the heartbeat is missing
+	Failsafe *Failsafe
+	// LimitManager keeps the desired OPEV and OSCEV limits applied by sending them again
+	LimitManager *LimitManager
+
+	useCases []dataChangeHandler
+	handlers []EventHandler
+	mux      sync.Mutex
+}
+
+// New creates the EV use cases for the CEM entity of the local device
+func New(local spine.Device, conn Connection) (*UseCases, error) {
+	cem := local.EntityByType(model.EntityTypeType(model.EntityTypeEnumTypeCEM))
+	if cem == nil {
+		return nil, fmt.Errorf("ev.New: local entity not found: %s", model.EntityTypeEnumTypeCEM)
+	}
+
+	u := new(UseCases)
+
+	base := func(name model.UseCaseNameEnumType, entityType model.EntityTypeEnumType) useCase {
+		return useCase{
+			name:       name,
+			entityType: entityType,
+			local:      cem,
+			conn:       conn,
+			publish:    u.publish,
+		}
+	}
+
+	u.EVSECC = &EVSECC{useCase: base(model.UseCaseNameEnumTypeEVSECommissioningAndConfiguration, model.EntityTypeEnumTypeEVSE)}
+	u.EVCC = &EVCC{useCase: base(model.UseCaseNameEnumTypeEVCommissioningAndConfiguration, model.EntityTypeEnumTypeEV)}
+	u.EVCEM = &EVCEM{useCase: base(model.UseCaseNameEnumTypeMeasurementOfElectricityDuringEVCharging, model.EntityTypeEnumTypeEV)}
+	u.EVSoC = &EVSoC{useCase: base(model.UseCaseNameEnumTypeEVStateOfCharge, model.EntityTypeEnumTypeEV)}
+	u.OPEV = &OPEV{limits: limits{
+		useCase: base(model.UseCaseNameEnumTypeOverloadProtectionByEVChargingCurrentCurtailment, model.EntityTypeEnumTypeEV),
+		scope:   model.ScopeTypeEnumTypeOverloadProtection,
+	}}
+	u.OSCEV = &OSCEV{limits{
+		useCase: base(model.UseCaseNameEnumTypeOptimizationOfSelfConsumptionDuringEVCharging, model.EntityTypeEnumTypeEV),
+		scope:   model.ScopeTypeEnumTypeSelfConsumption,
+	}}
+	u.CEVC = &CEVC{useCase: base(model.UseCaseNameEnumTypeCoordinatedEVCharging, model.EntityTypeEnumTypeEV)}
+
+	u.EVCS = &EVCS{
+		useCase: base(model.UseCaseNameEnumTypeEVChargingSummary, model.EntityTypeEnumTypeEV),
+		evcem:   u.EVCEM,
+	}
+
+	u.Authorization = &Authorization{evcc: u.EVCC, opev: u.OPEV, state: AuthorizationStateNone}
+	u.OPEV.held = u.Authorization.held
+
+	u.PhaseSwitching = &PhaseSwitching{
+		useCase: base(model.UseCaseNameEnumTypeEVCommissioningAndConfiguration, model.EntityTypeEnumTypeEV),
+		evcem:   u.EVCEM,
+		opev:    u.OPEV,
+	}
+	u.OPEV.phases = u.PhaseSwitching.activePhases
+	u.OSCEV.phases = u.PhaseSwitching.activePhases
+
+	u.Failsafe = &Failsafe{useCase: base(model.UseCaseNameEnumTypeEVSECommissioningAndConfiguration, model.EntityTypeEnumTypeEVSE)}
+
+	u.LimitManager = newLimitManager(u.OPEV, u.OSCEV)
+
+	// the key values of the EVSE are cached apart from the EV's
+	if f, ok := cem.FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeClient).(*feature.DeviceConfiguration); ok {
+		f.EVSE().AddDataChangeHandler(func(function model.FunctionEnumType) {
+			u.Failsafe.dataChanged(model.FeatureTypeEnumTypeDeviceConfiguration, function)
+		})
+	}
+
+	// the limit manager has to follow OPEV and the authorization for writing the limits of a newly connected EV
+	u.useCases = []dataChangeHandler{u.EVSECC, u.EVCC, u.EVCEM, u.EVSoC, u.OPEV, u.OSCEV, u.CEVC, u.EVCS, u.Authorization, u.PhaseSwitching, u.Failsafe, u.LimitManager}
+
+	for _, uc := range []registrar{u.EVSECC, u.EVCC, u.EVCEM, u.EVSoC, u.OPEV, u.OSCEV, u.CEVC, u.EVCS} {
+		if err := uc.register(); err != nil {
+			return nil, fmt.Errorf("ev.New: %w", err)
+		}
+	}
+
+	for _, f := range cem.GetFeatures() {
+		if f.GetRole() != model.RoleTypeClient {
+			continue
+		}
+
+		featureType := f.GetType()
+		f.AddDataChangeHandler(func(function model.FunctionEnumType) {
+			for _, uc := range u.useCases {
+				uc.dataChanged(featureType, function)
+			}
+		})
+	}
+
+	conn.AddEVConnectionHandler(func(connected bool) {
+		for _, uc := range u.useCases {
+			uc.evConnectionChanged(connected)
+		}
+	})
+
+	return u, nil
+}
+
+// AddEventHandler registers a handler for the events of all use cases.
+// The returned function removes the handler.
+func (u *UseCases) AddEventHandler(handler EventHandler) func() {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.handlers = append(u.handlers, handler)
+	i := len(u.handlers) - 1
+
+	return func() {
+		u.mux.Lock()
+		defer u.mux.Unlock()
+
+		u.handlers[i] = nil
+	}
+}
+
+func (u *UseCases) publish(event Event) {
+	u.mux.Lock()
+	handlers := append([]EventHandler(nil), u.handlers...)
+	u.mux.Unlock()
+
+	for _, handler := range handlers {
+		if handler != nil {
+			handler(event)
+		}
+	}
+}
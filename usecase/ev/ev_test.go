@@ -0,0 +1,288 @@
+package ev
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/evcc-io/eebus/communication"
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+var _ Connection = (*communication.ConnectionController)(nil)
+
+type testContext struct {
+	spine.Context
+	writes [][]model.CmdType
+}
+
+func (c *testContext) Write(senderAddress, destinationAddress *model.FeatureAddressType, cmd []model.CmdType) error {
+	c.writes = append(c.writes, cmd)
+	return nil
+}
+
+type testConnection struct {
+	remote   spine.Device
+	ctx      testContext
+	handlers []func(bool)
+	notifies [][]model.CmdType
+}
+
+func (c *testConnection) GetDevice() spine.Device {
+	return c.remote
+}
+
+func (c *testConnection) WriteBatch(fn func(ctx spine.Context) error) error {
+	return fn(&c.ctx)
+}
+
+func (c *testConnection) AddEVConnectionHandler(handler func(connected bool)) {
+	c.handlers = append(c.handlers, handler)
+}
+
+// evConnected calls the EV connection handlers
+func (c *testConnection) evConnected(connected bool) {
+	for _, handler := range c.handlers {
+		handler(connected)
+	}
+}
+
+func (c *testConnection) NotifySubscribers(lf spine.Feature, cmd []model.CmdType) error {
+	c.notifies = append(c.notifies, cmd)
+	return nil
+}
+
+func testRemoteDevice(t *testing.T) spine.Device {
+	var data model.NodeManagementDetailedDiscoveryDataType
+	if err := json.Unmarshal([]byte(`[
+		{"deviceInformation":[{"description":[{"deviceAddress":[{"device":"d:_i:EVSE"}]},{"deviceType":"ChargingStation"}]}]},
+		{"entityInformation":[
+			[{"description":[{"entityAddress":[{"entity":[1]}]},{"entityType":"EVSE"}]}],
+			[{"description":[{"entityAddress":[{"entity":[1,1]}]},{"entityType":"EV"}]}]
+		]},
+		{"featureInformation":[
+			[{"description":[{"featureAddress":[{"entity":[1,1]},{"feature":1}]},{"featureType":"LoadControl"},{"role":"server"},{"supportedFunction":[[{"function":"loadControlLimitListData"},{"possibleOperations":[{"read":[]},{"write":[]}]}]]}]}],
+			[{"description":[{"featureAddress":[{"entity":[1,1]},{"feature":2}]},{"featureType":"DeviceConfiguration"},{"role":"server"},{"supportedFunction":[[{"function":"deviceConfigurationKeyValueListData"},{"possibleOperations":[{"read":[]},{"write":[]}]}]]}]}],
+			[{"description":[{"featureAddress":[{"entity":[1]},{"feature":3}]},{"featureType":"DeviceConfiguration"},{"role":"server"},{"supportedFunction":[[{"function":"deviceConfigurationKeyValueListData"},{"possibleOperations":[{"read":[]},{"write":[]}]}]]}]}]
+		]}
+	]`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	dev := spine.UnmarshalDevice(data)
+	for _, ei := range data.EntityInformation {
+		dev.Add(spine.UnmarshalEntity(dev.GetAddress(), ei))
+	}
+
+	for _, fi := range data.FeatureInformation {
+		dev.Entity(fi.Description.FeatureAddress.Entity).Add(spine.UnmarshalFeature(fi))
+	}
+
+	return dev
+}
+
+// setTestLimitData provides the current measurements, permitted currents and overload protection limits of three phases
+func setTestLimitData(cem spine.Entity) {
+	ec := cem.FeatureByProps(model.FeatureTypeEnumTypeElectricalConnection, model.RoleTypeClient)
+	m := cem.FeatureByProps(model.FeatureTypeEnumTypeMeasurement, model.RoleTypeClient)
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+
+	var params []model.ElectricalConnectionParameterDescriptionDataType
+	var permitted []model.ElectricalConnectionPermittedValueSetDataType
+	var descriptions []model.MeasurementDescriptionDataType
+	var limits []model.LoadControlLimitDescriptionDataType
+
+	for i, phase := range []model.ElectricalConnectionPhaseNameType{"a", "b", "c"} {
+		id := model.MeasurementIdType(i)
+		params = append(params, model.ElectricalConnectionParameterDescriptionDataType{
+			ParameterId:      ptr(model.ElectricalConnectionParameterIdType(i)),
+			MeasurementId:    &id,
+			AcMeasuredPhases: ptr(phase),
+		})
+		permitted = append(permitted, model.ElectricalConnectionPermittedValueSetDataType{
+			ParameterId: ptr(model.ElectricalConnectionParameterIdType(i)),
+			PermittedValueSet: []model.ScaledNumberSetType{{
+				Value: []model.ScaledNumberType{*model.NewScaledNumberType(0.1)},
+				Range: []model.ScaledNumberRangeType{{Min: model.NewScaledNumberType(6), Max: model.NewScaledNumberType(16)}},
+			}},
+		})
+		descriptions = append(descriptions, model.MeasurementDescriptionDataType{
+			MeasurementId: &id,
+			ScopeType:     ptr(model.ScopeTypeType(model.ScopeTypeEnumTypeACCurrent)),
+		})
+		limits = append(limits, model.LoadControlLimitDescriptionDataType{
+			LimitId:       ptr(model.LoadControlLimitIdType(i + 1)),
+			MeasurementId: &id,
+			ScopeType:     ptr(model.ScopeTypeType(model.ScopeTypeEnumTypeOverloadProtection)),
+		})
+	}
+
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionParameterDescriptionListData, &model.ElectricalConnectionParameterDescriptionListDataType{
+		ElectricalConnectionParameterDescriptionData: params,
+	})
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData, &model.ElectricalConnectionPermittedValueSetListDataType{
+		ElectricalConnectionPermittedValueSetData: permitted,
+	})
+	m.SetData(model.FunctionEnumTypeMeasurementDescriptionListData, &model.MeasurementDescriptionListDataType{
+		MeasurementDescriptionData: descriptions,
+	})
+	m.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{
+		MeasurementData: []model.MeasurementDataType{
+			{MeasurementId: ptr(model.MeasurementIdType(0)), Value: model.NewScaledNumberType(10)},
+			{MeasurementId: ptr(model.MeasurementIdType(2)), Value: model.NewScaledNumberType(12)},
+		},
+	})
+	lc.SetData(model.FunctionEnumTypeLoadControlLimitDescriptionListData, &model.LoadControlLimitDescriptionListDataType{
+		LoadControlLimitDescriptionData: limits,
+	})
+}
+
+func TestUseCases(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	uc.AddEventHandler(func(e Event) {
+		events = append(events, e)
+	})
+
+	if _, err := uc.EVSoC.SoC(); !errors.Is(err, spine.ErrNotConnected) {
+		t.Errorf("expected not connected, got %v", err)
+	}
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+
+	if !uc.EVCC.EVConnected() {
+		t.Error("ev not connected")
+	}
+	if len(events) != 1 || events[0].Type != EventEVConnected {
+		t.Errorf("unexpected events: %v", events)
+	}
+
+	setTestLimitData(cem)
+
+	currents, err := uc.EVCEM.CurrentPerPhase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(currents) != 3 || currents[0] != 10 || currents[1] != 0 || currents[2] != 12 {
+		t.Errorf("unexpected currents: %v", currents)
+	}
+
+	currentLimits, err := uc.OPEV.CurrentLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(currentLimits) != 3 || currentLimits[1] != (CurrentLimit{Phase: 2, Min: 6, Max: 16, Default: 0.1}) {
+		t.Errorf("unexpected current limits: %v", currentLimits)
+	}
+
+	// minimum currents are not raised above the maximum currents
+	ec := cem.FeatureByProps(model.FeatureTypeEnumTypeElectricalConnection, model.RoleTypeClient)
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData, &model.ElectricalConnectionPermittedValueSetListDataType{
+		ElectricalConnectionPermittedValueSetData: []model.ElectricalConnectionPermittedValueSetDataType{{
+			ParameterId: ptr(model.ElectricalConnectionParameterIdType(1)),
+			PermittedValueSet: []model.ScaledNumberSetType{{
+				Value: []model.ScaledNumberType{*model.NewScaledNumberType(0)},
+				Range: []model.ScaledNumberRangeType{{Min: model.NewScaledNumberType(0), Max: model.NewScaledNumberType(4)}},
+			}},
+		}},
+	})
+
+	currentLimits, err = uc.OPEV.CurrentLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(currentLimits) != 1 || currentLimits[0] != (CurrentLimit{Phase: 2, Min: 4, Max: 4}) {
+		t.Errorf("unexpected current limits: %v", currentLimits)
+	}
+
+	setTestLimitData(cem)
+
+	// currents outside of the permitted currents are rejected instead of being adjusted
+	var limitErr *LimitsError
+	if err := uc.OPEV.WriteOverloadLimits([]float64{20, 3, 10}); !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitOutOfRange) {
+		t.Errorf("expected out of range limits, got %v", err)
+	}
+	if len(conn.ctx.writes) != 0 {
+		t.Fatalf("unexpected write of invalid limits")
+	}
+
+	if err := uc.OPEV.WriteOverloadLimits([]float64{10, 0, 10}); !errors.Is(err, ErrAsymmetricLimitsNotSupported) {
+		t.Errorf("expected asymmetric limits not supported, got %v", err)
+	}
+
+	if err := uc.OPEV.WriteOverloadLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.ctx.writes) != 1 {
+		t.Fatalf("expected single write, got %d", len(conn.ctx.writes))
+	}
+
+	var values []float64
+	for _, item := range conn.ctx.writes[0][0].LoadControlLimitListData.LoadControlLimitData {
+		values = append(values, item.Value.GetValue())
+	}
+	if len(values) != 3 || values[0] != 10 || values[1] != 10 || values[2] != 10 {
+		t.Errorf("unexpected limits written: %v", values)
+	}
+
+	if err := uc.OSCEV.WriteRecommendationLimits([]float64{10}); !errors.Is(err, ErrDataNotAvailable) {
+		t.Errorf("expected no self consumption limits, got %v", err)
+	}
+
+	conn.remote.RemoveByAddress([]model.AddressEntityType{1, 1})
+	conn.evConnected(false)
+
+	if _, err := uc.EVCEM.CurrentPerPhase(); !errors.Is(err, spine.ErrNoEV) {
+		t.Errorf("expected no ev, got %v", err)
+	}
+	if last := events[len(events)-1]; last.Type != EventEVDisconnected {
+		t.Errorf("unexpected last event: %v", last)
+	}
+}
+
+func TestUseCaseRegistration(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	local.Add(entity.CEM())
+
+	if _, err := New(local, &testConnection{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(local.UseCases()) != 8 {
+		t.Fatalf("expected 8 use cases, got %d", len(local.UseCases()))
+	}
+
+	for _, uc := range local.UseCases() {
+		if uc.Actor != model.UseCaseActorEnumTypeCEM || uc.Version == "" || len(uc.Scenarios) == 0 || !uc.Available {
+			t.Errorf("unexpected use case: %+v", uc)
+		}
+	}
+
+	// the use cases of another connection keep the availability
+	if err := local.SetUseCaseAvailable(model.UseCaseActorEnumTypeCEM, model.UseCaseNameEnumTypeCoordinatedEVCharging, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(local, &testConnection{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uc := range local.UseCases() {
+		if uc.Name == model.UseCaseNameEnumTypeCoordinatedEVCharging && uc.Available {
+			t.Error("expected use case to remain unavailable")
+		}
+	}
+}
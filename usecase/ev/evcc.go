@@ -0,0 +1,201 @@
+package ev
+
+import (
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// CommunicationStandardIEC61851 is the communication standard assumed if the EV does not report one
+const CommunicationStandardIEC61851 = "iec61851"
+
+// Identification is an identification of the EV, e.g. its MAC address
+type Identification struct {
+	Type  model.IdentificationTypeEnumType
+	Value string
+}
+
+// IdentificationHistoryMax is the number of identifications kept in the history of the EVSE
+const IdentificationHistoryMax = 20
+
+// IdentificationRecord is an identification reported by the EVSE
+type IdentificationRecord struct {
+	Identification
+	Time time.Time // time the identification has first been reported for the connected EV
+}
+
+// PowerLimits are the charging power limits of the EV in W
+type PowerLimits struct {
+	Min, Max, Standby float64
+}
+
+// EVCC implements the EV Commissioning and Configuration use case
+type EVCC struct {
+	useCase
+	state   entityState
+	seen    map[Identification]bool // identifications of the connected EV
+	history []IdentificationRecord
+	mux     sync.Mutex
+}
+
+func (u *EVCC) register() error {
+	return u.useCase.register("1.0.1", []model.UseCaseScenarioSupportType{1, 2, 3, 4, 5, 6, 7, 8}, clientFeatures(model.FeatureTypeEnumTypeDeviceConfiguration, model.FeatureTypeEnumTypeIdentification, model.FeatureTypeEnumTypeDeviceClassification, model.FeatureTypeEnumTypeElectricalConnection, model.FeatureTypeEnumTypeDeviceDiagnosis))
+}
+
+// EVConnected returns if an EV is connected to the EVSE
+func (u *EVCC) EVConnected() bool {
+	_, err := u.remoteEntity()
+	return err == nil
+}
+
+// Manufacturer returns the manufacturer data of the EV
+func (u *EVCC) Manufacturer() (model.DeviceClassificationManufacturerDataType, error) {
+	return u.manufacturer()
+}
+
+// OperatingState returns the operating state of the EV
+func (u *EVCC) OperatingState() (model.DeviceDiagnosisOperatingStateType, error) {
+	return u.operatingState()
+}
+
+// CommunicationStandard returns the communication standard between EVSE and EV, e.g. iso15118-2ed1
+func (u *EVCC) CommunicationStandard() (string, error) {
+	return communicationStandard(&u.useCase)
+}
+
+// communicationStandard reads the communication standard from the EV's device configuration
+func communicationStandard(u *useCase) (string, error) {
+	f, err := localFeature[*feature.DeviceConfiguration](u, model.FeatureTypeEnumTypeDeviceConfiguration)
+	if err != nil {
+		return "", err
+	}
+
+	if f.KeyValueListData() == nil {
+		return "", ErrDataNotAvailable
+	}
+
+	value, ok := keyValue(f, model.DeviceConfigurationKeyNameEnumTypeCommunicationsStandard)
+	if !ok || value.String == nil {
+		return CommunicationStandardIEC61851, nil
+	}
+
+	return string(*value.String), nil
+}
+
+// AsymmetricChargingSupported returns if the EV supports different limits per phase
+func (u *EVCC) AsymmetricChargingSupported() (bool, error) {
+	return u.asymmetricChargingSupported()
+}
+
+// Identifications returns the identifications of the EV
+func (u *EVCC) Identifications() ([]Identification, error) {
+	f, err := localFeature[*feature.Identification](&u.useCase, model.FeatureTypeEnumTypeIdentification)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Identification
+	for _, item := range f.ListData() {
+		if item.IdentificationValue == nil || *item.IdentificationValue == "" {
+			continue
+		}
+
+		id := Identification{Value: string(*item.IdentificationValue)}
+		if item.IdentificationType != nil {
+			id.Type = model.IdentificationTypeEnumType(*item.IdentificationType)
+		}
+
+		res = append(res, id)
+	}
+
+	if len(res) == 0 {
+		return nil, ErrDataNotAvailable
+	}
+
+	return res, nil
+}
+
+// IdentificationHistory returns the identifications of the EVs connected to the EVSE, oldest first
+func (u *EVCC) IdentificationHistory() []IdentificationRecord {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	return append([]IdentificationRecord(nil), u.history...)
+}
+
+// updateHistory adds the identifications not yet reported for the connected EV to the history
+func (u *EVCC) updateHistory(ids []Identification) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	if u.seen == nil {
+		u.seen = make(map[Identification]bool)
+	}
+
+	for _, id := range ids {
+		if u.seen[id] {
+			continue
+		}
+
+		u.seen[id] = true
+		u.history = append(u.history, IdentificationRecord{Identification: id, Time: time.Now()})
+	}
+
+	if len(u.history) > IdentificationHistoryMax {
+		u.history = u.history[len(u.history)-IdentificationHistoryMax:]
+	}
+}
+
+// ChargingPowerLimits returns the total charging power limits of the EV
+func (u *EVCC) ChargingPowerLimits() (PowerLimits, error) {
+	f, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return PowerLimits{}, err
+	}
+
+	for _, item := range f.ParameterDescriptionListData() {
+		if item.ParameterId == nil || item.ScopeType == nil || model.ScopeTypeEnumType(*item.ScopeType) != model.ScopeTypeEnumTypeACPowerTotal {
+			continue
+		}
+
+		if standby, min, max, ok := permittedValues(f, *item.ParameterId); ok {
+			return PowerLimits{Min: min, Max: max, Standby: standby}, nil
+		}
+	}
+
+	return PowerLimits{}, ErrDataNotAvailable
+}
+
+func (u *EVCC) evConnectionChanged(connected bool) {
+	u.state.reset()
+
+	u.mux.Lock()
+	u.seen = nil
+	u.mux.Unlock()
+
+	if connected {
+		u.event(EventEVConnected)
+	} else {
+		u.event(EventEVDisconnected)
+	}
+}
+
+func (u *EVCC) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	u.entityStateChanged(&u.state, featureType, EventEVManufacturerUpdated, EventEVOperatingStateUpdated)
+
+	switch function {
+	case model.FunctionEnumTypeDeviceConfigurationKeyValueListData:
+		u.event(EventEVConfigurationUpdated)
+	case model.FunctionEnumTypeIdentificationListData:
+		if ids, err := u.Identifications(); err == nil {
+			u.updateHistory(ids)
+		}
+		u.event(EventEVIdentificationsUpdated)
+	case model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData:
+		if _, err := u.ChargingPowerLimits(); err == nil {
+			u.event(EventEVChargingPowerLimitUpdated)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ev
+
+import (
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// EVCEM implements the Measurement of Electricity during EV Charging use case
+type EVCEM struct {
+	useCase
+}
+
+func (u *EVCEM) register() error {
+	return u.useCase.register("1.0.1", []model.UseCaseScenarioSupportType{1, 2, 3}, clientFeatures(model.FeatureTypeEnumTypeMeasurement, model.FeatureTypeEnumTypeElectricalConnection))
+}
+
+// PhasesConnected returns the number of phases the EV is connected with
+func (u *EVCEM) PhasesConnected() (uint, error) {
+	f, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range f.DescriptionListData() {
+		if item.AcConnectedPhases != nil {
+			return *item.AcConnectedPhases, nil
+		}
+	}
+
+	return 0, ErrDataNotAvailable
+}
+
+// CurrentPerPhase returns the charging currents in A, the first value is phase 1
+func (u *EVCEM) CurrentPerPhase() ([]float64, error) {
+	return u.valuesPerPhase(model.ScopeTypeEnumTypeACCurrent)
+}
+
+// PowerPerPhase returns the charging power in W, the first value is phase 1
+func (u *EVCEM) PowerPerPhase() ([]float64, error) {
+	return u.valuesPerPhase(model.ScopeTypeEnumTypeACPower)
+}
+
+// EnergyCharged returns the energy charged in the current session in Wh
+func (u *EVCEM) EnergyCharged() (float64, error) {
+	m, err := localFeature[*feature.Measurement](&u.useCase, model.FeatureTypeEnumTypeMeasurement)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range measurementIds(m, model.ScopeTypeEnumTypeCharge) {
+		if item, ok := measurementValue(m, id); ok {
+			return item.Value.GetValue(), nil
+		}
+	}
+
+	return 0, ErrDataNotAvailable
+}
+
+// valuesPerPhase returns the single phase measurements of the scope ordered by phase
+func (u *EVCEM) valuesPerPhase(scope model.ScopeTypeEnumType) ([]float64, error) {
+	m, err := localFeature[*feature.Measurement](&u.useCase, model.FeatureTypeEnumTypeMeasurement)
+	if err != nil {
+		return nil, err
+	}
+
+	ec, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	params := ec.ParameterDescriptionListData()
+
+	var res []float64
+	for _, id := range measurementIds(m, scope) {
+		phase := phaseForMeasurement(params, id)
+		if phase == 0 {
+			continue
+		}
+
+		item, ok := measurementValue(m, id)
+		if !ok {
+			continue
+		}
+
+		for len(res) < int(phase) {
+			res = append(res, 0)
+		}
+		res[phase-1] = item.Value.GetValue()
+	}
+
+	if len(res) == 0 {
+		return nil, ErrDataNotAvailable
+	}
+
+	return res, nil
+}
+
+func (u *EVCEM) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	switch function {
+	case model.FunctionEnumTypeElectricalConnectionDescriptionListData:
+		u.event(EventEVPhasesUpdated)
+	case model.FunctionEnumTypeMeasurementListData:
+		u.event(EventEVMeasurementsUpdated)
+	}
+}
@@ -0,0 +1,301 @@
+package ev
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// SessionHistoryMax is the number of charging sessions kept in the history and provided as bills
+const SessionHistoryMax = 50
+
+// Session is the summary of a charging session
+type Session struct {
+	Id                uint      `json:"id"`
+	Start             time.Time `json:"start"`
+	End               time.Time `json:"end"`
+	Energy            float64   `json:"energy"` // Wh
+	Cost              float64   `json:"cost"`   // total cost in Currency
+	Currency          string    `json:"currency,omitempty"`
+	SelfProducedShare float64   `json:"selfProducedShare"` // percentage of the energy produced locally
+}
+
+// SessionCost is the cost and origin of the energy of a charging session
+type SessionCost struct {
+	Cost              float64
+	Currency          string
+	SelfProducedShare float64 // %
+}
+
+// SessionCostProvider calculates the cost of a finished charging session
+type SessionCostProvider func(Session) (SessionCost, error)
+
+// SessionStore persists the charging session history so that it is available across restarts
+type SessionStore interface {
+	Load() ([]Session, error)
+	Save([]Session) error
+}
+
+// NewFileSessionStore returns a session store persisting the sessions as JSON file
+func NewFileSessionStore(path string) SessionStore {
+	return spine.NewFileStore[[]Session](path)
+}
+
+// EVCS implements the EV Charging Summary use case.
+// A session starts when the EV is connected and is published as bill on the local Bill server when the EV is disconnected.
+type EVCS struct {
+	useCase
+	evcem    *EVCEM
+	provider SessionCostProvider
+	store    SessionStore
+	current  *Session
+	history  []Session
+	mux      sync.Mutex
+}
+
+func (u *EVCS) register() error {
+	return u.useCase.register("1.0.0", []model.UseCaseScenarioSupportType{1}, []spine.UseCaseFeature{{Type: model.FeatureTypeEnumTypeBill, Role: model.RoleTypeServer}})
+}
+
+// SetSessionCostProvider sets the provider for the cost and self-produced share of finished sessions
+func (u *EVCS) SetSessionCostProvider(provider SessionCostProvider) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.provider = provider
+}
+
+// SetSessionStore restores the session history from the store and persists finished sessions
+func (u *EVCS) SetSessionStore(store SessionStore) error {
+	history, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("evcs.SetSessionStore: %w", err)
+	}
+
+	u.mux.Lock()
+	u.store = store
+	u.history = trimHistory(history)
+	u.mux.Unlock()
+
+	return u.publishBills()
+}
+
+// CurrentSession returns the session of the connected EV
+func (u *EVCS) CurrentSession() (Session, error) {
+	if _, err := u.remoteEntity(); err != nil {
+		return Session{}, err
+	}
+
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	if u.current == nil {
+		return Session{}, ErrDataNotAvailable
+	}
+
+	return *u.current, nil
+}
+
+// Sessions returns the history of finished sessions, oldest first
+func (u *EVCS) Sessions() []Session {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	return append([]Session(nil), u.history...)
+}
+
+func trimHistory(history []Session) []Session {
+	if len(history) > SessionHistoryMax {
+		history = history[len(history)-SessionHistoryMax:]
+	}
+
+	return history
+}
+
+func (u *EVCS) evConnectionChanged(connected bool) {
+	if connected {
+		u.mux.Lock()
+		u.current = &Session{Start: time.Now()}
+		u.mux.Unlock()
+
+		return
+	}
+
+	if err := u.finishSession(); err != nil {
+		u.event(EventEVChargingSummaryFailed)
+	}
+}
+
+func (u *EVCS) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	if function != model.FunctionEnumTypeMeasurementListData {
+		return
+	}
+
+	// the energy is only available while the EV is connected
+	energy, err := u.evcem.EnergyCharged()
+	if err != nil {
+		return
+	}
+
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	if u.current != nil {
+		u.current.Energy = energy
+	}
+}
+
+// finishSession adds the current session to the history if energy has been charged
+func (u *EVCS) finishSession() error {
+	u.mux.Lock()
+
+	session := u.current
+	u.current = nil
+
+	if session == nil || session.Energy <= 0 {
+		u.mux.Unlock()
+		return nil
+	}
+
+	session.End = time.Now()
+	if n := len(u.history); n > 0 {
+		session.Id = u.history[n-1].Id + 1
+	}
+
+	provider := u.provider
+	u.mux.Unlock()
+
+	var err error
+	if provider != nil {
+		var cost SessionCost
+		if cost, err = provider(*session); err == nil {
+			session.Cost = cost.Cost
+			session.Currency = cost.Currency
+			session.SelfProducedShare = cost.SelfProducedShare
+		} else {
+			err = fmt.Errorf("evcs.finishSession: cost: %w", err)
+		}
+	}
+
+	u.mux.Lock()
+	u.history = trimHistory(append(u.history, *session))
+	history := append([]Session(nil), u.history...)
+	store := u.store
+	u.mux.Unlock()
+
+	if store != nil {
+		if serr := store.Save(history); serr != nil && err == nil {
+			err = fmt.Errorf("evcs.finishSession: store: %w", serr)
+		}
+	}
+
+	// the session is published even if cost or persistence failed
+	if perr := u.publishBills(); perr != nil && err == nil {
+		err = perr
+	}
+
+	u.event(EventEVChargingSummaryUpdated)
+
+	return err
+}
+
+// publishBills provides the session history as bills on the local Bill server and notifies its subscribers
+func (u *EVCS) publishBills() error {
+	f, ok := u.local.FeatureByProps(model.FeatureTypeEnumTypeBill, model.RoleTypeServer).(*feature.Bill)
+	if !ok {
+		return fmt.Errorf("evcs.publishBills: %w: %s on local device", ErrDataNotAvailable, model.FeatureTypeEnumTypeBill)
+	}
+
+	history := u.Sessions()
+
+	var bills []model.BillDataType
+	var descriptions []model.BillDescriptionDataType
+	for _, s := range history {
+		bills = append(bills, s.bill())
+		descriptions = append(descriptions, model.BillDescriptionDataType{
+			BillId:            billId(s.Id),
+			BillWriteable:     ptr(false),
+			SupportedBillType: []string{string(model.BillTypeEnumTypeChargingsummary)},
+		})
+	}
+
+	data := &model.BillListDataType{BillData: bills}
+	f.SetData(model.FunctionEnumTypeBillDescriptionListData, &model.BillDescriptionListDataType{BillDescriptionData: descriptions})
+	f.SetData(model.FunctionEnumTypeBillListData, data)
+
+	// bills are provided before the EVSE is connected
+	if u.conn.GetDevice() == nil {
+		return nil
+	}
+
+	if err := u.conn.NotifySubscribers(f, []model.CmdType{{BillListData: data}}); err != nil {
+		return fmt.Errorf("evcs.publishBills: %w", err)
+	}
+
+	return nil
+}
+
+func billId(id uint) *model.BillIdType {
+	res := model.BillIdType(id)
+	return &res
+}
+
+// bill returns the charging summary of the session. The total contains the charged energy
+// and cost, the positions split the energy into grid and self-produced energy.
+func (s Session) bill() model.BillDataType {
+	period := &model.TimePeriodType{
+		StartTime: ptr(s.Start.UTC().Format(time.RFC3339)),
+		EndTime:   ptr(s.End.UTC().Format(time.RFC3339)),
+	}
+
+	energy := func(id uint, share float64) model.BillValueType {
+		valueId := model.BillValueIdType(id)
+		return model.BillValueType{
+			ValueId:         &valueId,
+			Unit:            string(model.UnitOfMeasurementEnumTypeWh),
+			Value:           model.NewScaledNumberType(s.Energy * share / 100),
+			ValuePercentage: model.NewScaledNumberType(share),
+		}
+	}
+
+	total := &model.BillPositionType{
+		TimePeriod: period,
+		Value:      []model.BillValueType{energy(0, 100)},
+	}
+
+	if s.Currency != "" {
+		costId := model.BillCostIdType(0)
+		valueId := model.BillValueIdType(0)
+		total.Cost = []model.BillCostType{{
+			CostId:   &costId,
+			CostType: string(model.BillCostTypeEnumTypeAbsoluteprice),
+			ValueId:  &valueId,
+			Currency: s.Currency,
+			Cost:     model.NewScaledNumberType(s.Cost),
+		}}
+	}
+
+	position := func(id uint, typ model.BillPositionTypeEnumType, share float64) model.BillPositionType {
+		positionId := model.BillPositionIdType(id)
+		return model.BillPositionType{
+			PositionId:   &positionId,
+			PositionType: string(typ),
+			TimePeriod:   period,
+			Value:        []model.BillValueType{energy(0, share)},
+		}
+	}
+
+	return model.BillDataType{
+		BillId:   billId(s.Id),
+		BillType: string(model.BillTypeEnumTypeChargingsummary),
+		Total:    total,
+		Position: []model.BillPositionType{
+			position(1, model.BillPositionTypeEnumTypeGridelectricenergy, 100-s.SelfProducedShare),
+			position(2, model.BillPositionTypeEnumTypeSelfproducedelectricenergy, s.SelfProducedShare),
+		},
+	}
+}
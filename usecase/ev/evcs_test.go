@@ -0,0 +1,93 @@
+package ev
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestEVCSSession(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileSessionStore(filepath.Join(t.TempDir(), "sessions.json"))
+	if err := uc.EVCS.SetSessionStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	uc.EVCS.SetSessionCostProvider(func(s Session) (SessionCost, error) {
+		return SessionCost{Cost: s.Energy / 1e3 * 0.3, Currency: "EUR", SelfProducedShare: 40}, nil
+	})
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+
+	m := cem.FeatureByProps(model.FeatureTypeEnumTypeMeasurement, model.RoleTypeClient)
+	m.SetData(model.FunctionEnumTypeMeasurementDescriptionListData, &model.MeasurementDescriptionListDataType{
+		MeasurementDescriptionData: []model.MeasurementDescriptionDataType{{
+			MeasurementId: ptr(model.MeasurementIdType(0)),
+			ScopeType:     ptr(model.ScopeTypeType(model.ScopeTypeEnumTypeCharge)),
+		}},
+	})
+	m.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{
+		MeasurementData: []model.MeasurementDataType{{MeasurementId: ptr(model.MeasurementIdType(0)), Value: model.NewScaledNumberType(10000)}},
+	})
+
+	if s, err := uc.EVCS.CurrentSession(); err != nil || s.Energy != 10000 {
+		t.Errorf("unexpected current session: %v %v", s, err)
+	}
+
+	conn.remote.RemoveByAddress([]model.AddressEntityType{1, 1})
+	conn.evConnected(false)
+
+	sessions := uc.EVCS.Sessions()
+	if len(sessions) != 1 || sessions[0].Energy != 10000 || sessions[0].Cost != 3 || sessions[0].SelfProducedShare != 40 {
+		t.Fatalf("unexpected sessions: %v", sessions)
+	}
+
+	if len(conn.notifies) != 1 || len(conn.notifies[0][0].BillListData.BillData) != 1 {
+		t.Fatalf("unexpected notifies: %v", conn.notifies)
+	}
+
+	bill := conn.notifies[0][0].BillListData.BillData[0]
+	if bill.Total.Cost[0].Cost.GetValue() != 3 || bill.Total.Value[0].Value.GetValue() != 10000 {
+		t.Errorf("unexpected total: %+v", bill.Total)
+	}
+	if len(bill.Position) != 2 || bill.Position[1].Value[0].Value.GetValue() != 4000 {
+		t.Errorf("unexpected positions: %+v", bill.Position)
+	}
+
+	// the history is restored from the store
+	local2 := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem2 := entity.CEM()
+	local2.Add(cem2)
+
+	uc2, err := New(local2, &testConnection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc2.EVCS.SetSessionStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if sessions := uc2.EVCS.Sessions(); len(sessions) != 1 || sessions[0].Energy != 10000 {
+		t.Errorf("unexpected restored sessions: %v", sessions)
+	}
+
+	f := cem2.FeatureByProps(model.FeatureTypeEnumTypeBill, model.RoleTypeServer).(*feature.Bill)
+	if len(f.ListData()) != 1 || len(f.DescriptionListData()) != 1 {
+		t.Errorf("bills not restored: %v", f.ListData())
+	}
+}
@@ -0,0 +1,66 @@
+package ev
+
+import "github.com/evcc-io/eebus/spine/model"
+
+// EventType describes which data of a use case has changed
+type EventType string
+
+const (
+	// EVSECC
+	EventEVSEManufacturerUpdated   EventType = "evseManufacturerUpdated"
+	EventEVSEOperatingStateUpdated EventType = "evseOperatingStateUpdated"
+
+	// Failsafe
+	EventEVSEFailsafeUpdated EventType = "evseFailsafeUpdated"
+
+	// EVCC
+	EventEVConnected                 EventType = "evConnected"
+	EventEVDisconnected              EventType = "evDisconnected"
+	EventEVManufacturerUpdated       EventType = "evManufacturerUpdated"
+	EventEVOperatingStateUpdated     EventType = "evOperatingStateUpdated"
+	EventEVConfigurationUpdated      EventType = "evConfigurationUpdated"
+	EventEVIdentificationsUpdated    EventType = "evIdentificationsUpdated"
+	EventEVChargingPowerLimitUpdated EventType = "evChargingPowerLimitUpdated"
+	EventEVAuthorizationPending      EventType = "evAuthorizationPending"
+	EventEVAuthorized                EventType = "evAuthorized"
+	EventEVAuthorizationDenied       EventType = "evAuthorizationDenied"
+
+	// EVCEM
+	EventEVPhasesUpdated       EventType = "evPhasesUpdated"
+	EventEVMeasurementsUpdated EventType = "evMeasurementsUpdated"
+
+	// PhaseSwitching
+	EventEVPhasesSwitched    EventType = "evPhasesSwitched"
+	EventEVPhaseSwitchFailed EventType = "evPhaseSwitchFailed"
+
+	// EVSoC
+	EventEVSoCUpdated             EventType = "evSoCUpdated"
+	EventEVNominalCapacityUpdated EventType = "evNominalCapacityUpdated"
+	EventEVActualRangeUpdated     EventType = "evActualRangeUpdated"
+	EventEVTravelRangeUpdated     EventType = "evTravelRangeUpdated"
+
+	// OPEV and OSCEV
+	EventEVCurrentLimitsUpdated EventType = "evCurrentLimitsUpdated"
+	EventEVLimitsUpdated        EventType = "evLimitsUpdated"
+	EventEVLimitsRefused        EventType = "evLimitsRefused"
+
+	// CEVC
+	EventEVEnergyDemandUpdated            EventType = "evEnergyDemandUpdated"
+	EventEVChargingPlanConstraintsUpdated EventType = "evChargingPlanConstraintsUpdated"
+	EventEVChargingPlanRequired           EventType = "evChargingPlanRequired"
+	EventEVChargingPlanFailed             EventType = "evChargingPlanFailed"
+	EventEVChargingPlanUpdated            EventType = "evChargingPlanUpdated"
+
+	// EVCS
+	EventEVChargingSummaryUpdated EventType = "evChargingSummaryUpdated"
+	EventEVChargingSummaryFailed  EventType = "evChargingSummaryFailed"
+)
+
+// Event is published by the use cases when their data has changed
+type Event struct {
+	UseCase model.UseCaseNameEnumType
+	Type    EventType
+}
+
+// EventHandler is called for each event
+type EventHandler func(Event)
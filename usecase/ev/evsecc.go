@@ -0,0 +1,27 @@
+package ev
+
+import "github.com/evcc-io/eebus/spine/model"
+
+// EVSECC implements the EVSE Commissioning and Configuration use case
+type EVSECC struct {
+	useCase
+	state entityState
+}
+
+func (u *EVSECC) register() error {
+	return u.useCase.register("1.0.1", []model.UseCaseScenarioSupportType{1, 2}, clientFeatures(model.FeatureTypeEnumTypeDeviceClassification, model.FeatureTypeEnumTypeDeviceDiagnosis))
+}
+
+// Manufacturer returns the manufacturer data of the EVSE
+func (u *EVSECC) Manufacturer() (model.DeviceClassificationManufacturerDataType, error) {
+	return u.manufacturer()
+}
+
+// OperatingState returns the operating state of the EVSE
+func (u *EVSECC) OperatingState() (model.DeviceDiagnosisOperatingStateType, error) {
+	return u.operatingState()
+}
+
+func (u *EVSECC) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	u.entityStateChanged(&u.state, featureType, EventEVSEManufacturerUpdated, EventEVSEOperatingStateUpdated)
+}
@@ -0,0 +1,156 @@
+package ev
+
+import (
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// Scenarios of the EV State Of Charge use case. The state of health scenario 3 is not supported,
+// as the model provides no scope for its measurement.
+const (
+	EVSoCScenarioStateOfCharge   model.UseCaseScenarioSupportType = 1
+	EVSoCScenarioNominalCapacity model.UseCaseScenarioSupportType = 2
+	EVSoCScenarioRange           model.UseCaseScenarioSupportType = 4 // actual and travel range
+)
+
+// MeasuredValue is a value reported by the EV with its timestamp and state
+type MeasuredValue struct {
+	Value     float64
+	Timestamp time.Time                           // zero if not reported by the EV
+	State     model.MeasurementValueStateEnumType // normal if not reported by the EV
+}
+
+// EVSoC implements the EV State Of Charge use case
+type EVSoC struct {
+	useCase
+	values map[model.ScopeTypeEnumType]MeasuredValue // last published values
+	mux    sync.Mutex
+}
+
+func (u *EVSoC) register() error {
+	return u.useCase.register("1.0.0", []model.UseCaseScenarioSupportType{
+		EVSoCScenarioStateOfCharge, EVSoCScenarioNominalCapacity, EVSoCScenarioRange,
+	}, clientFeatures(model.FeatureTypeEnumTypeMeasurement))
+}
+
+// evSoCEvents are the events published for updated values of the scopes
+var evSoCEvents = []struct {
+	scope model.ScopeTypeEnumType
+	event EventType
+}{
+	{model.ScopeTypeEnumTypeStateOfCharge, EventEVSoCUpdated},
+	{model.ScopeTypeEnumTypeNominalEnergyCapacity, EventEVNominalCapacityUpdated},
+	{model.ScopeTypeEnumTypeActualRange, EventEVActualRangeUpdated},
+	{model.ScopeTypeEnumTypeTravelRange, EventEVTravelRangeUpdated},
+}
+
+// ScenarioSupported returns if the EV announces the scenario of the use case
+func (u *EVSoC) ScenarioSupported(scenario model.UseCaseScenarioSupportType) bool {
+	remoteDevice := u.conn.GetDevice()
+	if remoteDevice == nil {
+		return false
+	}
+
+	return remoteDevice.SupportsUseCase(nil, u.name, scenario, "")
+}
+
+// SoC returns the state of charge of the EV in %
+func (u *EVSoC) SoC() (float64, error) {
+	res, err := u.StateOfCharge()
+	return res.Value, err
+}
+
+// StateOfCharge returns the state of charge of the EV in %
+func (u *EVSoC) StateOfCharge() (MeasuredValue, error) {
+	return u.measuredValue(model.ScopeTypeEnumTypeStateOfCharge)
+}
+
+// NominalCapacity returns the nominal capacity of the EV battery in Wh
+func (u *EVSoC) NominalCapacity() (MeasuredValue, error) {
+	return u.measuredValue(model.ScopeTypeEnumTypeNominalEnergyCapacity)
+}
+
+// ActualRange returns the range of the EV with its current state of charge in m
+func (u *EVSoC) ActualRange() (MeasuredValue, error) {
+	return u.measuredValue(model.ScopeTypeEnumTypeActualRange)
+}
+
+// TravelRange returns the range of the EV with a fully charged battery in m
+func (u *EVSoC) TravelRange() (MeasuredValue, error) {
+	return u.measuredValue(model.ScopeTypeEnumTypeTravelRange)
+}
+
+// measuredValue returns the first measurement of the scope
+func (u *EVSoC) measuredValue(scope model.ScopeTypeEnumType) (MeasuredValue, error) {
+	m, err := localFeature[*feature.Measurement](&u.useCase, model.FeatureTypeEnumTypeMeasurement)
+	if err != nil {
+		return MeasuredValue{}, err
+	}
+
+	for _, id := range measurementIds(m, scope) {
+		item, ok := measurementValue(m, id)
+		if !ok {
+			continue
+		}
+
+		res := MeasuredValue{
+			Value: item.Value.GetValue(),
+			State: model.MeasurementValueStateEnumTypeNormal,
+		}
+
+		if item.Timestamp != nil {
+			if ts, err := time.Parse(time.RFC3339, *item.Timestamp); err == nil {
+				res.Timestamp = ts
+			}
+		}
+
+		if item.ValueState != nil {
+			res.State = model.MeasurementValueStateEnumType(*item.ValueState)
+		}
+
+		return res, nil
+	}
+
+	return MeasuredValue{}, ErrDataNotAvailable
+}
+
+func (u *EVSoC) evConnectionChanged(connected bool) {
+	// values are reported for the connected EV only
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.values = nil
+}
+
+func (u *EVSoC) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	if function != model.FunctionEnumTypeMeasurementListData && function != model.FunctionEnumTypeMeasurementDescriptionListData {
+		return
+	}
+
+	var events []EventType
+
+	u.mux.Lock()
+	if u.values == nil {
+		u.values = make(map[model.ScopeTypeEnumType]MeasuredValue)
+	}
+
+	for _, e := range evSoCEvents {
+		value, err := u.measuredValue(e.scope)
+		if err != nil {
+			continue
+		}
+
+		if last, ok := u.values[e.scope]; !ok || last != value {
+			u.values[e.scope] = value
+			events = append(events, e.event)
+		}
+	}
+	u.mux.Unlock()
+
+	for _, event := range events {
+		u.event(event)
+	}
+}
@@ -0,0 +1,100 @@
+package ev
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestEVSoC(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []EventType
+	uc.AddEventHandler(func(e Event) {
+		if e.UseCase == model.UseCaseNameEnumTypeEVStateOfCharge {
+			events = append(events, e.Type)
+		}
+	})
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+
+	var data model.NodeManagementUseCaseDataType
+	if err := json.Unmarshal([]byte(`[{"useCaseInformation":[[{"address":[{"device":"d:_i:EVSE"},{"entity":[1,1]}]},{"actor":"EV"},{"useCaseSupport":[[{"useCaseName":"evStateOfCharge"},{"useCaseAvailable":true},{"scenarioSupport":[1,4]}]]}]]}]`), &data); err != nil {
+		t.Fatal(err)
+	}
+	conn.remote.UpdateRemoteUseCases(data.UseCaseInformation, false)
+
+	for scenario, supported := range map[model.UseCaseScenarioSupportType]bool{
+		EVSoCScenarioStateOfCharge:   true,
+		EVSoCScenarioNominalCapacity: false,
+		EVSoCScenarioRange:           true,
+	} {
+		if uc.EVSoC.ScenarioSupported(scenario) != supported {
+			t.Errorf("scenario %d: expected supported %v", scenario, supported)
+		}
+	}
+
+	m := cem.FeatureByProps(model.FeatureTypeEnumTypeMeasurement, model.RoleTypeClient)
+	m.SetData(model.FunctionEnumTypeMeasurementDescriptionListData, &model.MeasurementDescriptionListDataType{
+		MeasurementDescriptionData: []model.MeasurementDescriptionDataType{
+			{MeasurementId: ptr(model.MeasurementIdType(1)), ScopeType: ptr(model.ScopeTypeType(model.ScopeTypeEnumTypeStateOfCharge))},
+			{MeasurementId: ptr(model.MeasurementIdType(2)), ScopeType: ptr(model.ScopeTypeType(model.ScopeTypeEnumTypeActualRange))},
+			{MeasurementId: ptr(model.MeasurementIdType(3)), ScopeType: ptr(model.ScopeTypeType(model.ScopeTypeEnumTypeTravelRange))},
+		},
+	})
+
+	setValues := func(soc float64, state model.MeasurementValueStateEnumType) {
+		m.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{
+			MeasurementData: []model.MeasurementDataType{
+				{MeasurementId: ptr(model.MeasurementIdType(1)), Value: model.NewScaledNumberType(soc), Timestamp: ptr("2022-06-01T12:00:00Z"), ValueState: ptr(model.MeasurementValueStateType(state))},
+				{MeasurementId: ptr(model.MeasurementIdType(2)), Value: model.NewScaledNumberType(120000)},
+				{MeasurementId: ptr(model.MeasurementIdType(3)), Value: model.NewScaledNumberType(400000)},
+			},
+		})
+	}
+
+	events = nil
+	setValues(42, model.MeasurementValueStateEnumTypeNormal)
+
+	if len(events) != 3 || events[0] != EventEVSoCUpdated || events[1] != EventEVActualRangeUpdated || events[2] != EventEVTravelRangeUpdated {
+		t.Errorf("unexpected events: %v", events)
+	}
+
+	soc, err := uc.EVSoC.StateOfCharge()
+	if err != nil || soc.Value != 42 || soc.State != model.MeasurementValueStateEnumTypeNormal || !soc.Timestamp.Equal(time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected soc: %+v %v", soc, err)
+	}
+
+	if r, err := uc.EVSoC.ActualRange(); err != nil || r.Value != 120000 || !r.Timestamp.IsZero() || r.State != model.MeasurementValueStateEnumTypeNormal {
+		t.Errorf("unexpected actual range: %+v %v", r, err)
+	}
+
+	if _, err := uc.EVSoC.NominalCapacity(); !errors.Is(err, ErrDataNotAvailable) {
+		t.Errorf("expected nominal capacity not available, got %v", err)
+	}
+
+	events = nil
+	setValues(42, model.MeasurementValueStateEnumTypeError)
+
+	if len(events) != 1 || events[0] != EventEVSoCUpdated {
+		t.Errorf("expected soc event only, got %v", events)
+	}
+	if soc, _ := uc.EVSoC.StateOfCharge(); soc.State != model.MeasurementValueStateEnumTypeError {
+		t.Errorf("expected error state, got %s", soc.State)
+	}
+}
@@ -0,0 +1,93 @@
+package ev
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// Permitted failsafe durations of the EVSE
+const (
+	FailsafeDurationMin = 2 * time.Hour
+	FailsafeDurationMax = 24 * time.Hour
+)
+
+// FailsafeValues are applied by the EVSE when it misses the heartbeat of the CEM
+type FailsafeValues struct {
+	ConsumptionLimit float64       // W
+	Duration         time.Duration // minimum time the failsafe limit applies
+}
+
+// Failsafe provides the failsafe values of the EVSE so that charging falls back to a safe power when the CEM stops sending heartbeats
+type Failsafe struct {
+	useCase
+	values *FailsafeValues
+	mux    sync.Mutex
+}
+
+// Values returns the failsafe values of the EVSE
+func (u *Failsafe) Values() (FailsafeValues, error) {
+	_, f, err := u.keyValues()
+	if err != nil {
+		return FailsafeValues{}, err
+	}
+
+	limit, ok := keyValue(f, model.DeviceConfigurationKeyNameEnumTypeFailsafeConsumptionActivePowerLimit)
+	if !ok || limit.ScaledNumber == nil {
+		return FailsafeValues{}, ErrDataNotAvailable
+	}
+
+	res := FailsafeValues{ConsumptionLimit: limit.ScaledNumber.GetValue()}
+
+	if duration, ok := keyValue(f, model.DeviceConfigurationKeyNameEnumTypeFailsafeDurationMinimum); ok && duration.Duration != nil {
+		if res.Duration, err = model.GetISO8601Duration(*duration.Duration); err != nil {
+			return FailsafeValues{}, err
+		}
+	}
+
+	return res, nil
+}
+
+// WriteConsumptionLimit writes the power in W the EVSE falls back to
+func (u *Failsafe) WriteConsumptionLimit(power float64) error {
+	if power < 0 {
+		return fmt.Errorf("%w: failsafe consumption limit %.0fW", ErrLimitOutOfRange, power)
+	}
+
+	return u.writeKeyValue(model.DeviceConfigurationKeyNameEnumTypeFailsafeConsumptionActivePowerLimit, model.DeviceConfigurationKeyValueValueType{
+		ScaledNumber: model.NewScaledNumberType(power),
+	})
+}
+
+// WriteDuration writes the minimum time the failsafe limit applies, between FailsafeDurationMin and FailsafeDurationMax
+func (u *Failsafe) WriteDuration(duration time.Duration) error {
+	if duration < FailsafeDurationMin || duration > FailsafeDurationMax {
+		return fmt.Errorf("%w: failsafe duration %v", ErrLimitOutOfRange, duration)
+	}
+
+	return u.writeKeyValue(model.DeviceConfigurationKeyNameEnumTypeFailsafeDurationMinimum, model.DeviceConfigurationKeyValueValueType{
+		Duration: model.NewISO8601Duration(duration),
+	})
+}
+
+func (u *Failsafe) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	if function != model.FunctionEnumTypeDeviceConfigurationKeyValueListData {
+		return
+	}
+
+	values, err := u.Values()
+	if err != nil {
+		return
+	}
+
+	u.mux.Lock()
+	changed := u.values == nil || *u.values != values
+	u.values = &values
+	u.mux.Unlock()
+
+	if changed {
+		u.event(EventEVSEFailsafeUpdated)
+	}
+}
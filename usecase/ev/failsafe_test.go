@@ -0,0 +1,151 @@
+package ev
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// remoteContext provides the remote device and records the requests
+type remoteContext struct {
+	spine.Context
+	device   spine.Device
+	requests []model.CmdType
+}
+
+func (c *remoteContext) GetDevice() spine.Device {
+	return c.device
+}
+
+func (c *remoteContext) Subscribe(lf spine.Feature, rf spine.Feature, typ model.FeatureTypeType) error {
+	return nil
+}
+
+func (c *remoteContext) Request(op model.CmdClassifierType, senderAddress, destinationAddress model.FeatureAddressType, ackRequest bool, cmd []model.CmdType) (*model.MsgCounterType, error) {
+	c.requests = append(c.requests, cmd...)
+	return nil, nil
+}
+
+func TestFailsafe(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []EventType
+	uc.AddEventHandler(func(e Event) {
+		events = append(events, e.Type)
+	})
+
+	conn.remote = testRemoteDevice(t)
+
+	if _, err := uc.Failsafe.Values(); !errors.Is(err, ErrDataNotAvailable) {
+		t.Errorf("expected data not available, got %v", err)
+	}
+
+	// replies and notifies of the EVSE's device configuration
+	ctx := &remoteContext{device: conn.remote}
+	handle := func(entity []model.AddressEntityType, cmd model.CmdType) {
+		t.Helper()
+
+		dc := cem.FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeClient)
+		if err := dc.Handle(ctx, model.FeatureAddressType{Entity: entity}, model.CmdClassifierTypeReply, cmd, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	evse := []model.AddressEntityType{1}
+	ev := []model.AddressEntityType{1, 1}
+
+	// the EVSE's key values are read as soon as its feature is found
+	dc := cem.FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeClient)
+	if err := dc.(spine.ClientFeature).ServerFound(ctx, conn.remote.Entity(evse).FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeServer)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.requests) != 2 || ctx.requests[0].DeviceConfigurationKeyValueDescriptionListData == nil || ctx.requests[1].DeviceConfigurationKeyValueListData == nil {
+		t.Errorf("expected key value reads, got %v", ctx.requests)
+	}
+
+	handle(evse, model.CmdType{DeviceConfigurationKeyValueDescriptionListData: &model.DeviceConfigurationKeyValueDescriptionListDataType{
+		DeviceConfigurationKeyValueDescriptionData: []model.DeviceConfigurationKeyValueDescriptionDataType{
+			{
+				KeyId:     ptr(model.DeviceConfigurationKeyIdType(5)),
+				KeyName:   ptr(string(model.DeviceConfigurationKeyNameEnumTypeFailsafeConsumptionActivePowerLimit)),
+				ValueType: ptr(model.DeviceConfigurationKeyValueTypeTypeScalednumber),
+			},
+			{
+				KeyId:     ptr(model.DeviceConfigurationKeyIdType(6)),
+				KeyName:   ptr(string(model.DeviceConfigurationKeyNameEnumTypeFailsafeDurationMinimum)),
+				ValueType: ptr(model.DeviceConfigurationKeyValueTypeTypeDuration),
+			},
+		},
+	}})
+	handle(evse, model.CmdType{DeviceConfigurationKeyValueListData: &model.DeviceConfigurationKeyValueListDataType{
+		DeviceConfigurationKeyValueData: []model.DeviceConfigurationKeyValueDataType{
+			{
+				KeyId:             ptr(model.DeviceConfigurationKeyIdType(5)),
+				Value:             &model.DeviceConfigurationKeyValueValueType{ScaledNumber: model.NewScaledNumberType(4140)},
+				IsValueChangeable: ptr(true),
+			},
+			{
+				KeyId:             ptr(model.DeviceConfigurationKeyIdType(6)),
+				Value:             &model.DeviceConfigurationKeyValueValueType{Duration: model.NewISO8601Duration(2 * time.Hour)},
+				IsValueChangeable: ptr(false),
+			},
+		},
+	}})
+
+	// the EV uses its own key ids
+	handle(ev, model.CmdType{DeviceConfigurationKeyValueDescriptionListData: &model.DeviceConfigurationKeyValueDescriptionListDataType{
+		DeviceConfigurationKeyValueDescriptionData: []model.DeviceConfigurationKeyValueDescriptionDataType{{
+			KeyId:     ptr(model.DeviceConfigurationKeyIdType(1)),
+			KeyName:   ptr(string(model.DeviceConfigurationKeyNameEnumTypeCommunicationsStandard)),
+			ValueType: ptr(model.DeviceConfigurationKeyValueTypeTypeString),
+		}},
+	}})
+	handle(ev, model.CmdType{DeviceConfigurationKeyValueListData: &model.DeviceConfigurationKeyValueListDataType{
+		DeviceConfigurationKeyValueData: []model.DeviceConfigurationKeyValueDataType{{
+			KeyId: ptr(model.DeviceConfigurationKeyIdType(1)),
+			Value: &model.DeviceConfigurationKeyValueValueType{String: ptr(model.DeviceConfigurationKeyValueStringType("iec61851"))},
+		}},
+	}})
+
+	if standard, err := uc.EVCC.CommunicationStandard(); err != nil || standard != "iec61851" {
+		t.Errorf("unexpected communication standard: %s %v", standard, err)
+	}
+
+	values, err := uc.Failsafe.Values()
+	if err != nil || values.ConsumptionLimit != 4140 || values.Duration != 2*time.Hour {
+		t.Errorf("unexpected failsafe values: %+v %v", values, err)
+	}
+	// the EV's key values do not update the failsafe values
+	if len(events) != 2 || events[0] != EventEVSEFailsafeUpdated {
+		t.Errorf("expected single failsafe updated event, got %v", events)
+	}
+
+	if err := uc.Failsafe.WriteConsumptionLimit(1380); err != nil {
+		t.Fatal(err)
+	}
+
+	data := conn.ctx.writes[len(conn.ctx.writes)-1][0].DeviceConfigurationKeyValueListData
+	if data == nil || *data.DeviceConfigurationKeyValueData[0].KeyId != 5 || data.DeviceConfigurationKeyValueData[0].Value.ScaledNumber.GetValue() != 1380 {
+		t.Errorf("unexpected write: %+v", data)
+	}
+
+	if err := uc.Failsafe.WriteDuration(time.Hour); !errors.Is(err, ErrLimitOutOfRange) {
+		t.Errorf("expected out of range, got %v", err)
+	}
+	if err := uc.Failsafe.WriteDuration(3 * time.Hour); !errors.Is(err, ErrKeyNotChangeable) {
+		t.Errorf("expected not changeable, got %v", err)
+	}
+}
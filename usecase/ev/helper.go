@@ -0,0 +1,111 @@
+package ev
+
+import (
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+var phases = map[model.ElectricalConnectionPhaseNameType]uint{
+	"a": 1,
+	"b": 2,
+	"c": 3,
+}
+
+// phaseForMeasurement returns the phase of the measurement, 0 if the measurement is not for a single phase
+func phaseForMeasurement(params []model.ElectricalConnectionParameterDescriptionDataType, id model.MeasurementIdType) uint {
+	for _, item := range params {
+		if item.MeasurementId == nil || *item.MeasurementId != id || item.AcMeasuredPhases == nil {
+			continue
+		}
+
+		return phases[*item.AcMeasuredPhases]
+	}
+
+	return 0
+}
+
+// measurementIds returns the ids of the measurements with the given scope
+func measurementIds(f *feature.Measurement, scope model.ScopeTypeEnumType) []model.MeasurementIdType {
+	var res []model.MeasurementIdType
+
+	for _, item := range f.DescriptionListData() {
+		if item.MeasurementId != nil && item.ScopeType != nil && model.ScopeTypeEnumType(*item.ScopeType) == scope {
+			res = append(res, *item.MeasurementId)
+		}
+	}
+
+	return res
+}
+
+// measurementValue returns the value of the measurement
+func measurementValue(f *feature.Measurement, id model.MeasurementIdType) (model.MeasurementDataType, bool) {
+	for _, item := range f.ListData() {
+		if item.MeasurementId != nil && *item.MeasurementId == id && item.Value != nil {
+			return item, true
+		}
+	}
+
+	return model.MeasurementDataType{}, false
+}
+
+// permittedValues returns default, min and max of the first permitted value set of the parameter
+func permittedValues(f *feature.ElectricalConnection, id model.ElectricalConnectionParameterIdType) (value, min, max float64, ok bool) {
+	for _, item := range f.PermittedValueSetListData() {
+		if item.ParameterId == nil || *item.ParameterId != id || len(item.PermittedValueSet) == 0 {
+			continue
+		}
+
+		set := item.PermittedValueSet[0]
+		if len(set.Value) > 0 {
+			value = set.Value[0].GetValue()
+		}
+		if len(set.Range) > 0 {
+			if set.Range[0].Min != nil {
+				min = set.Range[0].Min.GetValue()
+			}
+			if set.Range[0].Max != nil {
+				max = set.Range[0].Max.GetValue()
+			}
+		}
+
+		return value, min, max, true
+	}
+
+	return 0, 0, 0, false
+}
+
+// keyValues are the cached device configuration of a remote entity
+type keyValues interface {
+	KeyValueDescriptionListData() []model.DeviceConfigurationKeyValueDescriptionDataType
+	KeyValueListData() []model.DeviceConfigurationKeyValueDataType
+}
+
+// keyValue returns the value of the device configuration key
+func keyValue(f keyValues, name model.DeviceConfigurationKeyNameEnumType) (model.DeviceConfigurationKeyValueValueType, bool) {
+	if item, ok := keyValueData(f, name); ok && item.Value != nil {
+		return *item.Value, true
+	}
+
+	return model.DeviceConfigurationKeyValueValueType{}, false
+}
+
+// keyValueData returns the data of the device configuration key
+func keyValueData(f keyValues, name model.DeviceConfigurationKeyNameEnumType) (model.DeviceConfigurationKeyValueDataType, bool) {
+	for _, desc := range f.KeyValueDescriptionListData() {
+		if desc.KeyId == nil || desc.KeyName == nil || *desc.KeyName != string(name) {
+			continue
+		}
+
+		for _, item := range f.KeyValueListData() {
+			if item.KeyId != nil && *item.KeyId == *desc.KeyId && item.Value != nil {
+				return item, true
+			}
+		}
+	}
+
+	return model.DeviceConfigurationKeyValueDataType{}, false
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
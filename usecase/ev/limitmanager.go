@@ -0,0 +1,272 @@
+package ev
+
+import (
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// LimitRefreshInterval is the default interval the desired limits are sent again, as chargers may revert limits after a timeout
+const LimitRefreshInterval = time.Minute
+
+// LimitRefusalsMax is the number of consecutive deviating limits reported by the charger after which the limits are considered refused
+const LimitRefusalsMax = 3
+
+// managedLimits are the desired limits of a use case
+type managedLimits struct {
+	limits   *limits
+	write    func(currents []float64) error
+	desired  []float64
+	pending  bool // the last write failed, e.g. as the limits of the EV are not available yet
+	refusals int
+}
+
+// LimitManager remembers the desired overload and recommendation limits and sends them again periodically,
+// when an EV is connected, after a failed write and when the charger reports deviating limits.
+// The desired limits are kept across EV connections until they are changed or cleared, TakeOver carries them
+// over to the use cases of a new connection to the EVSE. Limits deactivated by the application are not sent
+// again until new limits are desired.
+type LimitManager struct {
+	opev     *managedLimits
+	oscev    *managedLimits
+	interval time.Duration
+	timer    *time.Timer
+	mux      sync.Mutex
+}
+
+func newLimitManager(opev *OPEV, oscev *OSCEV) *LimitManager {
+	return &LimitManager{
+		opev:     &managedLimits{limits: &opev.limits, write: opev.WriteOverloadLimits},
+		oscev:    &managedLimits{limits: &oscev.limits, write: oscev.WriteRecommendationLimits},
+		interval: LimitRefreshInterval,
+	}
+}
+
+// WriteOverloadLimits remembers and writes the maximum charging currents per phase, see OPEV.WriteOverloadLimits
+func (m *LimitManager) WriteOverloadLimits(currents []float64) error {
+	return m.setDesired(m.opev, currents)
+}
+
+// WriteRecommendationLimits remembers and writes the recommended charging currents per phase, see OSCEV.WriteRecommendationLimits
+func (m *LimitManager) WriteRecommendationLimits(currents []float64) error {
+	return m.setDesired(m.oscev, currents)
+}
+
+// SetRefreshInterval sets the interval the desired limits are sent again, 0 disables the periodic refresh
+func (m *LimitManager) SetRefreshInterval(interval time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.interval = interval
+	m.stopTimer()
+	m.schedule()
+}
+
+// Clear forgets the desired limits, the limits written last remain active on the charger
+func (m *LimitManager) Clear() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, ml := range []*managedLimits{m.opev, m.oscev} {
+		ml.desired = nil
+		ml.pending = false
+		ml.refusals = 0
+	}
+
+	m.stopTimer()
+}
+
+// TakeOver continues with the desired limits and refresh interval of the limit manager of a previous connection
+// to the EVSE, e.g. after a SHIP reconnect. The previous manager is stopped and the limits are sent again,
+// once the EV has provided its limits if they are not available yet.
+func (m *LimitManager) TakeOver(previous *LimitManager) error {
+	if previous == nil || previous == m {
+		return nil
+	}
+
+	previous.mux.Lock()
+	opev, oscev := previous.opev.desired, previous.oscev.desired
+	interval := previous.interval
+	previous.opev.desired, previous.oscev.desired = nil, nil
+	previous.stopTimer()
+	previous.mux.Unlock()
+
+	m.mux.Lock()
+	m.opev.desired, m.oscev.desired = opev, oscev
+	m.interval = interval
+	for _, ml := range []*managedLimits{m.opev, m.oscev} {
+		ml.refusals = 0
+	}
+	m.stopTimer()
+	m.schedule()
+	m.mux.Unlock()
+
+	return m.Refresh()
+}
+
+// Refused returns if the charger persistently refuses the desired limits of the scope
+func (m *LimitManager) Refused(scope model.ScopeTypeEnumType) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, ml := range []*managedLimits{m.opev, m.oscev} {
+		if ml.limits.scope == scope {
+			return ml.refusals >= LimitRefusalsMax
+		}
+	}
+
+	return false
+}
+
+// Refresh sends all desired limits again
+func (m *LimitManager) Refresh() error {
+	var err error
+	for _, ml := range []*managedLimits{m.opev, m.oscev} {
+		if werr := m.send(ml); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	return err
+}
+
+func (m *LimitManager) setDesired(ml *managedLimits, currents []float64) error {
+	m.mux.Lock()
+	ml.desired = append([]float64(nil), currents...)
+	ml.refusals = 0
+	m.schedule()
+	m.mux.Unlock()
+
+	return m.send(ml)
+}
+
+// send writes the desired limits and remembers failed writes for retrying once the limits are available
+func (m *LimitManager) send(ml *managedLimits) error {
+	m.mux.Lock()
+	desired := ml.desired
+	m.mux.Unlock()
+
+	if desired == nil {
+		return nil
+	}
+
+	err := ml.write(desired)
+
+	m.mux.Lock()
+	ml.pending = err != nil
+	m.mux.Unlock()
+
+	return err
+}
+
+// resend writes the desired limits again unless the application has deactivated the limits written last
+func (m *LimitManager) resend(ml *managedLimits) error {
+	if ml.limits.inactive() {
+		return nil
+	}
+
+	return m.send(ml)
+}
+
+// schedule starts the refresh timer if limits are desired, the caller must hold the lock
+func (m *LimitManager) schedule() {
+	if m.timer != nil || m.interval <= 0 || (m.opev.desired == nil && m.oscev.desired == nil) {
+		return
+	}
+
+	m.timer = time.AfterFunc(m.interval, m.tick)
+}
+
+// stopTimer stops the refresh timer, the caller must hold the lock
+func (m *LimitManager) stopTimer() {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+}
+
+func (m *LimitManager) tick() {
+	m.mux.Lock()
+	m.timer = nil
+	m.mux.Unlock()
+
+	// limits ignored by the EVSE are not reported as changed and are verified before sending them again
+	for _, ml := range []*managedLimits{m.opev, m.oscev} {
+		if !m.verify(ml) {
+			// the EV may not be connected, the limits are sent once it is
+			_ = m.resend(ml)
+		}
+	}
+
+	m.mux.Lock()
+	m.schedule()
+	m.mux.Unlock()
+}
+
+// verify sends the limits again if the charger reports deviating values and publishes an EventEVLimitsRefused
+// once the charger has deviated LimitRefusalsMax times in a row. It returns if the limits have been sent.
+func (m *LimitManager) verify(ml *managedLimits) bool {
+	deviating := ml.limits.deviating()
+
+	m.mux.Lock()
+	if ml.desired == nil {
+		m.mux.Unlock()
+		return false
+	}
+
+	if !deviating {
+		ml.refusals = 0
+		m.mux.Unlock()
+		return false
+	}
+
+	ml.refusals++
+	refusals := ml.refusals
+	m.mux.Unlock()
+
+	switch {
+	case refusals < LimitRefusalsMax:
+		_ = m.resend(ml)
+		return true
+	case refusals == LimitRefusalsMax:
+		// the limits are sent again by the periodic refresh or when they are changed
+		ml.limits.event(EventEVLimitsRefused)
+	}
+
+	return false
+}
+
+func (m *LimitManager) evConnectionChanged(connected bool) {
+	if !connected {
+		return
+	}
+
+	for _, ml := range []*managedLimits{m.opev, m.oscev} {
+		m.mux.Lock()
+		ml.refusals = 0
+		m.mux.Unlock()
+
+		// the limits of the EV are usually not available yet and are sent once it has provided them
+		_ = m.resend(ml)
+	}
+}
+
+func (m *LimitManager) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	switch function {
+	case model.FunctionEnumTypeLoadControlLimitDescriptionListData, model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData:
+		for _, ml := range []*managedLimits{m.opev, m.oscev} {
+			m.mux.Lock()
+			pending := ml.pending
+			m.mux.Unlock()
+
+			if pending {
+				_ = m.resend(ml)
+			}
+		}
+
+	case model.FunctionEnumTypeLoadControlLimitListData:
+		m.verify(m.opev)
+		m.verify(m.oscev)
+	}
+}
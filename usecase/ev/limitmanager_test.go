@@ -0,0 +1,260 @@
+package ev
+
+import (
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestLimitManager(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc.LimitManager.SetRefreshInterval(0)
+
+	var refused int
+	uc.AddEventHandler(func(e Event) {
+		if e.Type == EventEVLimitsRefused {
+			refused++
+		}
+	})
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+
+	// limits of the EV are not available yet
+	if err := uc.LimitManager.WriteOverloadLimits([]float64{10, 10, 10}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	setTestLimitData(cem)
+	if len(conn.ctx.writes) != 1 {
+		t.Fatalf("expected pending limits written, got %d writes", len(conn.ctx.writes))
+	}
+
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	report := func(value float64) {
+		var data []model.LoadControlLimitDataType
+		for id := 1; id <= 3; id++ {
+			data = append(data, model.LoadControlLimitDataType{
+				LimitId:       ptr(model.LoadControlLimitIdType(id)),
+				IsLimitActive: ptr(true),
+				Value:         model.NewScaledNumberType(value),
+			})
+		}
+		lc.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{LoadControlLimitData: data})
+	}
+
+	report(10)
+	if len(conn.ctx.writes) != 1 {
+		t.Errorf("expected no write for matching limits, got %d writes", len(conn.ctx.writes))
+	}
+
+	// the EVSE reverts the limits after each write
+	reverted := []float64{16, 13, 16, 13}
+	for i := 1; i < LimitRefusalsMax; i++ {
+		report(reverted[i-1])
+		if len(conn.ctx.writes) != 1+i {
+			t.Errorf("expected deviating limits written again, got %d writes", len(conn.ctx.writes))
+		}
+	}
+
+	report(reverted[LimitRefusalsMax-1])
+	if refused != 1 || !uc.LimitManager.Refused(model.ScopeTypeEnumTypeOverloadProtection) {
+		t.Errorf("expected limits refused, got %d events", refused)
+	}
+
+	writes := len(conn.ctx.writes)
+	report(reverted[LimitRefusalsMax])
+	if len(conn.ctx.writes) != writes || refused != 1 {
+		t.Errorf("expected refused limits not written again, got %d writes and %d events", len(conn.ctx.writes)-writes, refused)
+	}
+
+	// desired limits are kept for the next EV
+	conn.evConnected(false)
+	conn.evConnected(true)
+
+	if len(conn.ctx.writes) != writes+1 || uc.LimitManager.Refused(model.ScopeTypeEnumTypeOverloadProtection) {
+		t.Errorf("expected limits written after plug-in, got %d writes", len(conn.ctx.writes)-writes)
+	}
+
+	uc.LimitManager.Clear()
+	if err := uc.LimitManager.Refresh(); err != nil || len(conn.ctx.writes) != writes+1 {
+		t.Errorf("expected no write after clear, got %v", err)
+	}
+}
+
+func TestLimitManagerRefresh(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc.LimitManager.SetRefreshInterval(0)
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+	setTestLimitData(cem)
+
+	if err := uc.LimitManager.WriteOverloadLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the EVSE ignores the written limits without reporting a change
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	lc.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{
+		LoadControlLimitData: []model.LoadControlLimitDataType{
+			{LimitId: ptr(model.LoadControlLimitIdType(1)), IsLimitActive: ptr(false), Value: model.NewScaledNumberType(16)},
+		},
+	})
+
+	// the reported change is the first refusal
+	for i := 2; i < LimitRefusalsMax; i++ {
+		uc.LimitManager.tick()
+	}
+	if uc.LimitManager.Refused(model.ScopeTypeEnumTypeOverloadProtection) {
+		t.Error("unexpected refused limits")
+	}
+
+	writes := len(conn.ctx.writes)
+	uc.LimitManager.tick()
+	if !uc.LimitManager.Refused(model.ScopeTypeEnumTypeOverloadProtection) {
+		t.Error("expected refused limits")
+	}
+	if len(conn.ctx.writes) != writes+1 {
+		t.Errorf("expected refused limits refreshed, got %d writes", len(conn.ctx.writes)-writes)
+	}
+}
+
+func TestLimitManagerDeactivated(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc.LimitManager.SetRefreshInterval(0)
+
+	var refused int
+	uc.AddEventHandler(func(e Event) {
+		if e.Type == EventEVLimitsRefused {
+			refused++
+		}
+	})
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+	setTestLimitData(cem)
+
+	if err := uc.LimitManager.WriteOverloadLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	report := func(active bool) {
+		var data []model.LoadControlLimitDataType
+		for id := 1; id <= 3; id++ {
+			data = append(data, model.LoadControlLimitDataType{
+				LimitId:       ptr(model.LoadControlLimitIdType(id)),
+				IsLimitActive: ptr(active),
+				Value:         model.NewScaledNumberType(10),
+			})
+		}
+		lc.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{LoadControlLimitData: data})
+	}
+
+	report(true)
+
+	// the application deactivates the limits
+	if err := uc.OPEV.SetLimitsActive(false); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := len(conn.ctx.writes)
+	report(false)
+
+	for i := 0; i < LimitRefusalsMax; i++ {
+		uc.LimitManager.tick()
+	}
+	if refused != 0 || uc.LimitManager.Refused(model.ScopeTypeEnumTypeOverloadProtection) {
+		t.Errorf("unexpected refused limits, got %d events", refused)
+	}
+	if len(conn.ctx.writes) != writes {
+		t.Errorf("expected deactivated limits not written again, got %d writes", len(conn.ctx.writes)-writes)
+	}
+
+	// new limits activate them again
+	if err := uc.LimitManager.WriteOverloadLimits([]float64{8, 8, 8}); err != nil || len(conn.ctx.writes) != writes+1 {
+		t.Errorf("expected limits written, got %v", err)
+	}
+}
+
+func TestLimitManagerTakeOver(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc.LimitManager.SetRefreshInterval(0)
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+	setTestLimitData(cem)
+
+	if err := uc.LimitManager.WriteOverloadLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the SHIP connection is lost and the use cases of the new connection take over the desired limits
+	reconnected := &testConnection{}
+
+	next, err := New(local, reconnected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := next.LimitManager.TakeOver(uc.LimitManager); err == nil {
+		t.Error("expected error without remote device")
+	}
+
+	reconnected.remote = testRemoteDevice(t)
+	reconnected.evConnected(true)
+
+	if len(reconnected.ctx.writes) != 1 {
+		t.Fatalf("expected limits written after reconnect, got %d writes", len(reconnected.ctx.writes))
+	}
+
+	data := reconnected.ctx.writes[0][0].LoadControlLimitListData
+	if data == nil || len(data.LoadControlLimitData) != 3 || data.LoadControlLimitData[0].Value.GetValue() != 10 {
+		t.Errorf("unexpected write: %+v", data)
+	}
+
+	// the previous manager does not send the limits anymore
+	writes := len(conn.ctx.writes)
+	if err := uc.LimitManager.Refresh(); err != nil || len(conn.ctx.writes) != writes {
+		t.Errorf("expected no write by the previous manager, got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package ev
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine/model"
+	"github.com/samber/lo"
+)
+
+// CurrentLimit contains the permitted charging currents of a phase in A
+type CurrentLimit struct {
+	Phase   uint
+	Min     float64
+	Max     float64
+	Default float64 // used for pausing the charging process
+}
+
+// Limit is a load control limit of a phase in A
+type Limit struct {
+	Phase        uint
+	Value        float64
+	IsActive     bool
+	IsChangeable bool
+}
+
+// limits implements the current limits shared by OPEV and OSCEV
+type limits struct {
+	useCase
+	scope      model.ScopeTypeEnumType // scope of the load control limits
+	phases     func() uint             // phases enabled by phase switching, 0 if all phases are enabled
+	written    []feature.LoadControlLimitDatasetType
+	writtenMux sync.Mutex
+}
+
+// OPEV implements the Overload Protection by EV Charging Current Curtailment use case
+type OPEV struct {
+	limits
+	held      func() bool // charging is held until the EV has been authorized
+	requested []float64
+	mux       sync.Mutex
+}
+
+func (u *OPEV) register() error {
+	return u.useCase.register("1.0.1b", []model.UseCaseScenarioSupportType{1, 2, 3}, clientFeatures(model.FeatureTypeEnumTypeLoadControl, model.FeatureTypeEnumTypeElectricalConnection))
+}
+
+// WriteOverloadLimits writes the maximum charging currents per phase, the first value is phase 1, a current of 0 pauses the phase.
+// The currents are validated as by WritePhaseLimits, if any phase limit cannot be applied none of the limits are written
+// and a LimitsError is returned. While the EV has not been authorized zero currents are written and the currents are
+// applied after the authorization.
+func (u *OPEV) WriteOverloadLimits(currents []float64) error {
+	u.mux.Lock()
+	u.requested = append([]float64(nil), currents...)
+	u.mux.Unlock()
+
+	items, err := u.phaseLimitItems(currents)
+	if err != nil {
+		return err
+	}
+
+	if u.held != nil && u.held() {
+		return u.hold()
+	}
+
+	return u.writeItems(items)
+}
+
+// WritePhaseLimits writes the maximum charging currents per phase, see WriteOverloadLimits
+func (u *OPEV) WritePhaseLimits(currents []float64) error {
+	return u.WriteOverloadLimits(currents)
+}
+
+// hold writes zero overload limits for all phases of the EV, pausing charging until the EV has been authorized
+func (u *OPEV) hold() error {
+	currentLimits, err := u.CurrentLimits()
+	if err != nil {
+		return err
+	}
+
+	items, err := u.phaseLimitItems(make([]float64, limitPhases(currentLimits)))
+	if err != nil {
+		return err
+	}
+
+	return u.writeItems(items)
+}
+
+// refresh writes the limits again after the enabled phases have changed, held limits are kept
+func (u *OPEV) refresh() error {
+	if u.held != nil && u.held() {
+		return nil
+	}
+
+	return u.release()
+}
+
+// release writes the requested overload limits, the maximum currents if no limits have been requested
+func (u *OPEV) release() error {
+	u.mux.Lock()
+	currents := u.requested
+	u.mux.Unlock()
+
+	if currents == nil {
+		currentLimits, err := u.CurrentLimits()
+		if err != nil {
+			return err
+		}
+
+		currents = make([]float64, limitPhases(currentLimits))
+		for _, limit := range currentLimits {
+			currents[limit.Phase-1] = limit.Max
+		}
+
+		currents = u.UnbalancedLoadLimits(currents)
+	}
+
+	items, err := u.phaseLimitItems(currents)
+	if err != nil {
+		return err
+	}
+
+	return u.writeItems(items)
+}
+
+func (u *OPEV) evConnectionChanged(connected bool) {
+	u.limits.evConnectionChanged(connected)
+
+	// requested limits apply to the connected EV only
+	u.mux.Lock()
+	u.requested = nil
+	u.mux.Unlock()
+}
+
+// OSCEV implements the Optimization of Self Consumption during EV Charging use case
+type OSCEV struct {
+	limits
+}
+
+func (u *OSCEV) register() error {
+	return u.useCase.register("1.0.0b", []model.UseCaseScenarioSupportType{1, 2, 3}, clientFeatures(model.FeatureTypeEnumTypeLoadControl, model.FeatureTypeEnumTypeElectricalConnection))
+}
+
+// WriteRecommendationLimits writes the recommended charging currents per phase, the first value is phase 1, a current
+// of 0 pauses the phase. The currents are validated as by WritePhaseLimits, if any phase limit cannot be applied none
+// of the limits are written and a LimitsError is returned.
+func (u *OSCEV) WriteRecommendationLimits(currents []float64) error {
+	return u.WritePhaseLimits(currents)
+}
+
+// CurrentLimits returns the permitted charging currents per phase. The minimum currents are raised as by
+// ElectricalLimits, but never above the maximum currents.
+func (u *limits) CurrentLimits() ([]CurrentLimit, error) {
+	res, err := u.permittedCurrentLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	// the minimum currents reported by the EV are only reliable with ISO 15118-2 VAS
+	el, err := u.ElectricalLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res {
+		for _, p := range el.Phases {
+			if p.Phase == res[i].Phase && p.CurrentMin > res[i].Min {
+				res[i].Min = p.CurrentMin
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// permittedCurrentLimits returns the charging currents per phase as permitted by the EV
+func (u *limits) permittedCurrentLimits() ([]CurrentLimit, error) {
+	m, err := localFeature[*feature.Measurement](&u.useCase, model.FeatureTypeEnumTypeMeasurement)
+	if err != nil {
+		return nil, err
+	}
+
+	ec, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	currentIds := measurementIds(m, model.ScopeTypeEnumTypeACCurrent)
+
+	var res []CurrentLimit
+	for _, item := range ec.ParameterDescriptionListData() {
+		if item.ParameterId == nil || item.MeasurementId == nil || item.AcMeasuredPhases == nil {
+			continue
+		}
+
+		phase, ok := phases[*item.AcMeasuredPhases]
+		if !ok || !lo.Contains(currentIds, *item.MeasurementId) {
+			continue
+		}
+
+		if value, min, max, ok := permittedValues(ec, *item.ParameterId); ok {
+			res = append(res, CurrentLimit{Phase: phase, Min: min, Max: max, Default: value})
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, ErrDataNotAvailable
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Phase < res[j].Phase
+	})
+
+	return res, nil
+}
+
+// minimumCurrent returns the minimum charging current of the EV's communication standard
+func (u *limits) minimumCurrent(phases uint) float64 {
+	standard, _ := communicationStandard(&u.useCase)
+	return feature.MinimumCurrent(standard, phases)
+}
+
+// ElectricalLimits returns the permitted charging currents and powers per phase and in total.
+// Power limits not reported by the EV are derived from the current limits and the measured or nominal voltage,
+// minimum currents are raised to the minimum of the communication standard.
+func (u *limits) ElectricalLimits() (feature.ElectricalLimits, error) {
+	ec, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return feature.ElectricalLimits{}, err
+	}
+
+	// voltages are optional
+	m, _ := localFeature[*feature.Measurement](&u.useCase, model.FeatureTypeEnumTypeMeasurement)
+
+	res, ok := ec.ElectricalLimits(m, feature.NominalVoltage)
+	if !ok {
+		return res, ErrDataNotAvailable
+	}
+
+	return res.ApplyMinimumCurrent(u.minimumCurrent(res.ConnectedPhases), feature.NominalVoltage), nil
+}
+
+// LoadControlLimits returns the load control limits of the use case per phase
+func (u *limits) LoadControlLimits() ([]Limit, error) {
+	lc, err := localFeature[*feature.LoadControl](&u.useCase, model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return nil, err
+	}
+
+	ec, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	params := ec.ParameterDescriptionListData()
+	data := lc.LimitListData()
+
+	var res []Limit
+	for _, desc := range u.limitDescriptions(lc) {
+		for _, item := range data {
+			if item.LimitId == nil || *item.LimitId != *desc.LimitId || item.Value == nil {
+				continue
+			}
+
+			limit := Limit{
+				Phase: phaseForMeasurement(params, *desc.MeasurementId),
+				Value: item.Value.GetValue(),
+			}
+			if item.IsLimitActive != nil {
+				limit.IsActive = *item.IsLimitActive
+			}
+			if item.IsLimitChangeable != nil {
+				limit.IsChangeable = *item.IsLimitChangeable
+			}
+
+			res = append(res, limit)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, ErrDataNotAvailable
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Phase < res[j].Phase
+	})
+
+	return res, nil
+}
+
+// limitDescriptions returns the limit descriptions of the use case's scope
+func (u *limits) limitDescriptions(lc *feature.LoadControl) []model.LoadControlLimitDescriptionDataType {
+	var res []model.LoadControlLimitDescriptionDataType
+
+	for _, item := range lc.LimitDescriptionListData() {
+		if item.LimitId != nil && item.MeasurementId != nil && item.ScopeType != nil && model.ScopeTypeEnumType(*item.ScopeType) == u.scope {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+// disabled returns if the phase has been disabled by phase switching. Disabled phases are always limited to 0 A.
+func (u *limits) disabled(phase uint) bool {
+	if u.phases == nil {
+		return false
+	}
+
+	n := u.phases()
+	return n > 0 && phase > n
+}
+
+// limitPhases returns the number of phases covered by the current limits
+func limitPhases(currentLimits []CurrentLimit) uint {
+	var res uint
+	for _, limit := range currentLimits {
+		if limit.Phase > res {
+			res = limit.Phase
+		}
+	}
+
+	return res
+}
+
+func (u *limits) evConnectionChanged(connected bool) {
+	u.writtenMux.Lock()
+	u.written = nil
+	u.writtenMux.Unlock()
+}
+
+func (u *limits) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	switch function {
+	case model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData:
+		u.event(EventEVCurrentLimitsUpdated)
+	case model.FunctionEnumTypeLoadControlLimitListData:
+		u.event(EventEVLimitsUpdated)
+	}
+}
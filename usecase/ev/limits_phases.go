@@ -0,0 +1,412 @@
+package ev
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// limitTolerance is the deviation in A of reported limits accepted due to scaling of the values
+const limitTolerance = 0.05
+
+var (
+	// ErrLimitNotAvailable is reported for phases without load control limit or permitted currents
+	ErrLimitNotAvailable = errors.New("limit not available")
+	// ErrLimitNotChangeable is reported for phases whose load control limit is not changeable
+	ErrLimitNotChangeable = errors.New("limit not changeable")
+	// ErrLimitOutOfRange is reported for currents outside of the permitted currents of the phase
+	ErrLimitOutOfRange = errors.New("limit out of range")
+	// ErrAsymmetricLimitsNotSupported is reported for different currents per phase if the EV only supports symmetric limits
+	ErrAsymmetricLimitsNotSupported = errors.New("asymmetric limits not supported")
+)
+
+// PhaseLimitError is a phase limit that could not be applied
+type PhaseLimitError struct {
+	Phase   uint
+	Current float64
+	Err     error
+}
+
+// LimitsError reports the phase limits that could not be applied. None of the limits have been written.
+type LimitsError struct {
+	Scope  model.ScopeTypeEnumType
+	Phases []PhaseLimitError
+}
+
+func (e *LimitsError) Error() string {
+	var phases []string
+	for _, p := range e.Phases {
+		phases = append(phases, fmt.Sprintf("L%d %.1fA: %v", p.Phase, p.Current, p.Err))
+	}
+
+	return fmt.Sprintf("invalid %s limits: %s", e.Scope, strings.Join(phases, ", "))
+}
+
+// Is reports if any of the phase limits failed with the target error
+func (e *LimitsError) Is(target error) bool {
+	for _, p := range e.Phases {
+		if errors.Is(p.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WritePhaseLimits writes the charging currents per phase, the first value is phase 1, a current of 0 pauses the phase.
+// Phases disabled by phase switching are limited to 0 A regardless of the current.
+// Different currents per phase are only written if the EV supports asymmetric charging. All phases are validated
+// before writing, if any phase limit cannot be applied none of the limits are written and a LimitsError is returned.
+func (u *limits) WritePhaseLimits(currents []float64) error {
+	items, err := u.phaseLimitItems(currents)
+	if err != nil {
+		return err
+	}
+
+	return u.writeItems(items)
+}
+
+// UnbalancedLoadLimits returns the currents to be written for protecting against unbalanced load.
+// If the EV only supports symmetric limits all phases are reduced to the lowest current.
+func (u *limits) UnbalancedLoadLimits(currents []float64) []float64 {
+	res := append([]float64(nil), currents...)
+
+	if asymmetric, err := u.asymmetricChargingSupported(); err == nil && asymmetric {
+		return res
+	}
+
+	for _, current := range currents {
+		for i := range res {
+			if current < res[i] {
+				res[i] = current
+			}
+		}
+	}
+
+	return res
+}
+
+// phaseLimitItems validates the currents of all phases and returns the load control limits to be written
+func (u *limits) phaseLimitItems(currents []float64) ([]feature.LoadControlLimitDatasetType, error) {
+	if len(currents) == 0 {
+		return nil, fmt.Errorf("%w: no currents", ErrLimitNotAvailable)
+	}
+
+	lc, err := localFeature[*feature.LoadControl](&u.useCase, model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return nil, err
+	}
+
+	ec, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	currentLimits, err := u.CurrentLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	params := ec.ParameterDescriptionListData()
+	descriptions := u.limitDescriptions(lc)
+	data := lc.LimitListData()
+
+	if len(descriptions) == 0 {
+		return nil, fmt.Errorf("%w: %s limits", ErrDataNotAvailable, u.scope)
+	}
+
+	limitErr := &LimitsError{Scope: u.scope}
+	fail := func(phase uint, current float64, err error) {
+		limitErr.Phases = append(limitErr.Phases, PhaseLimitError{Phase: phase, Current: current, Err: err})
+	}
+
+	symmetric := true
+	if asymmetric, err := u.asymmetricChargingSupported(); err == nil && asymmetric {
+		symmetric = false
+	}
+
+	var items []feature.LoadControlLimitDatasetType
+	for index, current := range currents {
+		phase := uint(index) + 1
+
+		if symmetric && current != currents[0] && !u.disabled(phase) {
+			fail(phase, current, ErrAsymmetricLimitsNotSupported)
+			continue
+		}
+
+		var desc *model.LoadControlLimitDescriptionDataType
+		for i := range descriptions {
+			if phaseForMeasurement(params, *descriptions[i].MeasurementId) == phase {
+				desc = &descriptions[i]
+				break
+			}
+		}
+
+		var limit *CurrentLimit
+		for i := range currentLimits {
+			if currentLimits[i].Phase == phase {
+				limit = &currentLimits[i]
+				break
+			}
+		}
+
+		if desc == nil || limit == nil {
+			fail(phase, current, ErrLimitNotAvailable)
+			continue
+		}
+
+		if !limitChangeable(data, *desc.LimitId) {
+			fail(phase, current, ErrLimitNotChangeable)
+			continue
+		}
+
+		value := current
+		switch {
+		case u.disabled(phase):
+			value = 0
+		case current == 0:
+			value = limit.Default
+		case current < limit.Min || current > limit.Max:
+			fail(phase, current, fmt.Errorf("%w: %.1f-%.1fA", ErrLimitOutOfRange, limit.Min, limit.Max))
+			continue
+		}
+
+		items = append(items, feature.LoadControlLimitDatasetType{
+			LimitId: uint(*desc.LimitId),
+			Value:   value,
+		})
+	}
+
+	if len(limitErr.Phases) > 0 {
+		return nil, limitErr
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].LimitId < items[j].LimitId
+	})
+
+	return items, nil
+}
+
+// SetLimitsActive activates or deactivates the load control limits of the phases, all phases if none are given.
+// The values of the limits are kept. If any limit is not changeable none of the limits are written.
+func (u *limits) SetLimitsActive(active bool, phases ...uint) error {
+	lc, err := localFeature[*feature.LoadControl](&u.useCase, model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return err
+	}
+
+	descriptions, err := u.phaseLimitDescriptions(lc, phases)
+	if err != nil {
+		return err
+	}
+
+	data := lc.LimitListData()
+
+	var items []model.LoadControlLimitDataType
+	for _, desc := range descriptions {
+		item := model.LoadControlLimitDataType{
+			LimitId:       desc.LimitId,
+			IsLimitActive: &active,
+		}
+
+		for _, limit := range data {
+			if limit.LimitId != nil && *limit.LimitId == *desc.LimitId {
+				item.Value = limit.Value
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	rf, err := u.remoteFeature(model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return err
+	}
+
+	if err := u.write(func(ctx spine.Context) error {
+		return lc.WriteLimitListData(ctx, rf, items)
+	}); err != nil {
+		return err
+	}
+
+	// the active state is verified against the state written last
+	u.writtenMux.Lock()
+	defer u.writtenMux.Unlock()
+
+	written := append([]feature.LoadControlLimitDatasetType(nil), u.written...)
+	for _, item := range items {
+		index := -1
+		for i := range written {
+			if written[i].LimitId == uint(*item.LimitId) {
+				index = i
+			}
+		}
+
+		switch {
+		case index >= 0:
+			written[index].IsLimitActive = active
+		case item.Value != nil:
+			written = append(written, feature.LoadControlLimitDatasetType{LimitId: uint(*item.LimitId), IsLimitActive: active, Value: item.Value.GetValue()})
+		}
+	}
+	u.written = written
+
+	return nil
+}
+
+// CheckLimitsChangeable returns a LimitsError if any load control limit of the phases is not changeable, all phases if none are given
+func (u *limits) CheckLimitsChangeable(phases ...uint) error {
+	lc, err := localFeature[*feature.LoadControl](&u.useCase, model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.phaseLimitDescriptions(lc, phases)
+	return err
+}
+
+// phaseLimitDescriptions returns the descriptions of the changeable limits of the phases, all phases if none are given
+func (u *limits) phaseLimitDescriptions(lc *feature.LoadControl, phases []uint) ([]model.LoadControlLimitDescriptionDataType, error) {
+	ec, err := localFeature[*feature.ElectricalConnection](&u.useCase, model.FeatureTypeEnumTypeElectricalConnection)
+	if err != nil {
+		return nil, err
+	}
+
+	params := ec.ParameterDescriptionListData()
+	descriptions := u.limitDescriptions(lc)
+	data := lc.LimitListData()
+
+	if len(phases) == 0 {
+		for _, desc := range descriptions {
+			if phase := phaseForMeasurement(params, *desc.MeasurementId); phase > 0 {
+				phases = append(phases, phase)
+			}
+		}
+
+		if len(phases) == 0 {
+			return nil, fmt.Errorf("%w: %s limits", ErrDataNotAvailable, u.scope)
+		}
+	}
+
+	limitErr := &LimitsError{Scope: u.scope}
+
+	var res []model.LoadControlLimitDescriptionDataType
+	for _, phase := range phases {
+		var desc *model.LoadControlLimitDescriptionDataType
+		for i := range descriptions {
+			if phaseForMeasurement(params, *descriptions[i].MeasurementId) == phase {
+				desc = &descriptions[i]
+				break
+			}
+		}
+
+		switch {
+		case desc == nil:
+			limitErr.Phases = append(limitErr.Phases, PhaseLimitError{Phase: phase, Err: ErrLimitNotAvailable})
+		case !limitChangeable(data, *desc.LimitId):
+			limitErr.Phases = append(limitErr.Phases, PhaseLimitError{Phase: phase, Err: ErrLimitNotChangeable})
+		default:
+			res = append(res, *desc)
+		}
+	}
+
+	if len(limitErr.Phases) > 0 {
+		return nil, limitErr
+	}
+
+	return res, nil
+}
+
+// limitChangeable returns if the load control limit may be written, limits without data are assumed to be changeable
+func limitChangeable(data []model.LoadControlLimitDataType, id model.LoadControlLimitIdType) bool {
+	for _, item := range data {
+		if item.LimitId != nil && *item.LimitId == id && item.IsLimitChangeable != nil {
+			return *item.IsLimitChangeable
+		}
+	}
+
+	return true
+}
+
+// writeItems writes the load control limits to the remote device
+func (u *limits) writeItems(items []feature.LoadControlLimitDatasetType) error {
+	lc, err := localFeature[*feature.LoadControl](&u.useCase, model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return err
+	}
+
+	rf, err := u.remoteFeature(model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return err
+	}
+
+	if err := u.write(func(ctx spine.Context) error {
+		return lc.WriteLoadControlLimitListData(ctx, rf, items)
+	}); err != nil {
+		return err
+	}
+
+	// writing the values activates the limits
+	written := make([]feature.LoadControlLimitDatasetType, 0, len(items))
+	for _, item := range items {
+		item.IsLimitActive = true
+		written = append(written, item)
+	}
+
+	u.writtenMux.Lock()
+	u.written = written
+	u.writtenMux.Unlock()
+
+	return nil
+}
+
+// inactive returns if the limits written last have been deactivated by the application
+func (u *limits) inactive() bool {
+	u.writtenMux.Lock()
+	defer u.writtenMux.Unlock()
+
+	for _, item := range u.written {
+		if !item.IsLimitActive {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deviating returns if the EVSE reports other values or another active state than written last
+func (u *limits) deviating() bool {
+	lc, err := localFeature[*feature.LoadControl](&u.useCase, model.FeatureTypeEnumTypeLoadControl)
+	if err != nil {
+		return false
+	}
+
+	u.writtenMux.Lock()
+	written := u.written
+	u.writtenMux.Unlock()
+
+	data := lc.LimitListData()
+	for _, item := range written {
+		for _, limit := range data {
+			if limit.LimitId == nil || uint(*limit.LimitId) != item.LimitId {
+				continue
+			}
+
+			if limit.IsLimitActive != nil && *limit.IsLimitActive != item.IsLimitActive {
+				return true
+			}
+			if limit.Value != nil && math.Abs(limit.Value.GetValue()-item.Value) > limitTolerance {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,186 @@
+package ev
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestWritePhaseLimits(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+	setTestLimitData(cem)
+
+	lastWrite := func() []float64 {
+		t.Helper()
+
+		if len(conn.ctx.writes) == 0 {
+			t.Fatal("no limits written")
+		}
+
+		var res []float64
+		for _, item := range conn.ctx.writes[len(conn.ctx.writes)-1][0].LoadControlLimitListData.LoadControlLimitData {
+			res = append(res, item.Value.GetValue())
+		}
+		return res
+	}
+
+	failed := func(err error) []PhaseLimitError {
+		t.Helper()
+
+		var limitErr *LimitsError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("expected limits error, got %v", err)
+		}
+		return limitErr.Phases
+	}
+
+	for _, tc := range []struct {
+		currents []float64
+		phases   []uint
+		err      error
+	}{
+		{[]float64{10, 16, 10}, []uint{2}, ErrAsymmetricLimitsNotSupported},
+		{[]float64{20, 20, 20}, []uint{1, 2, 3}, ErrLimitOutOfRange},
+		{[]float64{4, 4, 4}, []uint{1, 2, 3}, ErrLimitOutOfRange},
+		{[]float64{10, 10, 10, 10}, []uint{4}, ErrLimitNotAvailable},
+	} {
+		err := uc.OPEV.WritePhaseLimits(tc.currents)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%v: expected %v, got %v", tc.currents, tc.err, err)
+			continue
+		}
+
+		var phases []uint
+		for _, p := range failed(err) {
+			phases = append(phases, p.Phase)
+		}
+		if len(phases) != len(tc.phases) || phases[0] != tc.phases[0] {
+			t.Errorf("%v: expected failed phases %v, got %v", tc.currents, tc.phases, phases)
+		}
+	}
+
+	if len(conn.ctx.writes) != 0 {
+		t.Errorf("expected no writes, got %d", len(conn.ctx.writes))
+	}
+
+	// unbalanced load protection reduces all phases for symmetric EVs
+	currents := uc.OPEV.UnbalancedLoadLimits([]float64{16, 10, 16})
+	if err := uc.OPEV.WritePhaseLimits(currents); err != nil {
+		t.Fatal(err)
+	}
+	if values := lastWrite(); len(values) != 3 || values[0] != 10 || values[2] != 10 {
+		t.Errorf("expected symmetric limits, got %v", values)
+	}
+
+	// asymmetric EVs accept different currents per phase
+	dc := cem.FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeClient)
+	dc.SetData(model.FunctionEnumTypeDeviceConfigurationKeyValueDescriptionListData, &model.DeviceConfigurationKeyValueDescriptionListDataType{
+		DeviceConfigurationKeyValueDescriptionData: []model.DeviceConfigurationKeyValueDescriptionDataType{{
+			KeyId:   ptr(model.DeviceConfigurationKeyIdType(1)),
+			KeyName: ptr(string(model.DeviceConfigurationKeyNameEnumTypeAsymmetricChargingSupported)),
+		}},
+	})
+	dc.SetData(model.FunctionEnumTypeDeviceConfigurationKeyValueListData, &model.DeviceConfigurationKeyValueListDataType{
+		DeviceConfigurationKeyValueData: []model.DeviceConfigurationKeyValueDataType{{
+			KeyId: ptr(model.DeviceConfigurationKeyIdType(1)),
+			Value: &model.DeviceConfigurationKeyValueValueType{Boolean: ptr(true)},
+		}},
+	})
+
+	if currents := uc.OPEV.UnbalancedLoadLimits([]float64{16, 10, 16}); currents[0] != 16 || currents[1] != 10 {
+		t.Errorf("expected asymmetric limits, got %v", currents)
+	}
+
+	if err := uc.OPEV.WritePhaseLimits([]float64{16, 10, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if values := lastWrite(); len(values) != 3 || values[0] != 16 || values[1] != 10 || values[2] != 0.1 {
+		t.Errorf("expected asymmetric limits, got %v", values)
+	}
+
+	// limits reported as not changeable are not written
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	lc.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{
+		LoadControlLimitData: []model.LoadControlLimitDataType{{
+			LimitId:           ptr(model.LoadControlLimitIdType(2)),
+			IsLimitChangeable: ptr(false),
+			Value:             model.NewScaledNumberType(16),
+		}},
+	})
+
+	writes := len(conn.ctx.writes)
+	if err := uc.OPEV.WritePhaseLimits([]float64{10, 10, 10}); !errors.Is(err, ErrLimitNotChangeable) || failed(err)[0].Phase != 2 {
+		t.Errorf("expected phase 2 not changeable, got %v", err)
+	}
+	if len(conn.ctx.writes) != writes {
+		t.Error("expected no writes")
+	}
+}
+
+func TestSetLimitsActive(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+	setTestLimitData(cem)
+
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+	lc.SetData(model.FunctionEnumTypeLoadControlLimitListData, &model.LoadControlLimitListDataType{
+		LoadControlLimitData: []model.LoadControlLimitDataType{
+			{LimitId: ptr(model.LoadControlLimitIdType(1)), IsLimitChangeable: ptr(true), IsLimitActive: ptr(true), Value: model.NewScaledNumberType(16)},
+			{LimitId: ptr(model.LoadControlLimitIdType(2)), IsLimitChangeable: ptr(true), IsLimitActive: ptr(true), Value: model.NewScaledNumberType(16)},
+			{LimitId: ptr(model.LoadControlLimitIdType(3)), IsLimitChangeable: ptr(false), IsLimitActive: ptr(true), Value: model.NewScaledNumberType(16)},
+		},
+	})
+
+	if err := uc.OPEV.CheckLimitsChangeable(1, 2); err != nil {
+		t.Error(err)
+	}
+	if err := uc.OPEV.CheckLimitsChangeable(); !errors.Is(err, ErrLimitNotChangeable) {
+		t.Errorf("expected not changeable, got %v", err)
+	}
+
+	// not changeable limits are neither activated nor written with values
+	if err := uc.OPEV.SetLimitsActive(false); !errors.Is(err, ErrLimitNotChangeable) {
+		t.Errorf("expected not changeable, got %v", err)
+	}
+	if err := uc.OPEV.WriteOverloadLimits([]float64{10, 10, 10}); !errors.Is(err, ErrLimitNotChangeable) {
+		t.Errorf("expected not changeable, got %v", err)
+	}
+	if len(conn.ctx.writes) != 0 {
+		t.Errorf("expected no writes, got %d", len(conn.ctx.writes))
+	}
+
+	if err := uc.OPEV.SetLimitsActive(false, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	data := conn.ctx.writes[0][0].LoadControlLimitListData.LoadControlLimitData
+	if len(data) != 1 || *data[0].LimitId != 2 || *data[0].IsLimitActive || data[0].Value.GetValue() != 16 {
+		t.Errorf("unexpected write: %+v", data)
+	}
+}
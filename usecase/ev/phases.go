@@ -0,0 +1,272 @@
+package ev
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/eebus/spine/model"
+	"github.com/samber/lo"
+)
+
+// PhaseSwitchTimeout is the time the EV has for charging with the requested number of phases
+const PhaseSwitchTimeout = 2 * time.Minute
+
+// phaseCurrentThreshold is the measured current in A above which a phase is used for charging
+const phaseCurrentThreshold = 1.0
+
+var (
+	// ErrPhaseSwitchingNotSupported is returned if the EVSE does not support switching phases
+	ErrPhaseSwitchingNotSupported = errors.New("phase switching not supported")
+	// ErrPhaseSwitchTimeout is reported if the EV has not charged with the requested phases before the timeout
+	ErrPhaseSwitchTimeout = errors.New("phase switch timeout")
+)
+
+// PhaseSwitchingMethod is the way the EVSE is asked to switch phases
+type PhaseSwitchingMethod string
+
+const (
+	PhaseSwitchingMethodNone                PhaseSwitchingMethod = "none"
+	PhaseSwitchingMethodDeviceConfiguration PhaseSwitchingMethod = "deviceConfiguration" // number of phases is written to the vendor key set by SetPhasesKey
+	PhaseSwitchingMethodPermittedValues     PhaseSwitchingMethod = "permittedValues"     // phases 2 and 3 permit 0 A and are limited to 0 A
+)
+
+// PhaseSwitchingCapability describes if and how the EVSE can switch phases
+type PhaseSwitchingCapability struct {
+	Method PhaseSwitchingMethod
+	Phases []uint // numbers of phases that can be requested
+}
+
+// PhaseSwitching switches the EV between single and three phase charging, e.g. for charging with low PV surplus
+type PhaseSwitching struct {
+	useCase
+	evcem     *EVCEM
+	opev      *OPEV
+	key       model.DeviceConfigurationKeyNameEnumType // vendor specific phases key, not used if empty
+	requested uint                                     // phases requested but not yet used for charging
+	active    uint                                     // phases enabled by the load control limits, 0 if all phases are enabled
+	err       error
+	timer     *time.Timer
+	mux       sync.Mutex
+}
+
+// SetPhasesKey enables switching phases by writing the number of phases to a vendor specific device configuration key.
+// SPINE does not define such a key, it is only used if the EVSE provides it with a changeable scaled number value.
+func (u *PhaseSwitching) SetPhasesKey(name model.DeviceConfigurationKeyNameEnumType) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.key = name
+}
+
+// Capability probes if and how the EVSE supports switching phases.
+// The method is none if the connected EV and EVSE do not support it.
+func (u *PhaseSwitching) Capability() (PhaseSwitchingCapability, error) {
+	none := PhaseSwitchingCapability{Method: PhaseSwitchingMethodNone}
+
+	if u.phasesKey() {
+		return PhaseSwitchingCapability{Method: PhaseSwitchingMethodDeviceConfiguration, Phases: []uint{1, 3}}, nil
+	}
+
+	// the minimum currents of the communication standard do not apply to disabled phases
+	currentLimits, err := u.opev.permittedCurrentLimits()
+	if err != nil {
+		return none, err
+	}
+
+	// phase 1 is always used for charging while phases 2 and 3 can be limited to 0 A
+	var charging bool
+	var switchable int
+	for _, limit := range currentLimits {
+		switch {
+		case limit.Phase == 1:
+			charging = limit.Max > 0
+		case limit.Min <= 0:
+			switchable++
+		}
+	}
+
+	if !charging || switchable < len(phases)-1 {
+		return none, nil
+	}
+
+	return PhaseSwitchingCapability{Method: PhaseSwitchingMethodPermittedValues, Phases: []uint{1, 3}}, nil
+}
+
+// SwitchPhases asks the EVSE to charge with the number of phases. The switch is confirmed by an EventEVPhasesSwitched
+// once the measured currents show charging with the requested phases, an EventEVPhaseSwitchFailed is published after the PhaseSwitchTimeout.
+func (u *PhaseSwitching) SwitchPhases(phases uint) error {
+	capability, err := u.Capability()
+	if err != nil {
+		return err
+	}
+
+	if capability.Method == PhaseSwitchingMethodNone {
+		return ErrPhaseSwitchingNotSupported
+	}
+
+	if !lo.Contains(capability.Phases, phases) {
+		return fmt.Errorf("%w: %d phases", ErrPhaseSwitchingNotSupported, phases)
+	}
+
+	u.mux.Lock()
+	u.requested = phases
+	u.err = nil
+	u.stopTimeout()
+	u.timer = time.AfterFunc(PhaseSwitchTimeout, u.timeoutExceeded)
+	u.mux.Unlock()
+
+	switch capability.Method {
+	case PhaseSwitchingMethodDeviceConfiguration:
+		err = u.writePhasesKey(phases)
+
+	case PhaseSwitchingMethodPermittedValues:
+		u.mux.Lock()
+		u.active = phases
+		u.mux.Unlock()
+
+		err = u.opev.refresh()
+	}
+
+	if err != nil {
+		u.mux.Lock()
+		u.requested = 0
+		u.stopTimeout()
+		u.mux.Unlock()
+
+		return err
+	}
+
+	// the EV may already be charging with the requested phases
+	u.confirm()
+
+	return nil
+}
+
+// Pending returns the requested number of phases not yet used for charging
+func (u *PhaseSwitching) Pending() (uint, bool) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	return u.requested, u.requested > 0
+}
+
+// Err returns the error of the last phase switch
+func (u *PhaseSwitching) Err() error {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	return u.err
+}
+
+// activePhases returns the number of phases enabled by the load control limits, 0 if all phases are enabled
+func (u *PhaseSwitching) activePhases() uint {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	return u.active
+}
+
+// phasesKey returns if a vendor phases key has been set and the EVSE provides it changeable
+func (u *PhaseSwitching) phasesKey() bool {
+	u.mux.Lock()
+	key := u.key
+	u.mux.Unlock()
+
+	if key == "" {
+		return false
+	}
+
+	_, f, err := u.keyValues()
+	if err != nil {
+		return false
+	}
+
+	item, ok := keyValueData(f, key)
+	if !ok || item.Value.ScaledNumber == nil {
+		return false
+	}
+
+	return item.IsValueChangeable == nil || *item.IsValueChangeable
+}
+
+// writePhasesKey writes the number of phases to the phases key
+func (u *PhaseSwitching) writePhasesKey(phases uint) error {
+	u.mux.Lock()
+	key := u.key
+	u.mux.Unlock()
+
+	return u.writeKeyValue(key, model.DeviceConfigurationKeyValueValueType{
+		ScaledNumber: model.NewScaledNumberType(float64(phases)),
+	})
+}
+
+// confirm publishes the switch once the EV charges with the requested number of phases. The connected phases
+// reported by the EVSE do not change when phases are limited to 0 A, hence the measured currents are used.
+func (u *PhaseSwitching) confirm() {
+	currents, err := u.evcem.CurrentPerPhase()
+	if err != nil {
+		return
+	}
+
+	var charging uint
+	for _, current := range currents {
+		if current > phaseCurrentThreshold {
+			charging++
+		}
+	}
+
+	u.mux.Lock()
+	confirmed := u.requested > 0 && u.requested == charging
+	if confirmed {
+		u.requested = 0
+		u.stopTimeout()
+	}
+	u.mux.Unlock()
+
+	if confirmed {
+		u.event(EventEVPhasesSwitched)
+	}
+}
+
+// timeoutExceeded is called if the EV has not charged with the requested phases in time
+func (u *PhaseSwitching) timeoutExceeded() {
+	u.mux.Lock()
+	failed := u.requested > 0
+	if failed {
+		u.requested = 0
+		u.err = ErrPhaseSwitchTimeout
+	}
+	u.timer = nil
+	u.mux.Unlock()
+
+	if failed {
+		u.event(EventEVPhaseSwitchFailed)
+	}
+}
+
+// stopTimeout stops the timeout timer, the caller must hold the lock
+func (u *PhaseSwitching) stopTimeout() {
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+}
+
+func (u *PhaseSwitching) evConnectionChanged(connected bool) {
+	// phases are switched for the connected EV only
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.requested = 0
+	u.active = 0
+	u.err = nil
+	u.stopTimeout()
+}
+
+func (u *PhaseSwitching) dataChanged(featureType model.FeatureTypeEnumType, function model.FunctionEnumType) {
+	if function == model.FunctionEnumTypeMeasurementListData {
+		u.confirm()
+	}
+}
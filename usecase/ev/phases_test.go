@@ -0,0 +1,200 @@
+package ev
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/evcc-io/eebus/device/entity"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+func TestPhaseSwitching(t *testing.T) {
+	local := &spine.DeviceImpl{Address: "d:_i:HEMS"}
+	cem := entity.CEM()
+	local.Add(cem)
+
+	conn := &testConnection{}
+
+	uc, err := New(local, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []EventType
+	uc.AddEventHandler(func(e Event) {
+		events = append(events, e.Type)
+	})
+
+	conn.remote = testRemoteDevice(t)
+	conn.evConnected(true)
+	setTestLimitData(cem)
+
+	// all phases require 6 A
+	if capability, err := uc.PhaseSwitching.Capability(); err != nil || capability.Method != PhaseSwitchingMethodNone {
+		t.Errorf("expected no phase switching, got %v %v", capability, err)
+	}
+	if err := uc.PhaseSwitching.SwitchPhases(1); !errors.Is(err, ErrPhaseSwitchingNotSupported) {
+		t.Errorf("expected not supported, got %v", err)
+	}
+
+	// phases 2 and 3 may be limited to 0 A, phase 1 permitting 0 A as well is common
+	ec := cem.FeatureByProps(model.FeatureTypeEnumTypeElectricalConnection, model.RoleTypeClient)
+
+	var permitted []model.ElectricalConnectionPermittedValueSetDataType
+	for i, min := range []float64{0, 0, 0} {
+		permitted = append(permitted, model.ElectricalConnectionPermittedValueSetDataType{
+			ParameterId: ptr(model.ElectricalConnectionParameterIdType(i)),
+			PermittedValueSet: []model.ScaledNumberSetType{{
+				Value: []model.ScaledNumberType{*model.NewScaledNumberType(0)},
+				Range: []model.ScaledNumberRangeType{{Min: model.NewScaledNumberType(min), Max: model.NewScaledNumberType(16)}},
+			}},
+		})
+	}
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionPermittedValueSetListData, &model.ElectricalConnectionPermittedValueSetListDataType{
+		ElectricalConnectionPermittedValueSetData: permitted,
+	})
+
+	capability, err := uc.PhaseSwitching.Capability()
+	if err != nil || capability.Method != PhaseSwitchingMethodPermittedValues {
+		t.Fatalf("expected permitted values, got %v %v", capability, err)
+	}
+
+	if err := uc.PhaseSwitching.SwitchPhases(2); !errors.Is(err, ErrPhaseSwitchingNotSupported) {
+		t.Errorf("expected not supported, got %v", err)
+	}
+
+	if err := uc.PhaseSwitching.SwitchPhases(1); err != nil {
+		t.Fatal(err)
+	}
+
+	lastWrite := func() []model.CmdType {
+		t.Helper()
+
+		if len(conn.ctx.writes) == 0 {
+			t.Fatal("nothing written")
+		}
+		return conn.ctx.writes[len(conn.ctx.writes)-1]
+	}
+
+	var values []float64
+	for _, item := range lastWrite()[0].LoadControlLimitListData.LoadControlLimitData {
+		values = append(values, item.Value.GetValue())
+	}
+	if len(values) != 3 || values[0] != 16 || values[1] != 0 || values[2] != 0 {
+		t.Errorf("expected single phase limits, got %v", values)
+	}
+
+	// limits of the application are restricted to the enabled phases
+	if err := uc.OPEV.WriteOverloadLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+	if data := lastWrite()[0].LoadControlLimitListData.LoadControlLimitData; data[0].Value.GetValue() != 10 || data[1].Value.GetValue() != 0 {
+		t.Errorf("expected single phase limits, got %v", data)
+	}
+
+	// as are limits written through the other use cases
+	lc := cem.FeatureByProps(model.FeatureTypeEnumTypeLoadControl, model.RoleTypeClient)
+
+	var descriptions []model.LoadControlLimitDescriptionDataType
+	for i, scope := range []model.ScopeTypeEnumType{model.ScopeTypeEnumTypeOverloadProtection, model.ScopeTypeEnumTypeSelfConsumption} {
+		for phase := 0; phase < 3; phase++ {
+			descriptions = append(descriptions, model.LoadControlLimitDescriptionDataType{
+				LimitId:       ptr(model.LoadControlLimitIdType(3*i + phase + 1)),
+				MeasurementId: ptr(model.MeasurementIdType(phase)),
+				ScopeType:     ptr(model.ScopeTypeType(scope)),
+			})
+		}
+	}
+	lc.SetData(model.FunctionEnumTypeLoadControlLimitDescriptionListData, &model.LoadControlLimitDescriptionListDataType{
+		LoadControlLimitDescriptionData: descriptions,
+	})
+
+	if err := uc.OSCEV.WriteRecommendationLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+	if data := lastWrite()[0].LoadControlLimitListData.LoadControlLimitData; data[0].Value.GetValue() != 10 || data[1].Value.GetValue() != 0 {
+		t.Errorf("expected single phase limits, got %v", data)
+	}
+	if err := uc.OPEV.WritePhaseLimits([]float64{10, 10, 10}); err != nil {
+		t.Fatal(err)
+	}
+	if data := lastWrite()[0].LoadControlLimitListData.LoadControlLimitData; data[0].Value.GetValue() != 10 || data[1].Value.GetValue() != 0 {
+		t.Errorf("expected single phase limits, got %v", data)
+	}
+
+	// phase 3 is still charging
+	if phases, ok := uc.PhaseSwitching.Pending(); !ok || phases != 1 {
+		t.Errorf("expected pending switch, got %d", phases)
+	}
+
+	// the connected phases reported by the EVSE do not confirm the switch
+	ec.SetData(model.FunctionEnumTypeElectricalConnectionDescriptionListData, &model.ElectricalConnectionDescriptionListDataType{
+		ElectricalConnectionDescriptionData: []model.ElectricalConnectionDescriptionDataType{{AcConnectedPhases: ptr(uint(3))}},
+	})
+
+	if _, ok := uc.PhaseSwitching.Pending(); !ok {
+		t.Error("expected pending switch")
+	}
+
+	// the EV charges with phase 1 only
+	m := cem.FeatureByProps(model.FeatureTypeEnumTypeMeasurement, model.RoleTypeClient)
+	m.SetData(model.FunctionEnumTypeMeasurementListData, &model.MeasurementListDataType{
+		MeasurementData: []model.MeasurementDataType{
+			{MeasurementId: ptr(model.MeasurementIdType(0)), Value: model.NewScaledNumberType(10)},
+			{MeasurementId: ptr(model.MeasurementIdType(2)), Value: model.NewScaledNumberType(0.2)},
+		},
+	})
+
+	if _, ok := uc.PhaseSwitching.Pending(); ok {
+		t.Error("expected confirmed switch")
+	}
+	if events[len(events)-1] != EventEVPhasesSwitched {
+		t.Errorf("expected phases switched event, got %v", events)
+	}
+
+	// a vendor phases key is only used after opting in
+	dc := cem.FeatureByProps(model.FeatureTypeEnumTypeDeviceConfiguration, model.RoleTypeClient)
+	dc.SetData(model.FunctionEnumTypeDeviceConfigurationKeyValueDescriptionListData, &model.DeviceConfigurationKeyValueDescriptionListDataType{
+		DeviceConfigurationKeyValueDescriptionData: []model.DeviceConfigurationKeyValueDescriptionDataType{{
+			KeyId:     ptr(model.DeviceConfigurationKeyIdType(5)),
+			KeyName:   ptr("acConnectedPhases"),
+			ValueType: ptr(model.DeviceConfigurationKeyValueTypeTypeScalednumber),
+		}},
+	})
+	dc.SetData(model.FunctionEnumTypeDeviceConfigurationKeyValueListData, &model.DeviceConfigurationKeyValueListDataType{
+		DeviceConfigurationKeyValueData: []model.DeviceConfigurationKeyValueDataType{{
+			KeyId:             ptr(model.DeviceConfigurationKeyIdType(5)),
+			Value:             &model.DeviceConfigurationKeyValueValueType{ScaledNumber: model.NewScaledNumberType(1)},
+			IsValueChangeable: ptr(true),
+		}},
+	})
+
+	if capability, _ := uc.PhaseSwitching.Capability(); capability.Method != PhaseSwitchingMethodPermittedValues {
+		t.Errorf("expected permitted values, got %v", capability)
+	}
+
+	uc.PhaseSwitching.SetPhasesKey("acConnectedPhases")
+
+	if capability, _ := uc.PhaseSwitching.Capability(); capability.Method != PhaseSwitchingMethodDeviceConfiguration {
+		t.Errorf("expected device configuration, got %v", capability)
+	}
+
+	if err := uc.PhaseSwitching.SwitchPhases(3); err != nil {
+		t.Fatal(err)
+	}
+
+	data := lastWrite()[0].DeviceConfigurationKeyValueListData
+	if data == nil || len(data.DeviceConfigurationKeyValueData) != 1 || *data.DeviceConfigurationKeyValueData[0].KeyId != 5 ||
+		data.DeviceConfigurationKeyValueData[0].Value.ScaledNumber.GetValue() != 3 {
+		t.Errorf("expected phases key write, got %v", lastWrite())
+	}
+
+	// the switch is cancelled when the EV is disconnected
+	conn.remote.RemoveByAddress([]model.AddressEntityType{1, 1})
+	conn.evConnected(false)
+
+	if _, ok := uc.PhaseSwitching.Pending(); ok {
+		t.Error("expected no pending switch")
+	}
+}
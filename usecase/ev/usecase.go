@@ -0,0 +1,283 @@
+package ev
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/evcc-io/eebus/device/feature"
+	"github.com/evcc-io/eebus/spine"
+	"github.com/evcc-io/eebus/spine/model"
+)
+
+// useCase contains the common parts of all EV use cases
+type useCase struct {
+	name       model.UseCaseNameEnumType
+	entityType model.EntityTypeEnumType // remote entity the use case is operating on
+	local      spine.Entity
+	conn       Connection
+	publish    func(Event)
+}
+
+// Name returns the name of the use case
+func (u *useCase) Name() model.UseCaseNameEnumType {
+	return u.name
+}
+
+// IsSupported returns if the remote device announces the use case
+func (u *useCase) IsSupported() bool {
+	remoteDevice := u.conn.GetDevice()
+	if remoteDevice == nil {
+		return false
+	}
+
+	return remoteDevice.SupportsUseCase(nil, u.name, 0, "")
+}
+
+// register announces the use case of the local entity with the CEM actor. A use case that has already been
+// registered, e.g. by the use cases of another connection, keeps its availability.
+func (u *useCase) register(version model.SpecificationVersionType, scenarios []model.UseCaseScenarioSupportType, features []spine.UseCaseFeature) error {
+	device := u.local.GetDevice()
+	if device == nil {
+		return fmt.Errorf("usecase %s: entity not added to a device", u.name)
+	}
+
+	uc := spine.UseCase{
+		Entity:    u.local,
+		Actor:     model.UseCaseActorEnumTypeCEM,
+		Name:      u.name,
+		Version:   version,
+		Scenarios: scenarios,
+		Features:  features,
+		Available: true,
+	}
+
+	for _, existing := range device.UseCases() {
+		if existing.Actor == uc.Actor && existing.Name == uc.Name {
+			uc.Available = existing.Available
+		}
+	}
+
+	return device.AddUseCase(uc)
+}
+
+// clientFeatures returns the client features required by a use case
+func clientFeatures(typ ...model.FeatureTypeEnumType) []spine.UseCaseFeature {
+	res := make([]spine.UseCaseFeature, 0, len(typ))
+	for _, t := range typ {
+		res = append(res, spine.UseCaseFeature{Type: t, Role: model.RoleTypeClient})
+	}
+	return res
+}
+
+func (u *useCase) event(typ EventType) {
+	u.publish(Event{UseCase: u.name, Type: typ})
+}
+
+func (u *useCase) evConnectionChanged(connected bool) {}
+
+// remoteEntity returns the remote entity of the use case
+func (u *useCase) remoteEntity() (spine.Entity, error) {
+	remoteDevice := u.conn.GetDevice()
+	if remoteDevice == nil {
+		return nil, spine.ErrNotConnected
+	}
+
+	entity := remoteDevice.EntityByType(model.EntityTypeType(u.entityType))
+	if entity == nil {
+		if u.entityType == model.EntityTypeEnumTypeEV {
+			return nil, spine.ErrNoEV
+		}
+		return nil, fmt.Errorf("%w: %s entity", ErrDataNotAvailable, u.entityType)
+	}
+
+	return entity, nil
+}
+
+// remoteFeature returns the server feature of the remote entity
+func (u *useCase) remoteFeature(typ model.FeatureTypeEnumType) (spine.Feature, error) {
+	entity, err := u.remoteEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	rf := entity.FeatureByProps(typ, model.RoleTypeServer)
+	if rf == nil {
+		return nil, fmt.Errorf("%w: %s on remote device", spine.ErrFeatureNotSupported, typ)
+	}
+
+	return rf, nil
+}
+
+// localFeature returns the client feature of the local entity with its cached data.
+// The cached data is only valid while the remote entity of the use case is available.
+func localFeature[T spine.Feature](u *useCase, typ model.FeatureTypeEnumType) (T, error) {
+	var res T
+
+	if _, err := u.remoteEntity(); err != nil {
+		return res, err
+	}
+
+	res, ok := u.local.FeatureByProps(typ, model.RoleTypeClient).(T)
+	if !ok {
+		return res, fmt.Errorf("%w: %s on local device", spine.ErrFeatureNotSupported, typ)
+	}
+
+	return res, nil
+}
+
+// write sends the writes of fn to the remote device
+func (u *useCase) write(fn func(ctx spine.Context) error) error {
+	return u.conn.WriteBatch(fn)
+}
+
+// entityState tracks the manufacturer data and operating state of a remote entity for detecting changes
+type entityState struct {
+	manufacturer *model.DeviceClassificationManufacturerDataType
+	state        *model.DeviceDiagnosisOperatingStateType
+	mux          sync.Mutex
+}
+
+// updateManufacturer returns if the manufacturer data of the entity has changed since the last update
+func (s *entityState) updateManufacturer(entity spine.Entity) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	manufacturer := entity.GetManufacturerData()
+	if s.manufacturer != nil && reflect.DeepEqual(*s.manufacturer, manufacturer) {
+		return false
+	}
+
+	s.manufacturer = &manufacturer
+	return true
+}
+
+// updateState returns if the operating state of the entity has changed since the last update
+func (s *entityState) updateState(entity spine.Entity) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	state := entity.GetOperationState()
+	if s.state != nil && *s.state == state {
+		return false
+	}
+
+	s.state = &state
+	return true
+}
+
+func (s *entityState) reset() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.manufacturer = nil
+	s.state = nil
+}
+
+// manufacturer returns the manufacturer data of the remote entity
+func (u *useCase) manufacturer() (model.DeviceClassificationManufacturerDataType, error) {
+	entity, err := u.remoteEntity()
+	if err != nil {
+		return model.DeviceClassificationManufacturerDataType{}, err
+	}
+
+	data := entity.GetManufacturerData()
+	if reflect.DeepEqual(data, model.DeviceClassificationManufacturerDataType{}) {
+		return data, ErrDataNotAvailable
+	}
+
+	return data, nil
+}
+
+// operatingState returns the operating state of the remote entity
+func (u *useCase) operatingState() (model.DeviceDiagnosisOperatingStateType, error) {
+	entity, err := u.remoteEntity()
+	if err != nil {
+		return "", err
+	}
+
+	state := entity.GetOperationState()
+	if state == "" {
+		return state, ErrDataNotAvailable
+	}
+
+	return state, nil
+}
+
+// asymmetricChargingSupported returns if the EV supports different limits per phase
+func (u *useCase) asymmetricChargingSupported() (bool, error) {
+	f, err := localFeature[*feature.DeviceConfiguration](u, model.FeatureTypeEnumTypeDeviceConfiguration)
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := keyValue(f, model.DeviceConfigurationKeyNameEnumTypeAsymmetricChargingSupported)
+	if !ok || value.Boolean == nil {
+		return false, ErrDataNotAvailable
+	}
+
+	return *value.Boolean, nil
+}
+
+// keyValues returns the device configuration feature and the cached key values of the use case's remote entity
+func (u *useCase) keyValues() (*feature.DeviceConfiguration, keyValues, error) {
+	f, err := localFeature[*feature.DeviceConfiguration](u, model.FeatureTypeEnumTypeDeviceConfiguration)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if u.entityType == model.EntityTypeEnumTypeEVSE {
+		return f, f.EVSE(), nil
+	}
+
+	return f, f, nil
+}
+
+// writeKeyValue writes the value of a changeable device configuration key of the use case's remote entity
+func (u *useCase) writeKeyValue(name model.DeviceConfigurationKeyNameEnumType, value model.DeviceConfigurationKeyValueValueType) error {
+	f, cache, err := u.keyValues()
+	if err != nil {
+		return err
+	}
+
+	item, ok := keyValueData(cache, name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDataNotAvailable, name)
+	}
+	if item.IsValueChangeable != nil && !*item.IsValueChangeable {
+		return fmt.Errorf("%w: %s", ErrKeyNotChangeable, name)
+	}
+
+	rf, err := u.remoteFeature(model.FeatureTypeEnumTypeDeviceConfiguration)
+	if err != nil {
+		return err
+	}
+
+	data := []model.DeviceConfigurationKeyValueDataType{{
+		KeyId: item.KeyId,
+		Value: &value,
+	}}
+
+	return u.write(func(ctx spine.Context) error {
+		return f.WriteKeyValueListData(ctx, rf, data)
+	})
+}
+
+// entityStateChanged publishes the events for changed manufacturer data or operating state
+func (u *useCase) entityStateChanged(s *entityState, featureType model.FeatureTypeEnumType, manufacturerEvent, stateEvent EventType) {
+	entity, err := u.remoteEntity()
+	if err != nil {
+		return
+	}
+
+	switch featureType {
+	case model.FeatureTypeEnumTypeDeviceClassification:
+		if s.updateManufacturer(entity) {
+			u.event(manufacturerEvent)
+		}
+	case model.FeatureTypeEnumTypeDeviceDiagnosis:
+		if s.updateState(entity) {
+			u.event(stateEvent)
+		}
+	}
+}